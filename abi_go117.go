@@ -0,0 +1,17 @@
+//go:build go1.17
+// +build go1.17
+
+package monkey
+
+// gReturn returns the g gLoad put in r12 from the code built by
+// buildLoader, in rax as the register ABI does.
+var gReturn = []byte{
+	// mov rax,r12
+	0x4C, 0x89, 0xE0,
+	// ret
+	0xC3,
+}
+
+// registerABI tells whether Go functions take their arguments in registers,
+// unlike assembly ones.
+const registerABI = true
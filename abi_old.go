@@ -0,0 +1,17 @@
+//go:build !go1.17
+// +build !go1.17
+
+package monkey
+
+// gReturn returns the g gLoad put in r12 from the code built by
+// buildLoader, on the stack above the return address as ABI0 does.
+var gReturn = []byte{
+	// mov QWORD PTR [rsp+8],r12
+	0x4C, 0x89, 0x64, 0x24, 0x08,
+	// ret
+	0xC3,
+}
+
+// registerABI tells whether Go functions take their arguments in registers,
+// unlike assembly ones.
+const registerABI = false
@@ -0,0 +1,33 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// ErrActiveFrame is returned when a function can't be patched because the
+// goroutine patching it would return into the code being rewritten.
+var ErrActiveFrame = errors.New("monkey: target is running on this goroutine")
+
+// checkActive looks for the function at from among the callers of the
+// current goroutine, the first n bytes of which are about to be rewritten.
+// Returning into those bytes would run half of the jump written there, so
+// that is an error. Returning past them is safe but probably not what the
+// caller expects, the replacement only applies to new calls, so that is
+// logged.
+func checkActive(from uintptr, n int) error {
+	pc := make([]uintptr, 64)
+	pc = pc[:runtime.Callers(2, pc)]
+	f := runtime.FuncForPC(from)
+	for _, ret := range pc {
+		if ret > from && ret < from+uintptr(n) {
+			return fmt.Errorf("%w: %s would return into its patched prologue", ErrActiveFrame, SymbolName(from))
+		}
+		if f != nil && runtime.FuncForPC(ret-1) == f {
+			logf("patching %s while it runs on this goroutine, the running call is left alone", SymbolName(from))
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// ErrAssemblyTarget is returned when patching a function written in
+// assembly without AllowAssemblyTargets.
+var ErrAssemblyTarget = errors.New("monkey: target is written in assembly")
+
+// AllowAssemblyTargets lets the patch apply to a function written in
+// assembly, like those of crypto or runtime, which are refused otherwise.
+// Such functions have no stack check to start with, so the jump may cover
+// any code: the instructions it covers are checked to fit in the function
+// and not to be jumped back into, but instructions the decoder doesn't know
+// can hide such jumps.
+//
+// Assembly functions take their arguments on the stack, while Go ones take
+// them in registers since Go 1.17, so only functions without parameters nor
+// results can be patched there. Functions with arguments are usually called
+// from other packages through a wrapper, which can be patched as any Go
+// function by passing the assembly function itself to Patch.
+func AllowAssemblyTargets() PatchOption {
+	return func(c *patchConfig) {
+		c.assembly = true
+	}
+}
+
+// isAssembly tells whether the function starting at pc is written in
+// assembly, according to the file the func table places it in.
+func isAssembly(pc uintptr) bool {
+	f := runtime.FuncForPC(pc)
+	if f == nil {
+		return false
+	}
+	file, _ := f.FileLine(pc)
+	return strings.HasSuffix(file, ".s")
+}
+
+// checkAssembly returns an error unless the assembly function at from can be
+// patched with a replacement of type t.
+func checkAssembly(from uintptr, t reflect.Type, c *patchConfig) error {
+	if !c.assembly {
+		return fmt.Errorf("%w: %s, pass AllowAssemblyTargets to patch it anyway", ErrAssemblyTarget, SymbolName(from))
+	}
+	if registerABI && (t.NumIn() > 0 || t.NumOut() > 0) {
+		return fmt.Errorf("%w: %s takes its arguments on the stack, unlike %s", ErrAssemblyTarget, SymbolName(from), t)
+	}
+	return nil
+}
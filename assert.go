@@ -0,0 +1,40 @@
+package monkey
+
+import (
+	"strings"
+	"testing"
+)
+
+// AssertCalled fails t unless the replacement of g was called.
+func (g *PatchGuard) AssertCalled(t testing.TB) {
+	t.Helper()
+	if g.err != nil {
+		t.Errorf("monkey: patch of %s made at %s failed: %v", g.TargetName(), g.CreatedAt(), g.err)
+	} else if g.Hits() == 0 {
+		t.Errorf("monkey: replacement of %s patched at %s was not called", g.TargetName(), g.CreatedAt())
+	}
+}
+
+// AssertNotCalled fails t if the replacement of g was called.
+func (g *PatchGuard) AssertNotCalled(t testing.TB) {
+	t.Helper()
+	if n := g.Hits(); n > 0 {
+		t.Errorf("monkey: replacement of %s patched at %s was called %d times", g.TargetName(), g.CreatedAt(), n)
+	}
+}
+
+// AssertNoPatches fails t if any function is still patched, listing where
+// each patch left was made from. Call it at the end of tests, or of a suite,
+// that aren't run in parallel with others patching, to catch patches
+// leaking out of them.
+func AssertNoPatches(t testing.TB) {
+	t.Helper()
+	for _, p := range ListPatches() {
+		var b strings.Builder
+		for _, s := range p.CreationStacks {
+			b.WriteString("\npatched by:\n")
+			b.WriteString(s)
+		}
+		t.Errorf("monkey: %s is still patched%s", p, b.String())
+	}
+}
@@ -0,0 +1,103 @@
+package monkey
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// AuditEntry is a patch requested in audit mode, see AuditMode.
+type AuditEntry struct {
+	// Func is the fully qualified name of the function to patch.
+	Func string `json:"func"`
+	// Site is the file and line of the request, the first outside of
+	// this package.
+	Site string `json:"site"`
+	// Scope is the calls the patch would apply to, see Scope.
+	Scope string `json:"scope"`
+	// Count is the number of requests from Site.
+	Count int `json:"count"`
+}
+
+var audit struct {
+	sync.Mutex
+	on      bool
+	entries map[[2]string]*AuditEntry
+}
+
+// AuditMode turns audit mode on or off. In audit mode, patching functions
+// only record what would be patched and from where, and return guards that
+// work but do nothing, without writing any code: replacements are never
+// called. Run a test suite this way, e.g. where rewriting code is
+// forbidden, to review what it patches before onboarding it, and write the
+// report at the end of TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		monkey.AuditMode(true)
+//		code := m.Run()
+//		monkey.WriteAuditReport(os.Stderr)
+//		os.Exit(code)
+//	}
+//
+// Audit mode works in builds with the monkey_disabled tag as well.
+func AuditMode(on bool) {
+	audit.Lock()
+	defer audit.Unlock()
+	audit.on = on
+}
+
+// AuditReport returns the patches requested in audit mode so far, sorted
+// by function and site.
+func AuditReport() []AuditEntry {
+	audit.Lock()
+	defer audit.Unlock()
+	r := make([]AuditEntry, 0, len(audit.entries))
+	for _, e := range audit.entries {
+		r = append(r, *e)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Func != r[j].Func {
+			return r[i].Func < r[j].Func
+		}
+		return r[i].Site < r[j].Site
+	})
+	return r
+}
+
+// WriteAuditReport writes AuditReport to w as a JSON array.
+func WriteAuditReport(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(AuditReport())
+}
+
+// auditing tells whether audit mode is on.
+func auditing() bool {
+	audit.Lock()
+	defer audit.Unlock()
+	return audit.on
+}
+
+// auditPatch records the patch of g if audit mode is on, and returns
+// whether it is.
+func auditPatch(g *PatchGuard) bool {
+	audit.Lock()
+	defer audit.Unlock()
+	if !audit.on {
+		return false
+	}
+	name, site := g.describeTarget(), siteString(callSite())
+	key := [2]string{name, site}
+	if audit.entries == nil {
+		audit.entries = make(map[[2]string]*AuditEntry)
+	}
+	e, ok := audit.entries[key]
+	if !ok {
+		e = &AuditEntry{Func: name, Site: site, Scope: g.config.scope.String()}
+		audit.entries[key] = e
+	}
+	e.Count++
+	logf("audit: would patch %s at %s", name, site)
+	return true
+}
@@ -0,0 +1,48 @@
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Bind returns fn with its first argument bound to state, so that a single
+// replacement taking the row of a table driven test serves every row:
+//
+//	fake := func(row testCase, key string) (string, error) {
+//		return row.value, row.err
+//	}
+//	for _, row := range rows {
+//		g := monkey.Patch(fetch, monkey.Bind(fake, row))
+//		...
+//	}
+//
+// Bind panics if fn isn't a function or state can't be passed as its first
+// argument. Patch checks the rest of the signature against the target.
+func Bind(fn, state interface{}) interface{} {
+	f := reflect.ValueOf(fn)
+	if f.Kind() != reflect.Func || f.Type().NumIn() == 0 {
+		panic(fmt.Sprintf("monkey: cannot bind %T, a function taking arguments is needed", fn))
+	}
+	t := f.Type()
+	s := reflect.New(t.In(0)).Elem()
+	if state != nil {
+		v := reflect.ValueOf(state)
+		if !v.Type().AssignableTo(t.In(0)) || t.IsVariadic() && t.NumIn() == 1 {
+			panic(fmt.Sprintf("monkey: cannot bind %T as the first argument of %s", state, t))
+		}
+		s.Set(v)
+	}
+
+	in := make([]reflect.Type, t.NumIn()-1)
+	for i := range in {
+		in[i] = t.In(i + 1)
+	}
+	out := make([]reflect.Type, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i)
+	}
+	typ := reflect.FuncOf(in, out, t.IsVariadic())
+	return reflect.MakeFunc(typ, func(args []reflect.Value) []reflect.Value {
+		return call(f, append([]reflect.Value{s}, args...))
+	}).Interface()
+}
@@ -0,0 +1,152 @@
+// Command monkeyvet reports mistakes in the use of monkey in the packages
+// named on the command line and their tests, as listed by go list:
+//
+//	monkeyvet ./...
+//
+// It exits with status 1 if it found any. See package monkeyvet for the
+// checks made. For packages built with -gcflags=-l, as the tests patching
+// functions usually are, pass -noinline to leave out the check for targets
+// small enough to be inlined:
+//
+//	monkeyvet -noinline ./...
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-kiss/monkey/monkeyvet"
+)
+
+// listed is the part of the output of go list used.
+type listed struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	GoFiles    []string
+	Export     string
+	DepOnly    bool
+	ImportMap  map[string]string
+	Error      *struct{ Err string }
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: monkeyvet [-noinline] [packages]\n")
+		flag.PrintDefaults()
+	}
+	var c monkeyvet.Config
+	flag.BoolVar(&c.NoInline, "noinline", false, "the packages are built with -gcflags=-l, don't report targets that could be inlined")
+	flag.Parse()
+
+	pkgs, err := list(flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "monkeyvet:", err)
+		os.Exit(2)
+	}
+	exports := make(map[string]string)
+	variants := make(map[string]bool)
+	for _, p := range pkgs {
+		exports[p.ImportPath] = p.Export
+		if i := strings.Index(p.ImportPath, " ["); i > 0 {
+			variants[p.ImportPath[:i]] = true
+		}
+	}
+
+	found, failed := false, false
+	for _, p := range pkgs {
+		if p.DepOnly || strings.HasSuffix(p.ImportPath, ".test") || variants[p.ImportPath] {
+			continue
+		}
+		fset := token.NewFileSet()
+		diags, err := check(c, fset, p, exports)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "monkeyvet: %s: %v\n", p.ImportPath, err)
+			failed = true
+			continue
+		}
+		for _, d := range diags {
+			fmt.Printf("%s: %s\n", fset.Position(d.Pos), d.Message)
+			found = true
+		}
+	}
+	switch {
+	case failed:
+		os.Exit(2)
+	case found:
+		os.Exit(1)
+	}
+}
+
+// list runs go list on patterns, along with the tests and dependencies.
+func list(patterns []string) ([]*listed, error) {
+	args := append([]string{"list", "-e", "-export", "-deps", "-test", "-json"}, patterns...)
+	cmd := exec.Command("go", args...)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list: %w", err)
+	}
+
+	var pkgs []*listed
+	d := json.NewDecoder(strings.NewReader(string(out)))
+	for {
+		p := &listed{}
+		if err := d.Decode(p); err == io.EOF {
+			return pkgs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, p)
+	}
+}
+
+// check parses and type-checks p, importing its dependencies from the export
+// data go list built, and runs monkeyvet on it with c.
+func check(c monkeyvet.Config, fset *token.FileSet, p *listed, exports map[string]string) ([]monkeyvet.Diagnostic, error) {
+	if p.Error != nil {
+		return nil, errors.New(p.Error.Err)
+	}
+	var files []*ast.File
+	for _, name := range p.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(p.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	imp := importer.ForCompiler(fset, "gc", func(path string) (io.ReadCloser, error) {
+		if to, ok := p.ImportMap[path]; ok {
+			path = to
+		}
+		export := exports[path]
+		if export == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	})
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{Importer: imp}
+	if _, err := conf.Check(p.ImportPath, fset, files, info); err != nil {
+		return nil, err
+	}
+	return c.Check(files, info), nil
+}
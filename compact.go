@@ -0,0 +1,115 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
+)
+
+// ErrMaxTargets is returned when Options.MaxTargets would be exceeded.
+var ErrMaxTargets = errors.New("monkey: too many functions patched")
+
+// retired holds the patches forgotten by the last Compact. Their stubs are
+// freed by the next one, so that threads still running them meanwhile don't
+// see them reused, and the patches kept reachable as their stubs refer to
+// their tables.
+var retired []*patch
+
+// Compact forgets every function no goroutine has a patch for anymore,
+// restoring its original code if PatchEmpty left it jumping to its stub.
+// Long running processes patching over and over, e.g. to inject faults,
+// would otherwise keep a stub and a table for every function they ever
+// patched, and every table of the functions they keep patched. It returns
+// the number of functions forgotten, which Report doesn't list anymore.
+//
+// The stubs of those functions are reused by later patches, and the tables
+// replaced before this call dropped, once Compact is called again: calls
+// still running in a stub meanwhile are left to finish.
+func Compact() int {
+	lock.Lock()
+	defer lock.Unlock()
+	return compact()
+}
+
+func compact() int {
+	for _, p := range retired {
+		removeStub(uintptr(unsafe.Pointer(&p.stub[0])))
+		raw.FreeExecutable(p.stub)
+	}
+	retired = nil
+
+	r := loadRegistry()
+	patches := make(map[uintptr]*patch, len(r.patches))
+	for from, p := range r.patches {
+		if len(p.entries()) > 0 || p.installing != nil {
+			p.retireTables()
+			patches[from] = p
+			continue
+		}
+		if p.active {
+			if err := p.restore(); err != nil {
+				logf("restoring %s: %v", SymbolName(from), err)
+				patches[from] = p
+				continue
+			}
+		}
+		if p.stub != nil {
+			retired = append(retired, p)
+		}
+	}
+	aliases := make(map[uintptr]uintptr, len(r.aliases))
+	for target, from := range r.aliases {
+		if _, ok := patches[from]; ok {
+			aliases[target] = from
+		}
+	}
+	registry.Store(&registryMaps{patches: patches, aliases: aliases})
+	if err := raw.SyncCores(); err != nil {
+		logf("syncing cores: %v", err)
+	}
+
+	n := len(r.patches) - len(patches)
+	if n > 0 {
+		logf("compacted %d functions", n)
+	}
+	return n
+}
+
+// retireTables drops the tables of p replaced before the last Compact,
+// like the stubs of retired patches, keeping those replaced since for
+// threads still reading them and the current one.
+func (p *patch) retireTables() {
+	if len(p.tables) == 0 {
+		return
+	}
+	p.tables = append([][]dispatchEntry(nil), p.tables[p.settled:]...)
+	p.settled = len(p.tables) - 1
+}
+
+// restore writes the original prologue back over the jump to the stub.
+func (p *patch) restore() error {
+	if err := p.checkCode(raw.JmpStub(uintptr(unsafe.Pointer(&p.stub[0])))); err != nil {
+		return err
+	}
+	return raw.WriteText(p.from, len(p.original), func(b []byte) {
+		// Not a jump, but written the same way for threads entering
+		// the function meanwhile.
+		raw.StoreJump(b, p.original)
+	})
+}
+
+// checkMaxTargets compacts the registry if it has as many functions as
+// Options.MaxTargets allows, and returns an error if that isn't enough.
+func checkMaxTargets() error {
+	max := CurrentOptions().MaxTargets
+	if max <= 0 || len(loadRegistry().patches) < max {
+		return nil
+	}
+	compact()
+	if n := len(loadRegistry().patches); n >= max {
+		return fmt.Errorf("%w: %d patched or being unpatched", ErrMaxTargets, n)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import "testing"
+
+//go:noinline
+func churned() int { return 0 }
+
+func TestCompactRetiresTables(t *testing.T) {
+	g := Patch(churned, func() int { return 1 })
+	defer g.Unpatch()
+
+	// Other goroutines patch and unpatch the target while it stays
+	// patched by this one.
+	churn := func() {
+		for i := 0; i < 100; i++ {
+			done := make(chan struct{})
+			go func() {
+				Patch(churned, func() int { return 2 }).Unpatch()
+				close(done)
+			}()
+			<-done
+		}
+	}
+	churn()
+	p, _ := lookupPatch(g.target.Pointer())
+	lock.Lock()
+	n := len(p.tables)
+	lock.Unlock()
+	if n <= 200 {
+		t.Fatalf("%d tables, want every one kept", n)
+	}
+
+	Compact()
+	churn()
+	Compact()
+	lock.Lock()
+	n = len(p.tables)
+	lock.Unlock()
+	if n != 201 {
+		t.Fatalf("%d tables, want those since the last Compact", n)
+	}
+
+	Compact()
+	lock.Lock()
+	n = len(p.tables)
+	lock.Unlock()
+	if n != 1 {
+		t.Fatalf("%d tables, want the current one", n)
+	}
+	if churned() != 1 {
+		t.Error("the patch is lost")
+	}
+}
@@ -0,0 +1,62 @@
+// Package boukmonkey offers the API of bouk/monkey on top of monkey, so that
+// call sites can be migrated by changing the import path only:
+//
+//	import monkey "github.com/go-kiss/monkey/compat/boukmonkey"
+//
+// Unlike with bouk/monkey, patches only apply to the goroutine making them,
+// and Unpatch and UnpatchInstanceMethod only remove the patch of the
+// calling goroutine. Tests expecting functions called from other goroutines
+// to be patched have to patch on those goroutines, or use monkey directly
+// with ForLabels or OnContext.
+package boukmonkey
+
+import (
+	"reflect"
+
+	"github.com/go-kiss/monkey"
+)
+
+// PatchGuard is returned by Patch and PatchInstanceMethod to remove or
+// reapply the patch.
+type PatchGuard struct {
+	g *monkey.PatchGuard
+}
+
+// Unpatch removes the patch.
+func (g *PatchGuard) Unpatch() {
+	g.g.Unpatch()
+}
+
+// Restore applies the patch again after Unpatch.
+func (g *PatchGuard) Restore() {
+	g.g.Restore()
+}
+
+// Patch replaces target with replacement, which has to be a function of
+// the same type, on the calling goroutine.
+func Patch(target, replacement interface{}) *PatchGuard {
+	return &PatchGuard{monkey.Patch(target, replacement)}
+}
+
+// PatchInstanceMethod replaces the method of target with replacement, which
+// takes the receiver first, on the calling goroutine.
+func PatchInstanceMethod(target reflect.Type, methodName string, replacement interface{}) *PatchGuard {
+	return &PatchGuard{monkey.PatchInstanceMethod(target, methodName, replacement)}
+}
+
+// Unpatch removes the patch of the calling goroutine on target, and
+// returns whether there was one.
+func Unpatch(target interface{}) bool {
+	return monkey.Unpatch(target)
+}
+
+// UnpatchInstanceMethod removes the patch of the calling goroutine on the
+// method of target, and returns whether there was one.
+func UnpatchInstanceMethod(target reflect.Type, methodName string) bool {
+	return monkey.UnpatchInstanceMethod(target, methodName)
+}
+
+// UnpatchAll removes every patch, of every goroutine.
+func UnpatchAll() {
+	monkey.UnpatchAll()
+}
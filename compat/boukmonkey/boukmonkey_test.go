@@ -0,0 +1,48 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package boukmonkey_test
+
+import (
+	"reflect"
+	"testing"
+
+	monkey "github.com/go-kiss/monkey/compat/boukmonkey"
+)
+
+//go:noinline
+func no() bool { return false }
+
+type s struct{}
+
+//go:noinline
+func (s) Yes() bool { return true }
+
+func TestPatch(t *testing.T) {
+	g := monkey.Patch(no, func() bool { return true })
+	if !no() {
+		t.Fatal("patch not applied")
+	}
+	g.Unpatch()
+	if no() {
+		t.Fatal("patch not removed")
+	}
+	g.Restore()
+	if !no() {
+		t.Fatal("patch not restored")
+	}
+	if !monkey.Unpatch(no) || no() {
+		t.Fatal("Unpatch didn't remove the patch")
+	}
+}
+
+func TestPatchInstanceMethod(t *testing.T) {
+	typ := reflect.TypeOf(s{})
+	monkey.PatchInstanceMethod(typ, "Yes", func(s) bool { return false })
+	if (s{}).Yes() {
+		t.Fatal("patch not applied")
+	}
+	if !monkey.UnpatchInstanceMethod(typ, "Yes") || !(s{}).Yes() {
+		t.Fatal("UnpatchInstanceMethod didn't remove the patch")
+	}
+}
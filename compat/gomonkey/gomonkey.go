@@ -0,0 +1,194 @@
+// Package gomonkey offers the API of agiledragon/gomonkey on top of monkey,
+// so that call sites can be migrated by changing the import path only:
+//
+//	import "github.com/go-kiss/monkey/compat/gomonkey"
+//
+//	patches := gomonkey.ApplyFunc(time.Now, func() time.Time { return epoch })
+//	defer patches.Reset()
+//
+// Unlike with gomonkey, functions and methods are only patched for the
+// goroutine applying the patches, which Reset has to be called from as
+// well. Global variables, changed by ApplyGlobalVar and ApplyFuncVar, are
+// seen by every goroutine.
+package gomonkey
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/go-kiss/monkey"
+)
+
+// Patches is a set of patches removed together by Reset.
+type Patches struct {
+	guards []*monkey.PatchGuard
+	// vars restore the global variables changed.
+	vars []func()
+}
+
+// Params are the values returned by a call, see OutputCell.
+type Params []interface{}
+
+// OutputCell is the values returned by Times calls in a row, one if Times
+// is zero, see ApplyFuncSeq.
+type OutputCell struct {
+	Values Params
+	Times  int
+}
+
+// NewPatches returns an empty set of patches.
+func NewPatches() *Patches {
+	return &Patches{}
+}
+
+// ApplyFunc is NewPatches().ApplyFunc.
+func ApplyFunc(target, double interface{}) *Patches {
+	return NewPatches().ApplyFunc(target, double)
+}
+
+// ApplyMethod is NewPatches().ApplyMethod.
+func ApplyMethod(target interface{}, methodName string, double interface{}) *Patches {
+	return NewPatches().ApplyMethod(target, methodName, double)
+}
+
+// ApplyMethodFunc is NewPatches().ApplyMethodFunc.
+func ApplyMethodFunc(target interface{}, methodName string, doubleFunc interface{}) *Patches {
+	return NewPatches().ApplyMethodFunc(target, methodName, doubleFunc)
+}
+
+// ApplyGlobalVar is NewPatches().ApplyGlobalVar.
+func ApplyGlobalVar(target, double interface{}) *Patches {
+	return NewPatches().ApplyGlobalVar(target, double)
+}
+
+// ApplyFuncVar is NewPatches().ApplyFuncVar.
+func ApplyFuncVar(target, double interface{}) *Patches {
+	return NewPatches().ApplyFuncVar(target, double)
+}
+
+// ApplyFuncSeq is NewPatches().ApplyFuncSeq.
+func ApplyFuncSeq(target interface{}, outputs []OutputCell) *Patches {
+	return NewPatches().ApplyFuncSeq(target, outputs)
+}
+
+// ApplyFuncReturn is NewPatches().ApplyFuncReturn.
+func ApplyFuncReturn(target interface{}, output ...interface{}) *Patches {
+	return NewPatches().ApplyFuncReturn(target, output...)
+}
+
+// ApplyFunc replaces the function target with double.
+func (p *Patches) ApplyFunc(target, double interface{}) *Patches {
+	p.guards = append(p.guards, monkey.Patch(target, double))
+	return p
+}
+
+// ApplyMethod replaces the method of target, a reflect.Type or a value of
+// the type, with double, which takes the receiver first.
+func (p *Patches) ApplyMethod(target interface{}, methodName string, double interface{}) *Patches {
+	p.guards = append(p.guards, monkey.PatchInstanceMethod(typeOf(target), methodName, double))
+	return p
+}
+
+// ApplyMethodFunc is ApplyMethod with a double not taking the receiver.
+func (p *Patches) ApplyMethodFunc(target interface{}, methodName string, doubleFunc interface{}) *Patches {
+	t := typeOf(target)
+	m, ok := t.MethodByName(methodName)
+	if !ok {
+		panic(fmt.Sprintf("gomonkey: %s has no method %s", t, methodName))
+	}
+	f := reflect.ValueOf(doubleFunc)
+	double := reflect.MakeFunc(m.Type, func(in []reflect.Value) []reflect.Value {
+		return f.Call(in[1:])
+	})
+	return p.ApplyMethod(t, methodName, double.Interface())
+}
+
+// ApplyGlobalVar sets the variable target points to to double.
+func (p *Patches) ApplyGlobalVar(target, double interface{}) *Patches {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr {
+		panic("gomonkey: target is not a pointer")
+	}
+	old := reflect.New(v.Elem().Type()).Elem()
+	old.Set(v.Elem())
+	p.vars = append(p.vars, func() { v.Elem().Set(old) })
+	v.Elem().Set(reflect.ValueOf(double))
+	return p
+}
+
+// ApplyFuncVar sets the function variable target points to to double.
+func (p *Patches) ApplyFuncVar(target, double interface{}) *Patches {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Func {
+		panic("gomonkey: target is not a pointer to a func")
+	}
+	if v.Elem().Type() != reflect.TypeOf(double) {
+		panic(fmt.Sprintf("gomonkey: target is a %s, double a %T", v.Elem().Type(), double))
+	}
+	return p.ApplyGlobalVar(target, double)
+}
+
+// ApplyFuncSeq replaces the function target with one returning the values
+// of outputs in turn. Calls past the last one panic.
+func (p *Patches) ApplyFuncSeq(target interface{}, outputs []OutputCell) *Patches {
+	t := reflect.TypeOf(target)
+	var seq [][]reflect.Value
+	for _, o := range outputs {
+		out := results(t, o.Values)
+		for i := 0; i < o.Times || i == 0; i++ {
+			seq = append(seq, out)
+		}
+	}
+	double := reflect.MakeFunc(t, func([]reflect.Value) []reflect.Value {
+		if len(seq) == 0 {
+			panic("gomonkey: double seq is less than call seq")
+		}
+		out := seq[0]
+		seq = seq[1:]
+		return out
+	})
+	return p.ApplyFunc(target, double.Interface())
+}
+
+// ApplyFuncReturn replaces the function target with one returning output.
+func (p *Patches) ApplyFuncReturn(target interface{}, output ...interface{}) *Patches {
+	t := reflect.TypeOf(target)
+	out := results(t, output)
+	double := reflect.MakeFunc(t, func([]reflect.Value) []reflect.Value { return out })
+	return p.ApplyFunc(target, double.Interface())
+}
+
+// Reset removes the patches and restores the global variables, in the
+// reverse order they were applied.
+func (p *Patches) Reset() {
+	for i := len(p.guards) - 1; i >= 0; i-- {
+		p.guards[i].Unpatch()
+	}
+	for i := len(p.vars) - 1; i >= 0; i-- {
+		p.vars[i]()
+	}
+	p.guards, p.vars = nil, nil
+}
+
+func typeOf(target interface{}) reflect.Type {
+	if t, ok := target.(reflect.Type); ok {
+		return t
+	}
+	return reflect.TypeOf(target)
+}
+
+// results converts vals to the results of a function of type t, nil
+// standing for the zero value.
+func results(t reflect.Type, vals Params) []reflect.Value {
+	if len(vals) != t.NumOut() {
+		panic(fmt.Sprintf("gomonkey: %d values for the %d results of %s", len(vals), t.NumOut(), t))
+	}
+	out := make([]reflect.Value, len(vals))
+	for i, v := range vals {
+		out[i] = reflect.New(t.Out(i)).Elem()
+		if v != nil {
+			out[i].Set(reflect.ValueOf(v))
+		}
+	}
+	return out
+}
@@ -0,0 +1,87 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package gomonkey_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-kiss/monkey/compat/gomonkey"
+)
+
+//go:noinline
+func answer() (int, error) { return 42, nil }
+
+type s struct{}
+
+//go:noinline
+func (s) Name(prefix string) string { return prefix + "s" }
+
+var level = 1
+
+var hook = func() string { return "hook" }
+
+func TestApplyFunc(t *testing.T) {
+	p := gomonkey.ApplyFunc(answer, func() (int, error) { return 0, errors.New("no") })
+	if _, err := answer(); err == nil {
+		t.Fatal("patch not applied")
+	}
+	p.Reset()
+	if n, _ := answer(); n != 42 {
+		t.Fatal("patch not removed")
+	}
+}
+
+func TestApplyMethod(t *testing.T) {
+	p := gomonkey.ApplyMethod(reflect.TypeOf(s{}), "Name", func(_ s, prefix string) string { return prefix + "double" })
+	defer p.Reset()
+	if got := (s{}).Name("a "); got != "a double" {
+		t.Fatalf("got %q", got)
+	}
+	p.Reset()
+	p.ApplyMethodFunc(s{}, "Name", func(prefix string) string { return prefix + "func" })
+	if got := (s{}).Name("a "); got != "a func" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestApplyGlobalVar(t *testing.T) {
+	p := gomonkey.ApplyGlobalVar(&level, 2)
+	p.ApplyFuncVar(&hook, func() string { return "double" })
+	if level != 2 || hook() != "double" {
+		t.Fatal("variables not set")
+	}
+	p.Reset()
+	if level != 1 || hook() != "hook" {
+		t.Fatal("variables not restored")
+	}
+}
+
+func TestApplyFuncSeq(t *testing.T) {
+	p := gomonkey.ApplyFuncSeq(answer, []gomonkey.OutputCell{
+		{Values: gomonkey.Params{1, nil}, Times: 2},
+		{Values: gomonkey.Params{0, errors.New("no")}},
+	})
+	defer p.Reset()
+	for i, want := range []int{1, 1, 0} {
+		if n, _ := answer(); n != want {
+			t.Fatalf("call %d returned %d, want %d", i, n, want)
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("exhausted sequence didn't panic")
+		}
+	}()
+	answer()
+}
+
+func TestApplyFuncReturn(t *testing.T) {
+	p := gomonkey.ApplyFuncReturn(answer, 7, nil)
+	defer p.Reset()
+	if n, err := answer(); n != 7 || err != nil {
+		t.Fatalf("got %d, %v", n, err)
+	}
+}
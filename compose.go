@@ -0,0 +1,33 @@
+package monkey
+
+import "io"
+
+// Close removes the patch made by g, as Unpatch does, so that guards can be
+// kept with other resources torn down by a test. It always returns nil.
+func (g *PatchGuard) Close() error {
+	g.Unpatch()
+	return nil
+}
+
+// Compose returns a closer closing closers in reverse order, as deferred
+// calls would run, for fixtures tearing down a list of resources:
+//
+//	c := monkey.Compose(server, monkey.Patch(time.Now, fakeNow))
+//	defer c.Close()
+//
+// Every closer is closed even if some fail, Close returns the first error.
+func Compose(closers ...io.Closer) io.Closer {
+	return composed(append([]io.Closer(nil), closers...))
+}
+
+type composed []io.Closer
+
+func (c composed) Close() error {
+	var first error
+	for i := len(c) - 1; i >= 0; i-- {
+		if err := c[i].Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,51 @@
+package monkey
+
+import (
+	"context"
+	"reflect"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+type activeKey struct{}
+
+// Activate returns a copy of ctx activating the patches made with
+// OnContext for each of names, in addition to those ctx activates already.
+func Activate(ctx context.Context, names ...string) context.Context {
+	prev, _ := ctx.Value(activeKey{}).(map[string]bool)
+	active := make(map[string]bool, len(prev)+len(names))
+	for n := range prev {
+		active[n] = true
+	}
+	for _, n := range names {
+		active[n] = true
+	}
+	return context.WithValue(ctx, activeKey{}, active)
+}
+
+// Active reports whether ctx activates the patches made with OnContext for
+// name.
+func Active(ctx context.Context, name string) bool {
+	active, _ := ctx.Value(activeKey{}).(map[string]bool)
+	return active[name]
+}
+
+// OnContext makes the patch apply to every goroutine, for the calls whose
+// context, the first argument of the target, is activated for name with
+// Activate. The replacement gets the context like any other argument. In a
+// test of a running server, a middleware activating the names it finds in a
+// header of the request injects faults in single requests. Other calls run
+// the original.
+//
+// As with ForLabels, see Scope for which patch runs when several hold for
+// a call. Both options can be combined, then calls have to satisfy both.
+func OnContext(name string) PatchOption {
+	return func(c *patchConfig) {
+		c.narrow(ScopeContext)
+		c.onContext = true
+		c.addWhen(func(in []reflect.Value) bool {
+			ctx, _ := in[0].Interface().(context.Context)
+			return ctx != nil && Active(ctx, name)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBreakpoint is returned with DebuggerSafe when a debugger has a
+// breakpoint where the jump to the patch would be written.
+var ErrBreakpoint = errors.New("monkey: debugger breakpoint at the start of target")
+
+// DebuggerSafe makes the patch fail with ErrBreakpoint rather than write
+// over a breakpoint when the process is being debugged, e.g. by Delve.
+// Debuggers set a breakpoint by replacing the first byte of an instruction
+// with int3 and put the byte back when it is cleared: writing the jump over
+// it would copy the int3 into the stub, and clearing it afterwards would
+// corrupt the jump. Breakpoints further into the target don't get in the
+// way, and neither do those set on the target once it is patched, which
+// the debugger sets on the jump instead.
+//
+// Debuggers are detected from /proc/self/status on Linux and with
+// IsDebuggerPresent on Windows; elsewhere DebuggerSafe does nothing. To
+// patch a function with a breakpoint at its start, clear the breakpoint or
+// move it past the prologue, e.g. to the first line of the body.
+func DebuggerSafe() PatchOption {
+	return func(c *patchConfig) {
+		c.debuggerSafe = true
+	}
+}
+
+// checkBreakpoint returns an error if c asks for DebuggerSafe and a
+// debugger has a breakpoint in the bytes of from the jump would cover.
+func checkBreakpoint(from uintptr, c *patchConfig) error {
+	if !c.debuggerSafe || !debuggerAttached() {
+		return nil
+	}
+	if at, ok := breakpointAt(from); ok {
+		return fmt.Errorf("%w: %s has one at %#x, clear it to patch", ErrBreakpoint, SymbolName(from), at)
+	}
+	return nil
+}
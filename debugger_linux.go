@@ -0,0 +1,21 @@
+package monkey
+
+import (
+	"bytes"
+	"io/ioutil"
+)
+
+// debuggerAttached returns whether a process traces this one.
+func debuggerAttached() bool {
+	status, err := ioutil.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+	for _, line := range bytes.Split(status, []byte("\n")) {
+		if v := bytes.TrimPrefix(line, []byte("TracerPid:")); len(v) < len(line) {
+			v = bytes.TrimSpace(v)
+			return len(v) > 0 && !bytes.Equal(v, []byte("0"))
+		}
+	}
+	return false
+}
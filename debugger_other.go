@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+// +build !linux,!windows
+
+package monkey
+
+// debuggerAttached can't tell on this platform.
+func debuggerAttached() bool {
+	return false
+}
@@ -0,0 +1,46 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-kiss/monkey/raw"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+//go:noinline
+func breakpointTarget(a, b int) int {
+	return a*b + a
+}
+
+func TestBreakpointAt(t *testing.T) {
+	from := reflect.ValueOf(breakpointTarget).Pointer()
+	if _, ok := breakpointAt(from); ok {
+		t.Fatal("breakpoint found in untouched code")
+	}
+
+	// Set a breakpoint on the second instruction the way debuggers do.
+	i, err := x86asm.Decode(raw.Memory(from, 16), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := from + uintptr(i.Len)
+	saved := raw.Memory(at, 1)[0]
+	if err := raw.WriteText(at, 1, func(b []byte) { b[0] = 0xCC }); err != nil {
+		t.Fatal(err)
+	}
+	defer raw.WriteText(at, 1, func(b []byte) { b[0] = saved })
+
+	if got, ok := breakpointAt(from); !ok || got != at {
+		t.Fatalf("breakpointAt = %#x, %v, want %#x", got, ok, at)
+	}
+	if debuggerAttached() {
+		t.Skip("running under a debugger")
+	}
+	if err := checkBreakpoint(from, &patchConfig{debuggerSafe: true}); err != nil {
+		t.Fatalf("breakpoint reported without a debugger: %v", err)
+	}
+}
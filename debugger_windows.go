@@ -0,0 +1,11 @@
+package monkey
+
+import "syscall"
+
+var procIsDebuggerPresent = syscall.NewLazyDLL("kernel32.dll").NewProc("IsDebuggerPresent")
+
+// debuggerAttached returns whether a debugger is attached to the process.
+func debuggerAttached() bool {
+	r, _, _ := procIsDebuggerPresent.Call()
+	return r != 0
+}
@@ -0,0 +1,105 @@
+package monkey
+
+import (
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Pinned controls the values pinned by Deterministic.
+type Pinned struct {
+	rand *Session
+
+	lock     sync.Mutex
+	now      time.Time
+	pid      int
+	hostname string
+}
+
+// Deterministic pins the values that usually change from run to run for the
+// calling goroutine until t ends: time.Now returns a fixed time, math/rand
+// is seeded, see SeedRand, and os.Getpid and os.Hostname return fixed
+// values. Use the returned Pinned to change them. It needs Go 1.14, see
+// SessionT.
+func Deterministic(t testing.TB) *Pinned {
+	p := &Pinned{
+		now:      time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+		pid:      1000,
+		hostname: "localhost",
+	}
+	s := SessionT(t)
+	s.Patch(time.Now, p.Now)
+	// Built without inlining, os.Getpid calls syscall.Getpid right after
+	// its prologue instead of containing it.
+	if g, err := TryPatch(os.Getpid, p.getpid); err == nil {
+		s.Track(g)
+	} else if g, err := TryPatch(syscall.Getpid, p.getpid); err == nil {
+		s.Track(g)
+	} else {
+		t.Errorf("monkey: cannot pin the process id: %v", err)
+	}
+	// os.Hostname calls its implementation right after its prologue, and
+	// calls cannot be moved out of a patched function.
+	s.Track(PatchStd("os.hostname", p.getHostname))
+	p.rand = SeedRand(1)
+	cleanup(t, func() { p.rand.Unpatch() })
+	return p
+}
+
+// Now returns the pinned time.
+func (p *Pinned) Now() time.Time {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.now
+}
+
+// SetTime pins the time to now.
+func (p *Pinned) SetTime(now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.now = now
+}
+
+// Advance moves the pinned time forward by d.
+func (p *Pinned) Advance(d time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.now = p.now.Add(d)
+}
+
+// SetPID pins the process id returned by os.Getpid.
+func (p *Pinned) SetPID(pid int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.pid = pid
+}
+
+// SetHostname pins the name returned by os.Hostname.
+func (p *Pinned) SetHostname(name string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.hostname = name
+}
+
+// Seed restarts math/rand from seed. It has to be called from the goroutine
+// that called Deterministic.
+func (p *Pinned) Seed(seed int64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.rand.Unpatch()
+	p.rand = SeedRand(seed)
+}
+
+func (p *Pinned) getpid() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.pid
+}
+
+func (p *Pinned) getHostname() (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.hostname, nil
+}
@@ -0,0 +1,8 @@
+//go:build monkey_disabled
+// +build monkey_disabled
+
+package monkey
+
+// Built with monkey_disabled: nothing in this package can write to the
+// text segment.
+const disabled = true
@@ -0,0 +1,42 @@
+//go:build monkey_disabled
+// +build monkey_disabled
+
+package monkey_test
+
+import (
+	"testing"
+
+	"github.com/go-kiss/monkey"
+)
+
+func no() bool  { return false }
+func yes() bool { return true }
+
+func TestDisabled(t *testing.T) {
+	_, err := monkey.TryPatch(no, yes)
+	if err != monkey.ErrDisabled {
+		t.Fatal("expected ErrDisabled, got", err)
+	}
+	if err := monkey.SelfCheck(); err != monkey.ErrDisabled {
+		t.Fatal("expected ErrDisabled from SelfCheck, got", err)
+	}
+	defer func() {
+		if recover() != monkey.ErrDisabled {
+			t.Fatal("expected ErrDisabled panic")
+		}
+	}()
+	monkey.Patch(no, yes)
+}
+
+func TestDisabledAudit(t *testing.T) {
+	monkey.AuditMode(true)
+	defer monkey.AuditMode(false)
+	g, err := monkey.TryPatch(no, yes)
+	if err != nil || g.Err() != nil {
+		t.Fatal("audit mode failed in a disabled build:", err)
+	}
+	g.Unpatch()
+	if r := monkey.AuditReport(); len(r) != 1 || r[0].Count != 1 {
+		t.Fatal("unexpected audit report", r)
+	}
+}
@@ -0,0 +1,86 @@
+//go:build monkey_debug
+// +build monkey_debug
+
+package monkey
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// Disassembly is the code involved in a patch, see PatchGuard.Disassembly.
+type Disassembly struct {
+	Func string
+	// Addr is the address of Func, Stub that of the stub.
+	Addr, Stub uintptr
+	// Original is the start of Func as it was before being patched,
+	// Jump what is written there now.
+	Original, Jump []byte
+	// StubCode is the stub passing the table of the patch to the
+	// dispatcher, or to the code following it while a single goroutine
+	// has patched Func, then Original relocated and a jump back into
+	// Func.
+	StubCode []byte
+}
+
+// Disassembly returns the code of the patch made by g, to diagnose patches
+// misbehaving on some platform. It is only built with the monkey_debug tag.
+func (g *PatchGuard) Disassembly() (Disassembly, error) {
+	if g.err != nil {
+		return Disassembly{}, g.err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	p, ok := lookupPatch(g.target.Pointer())
+	if !ok || p.stub == nil {
+		return Disassembly{}, fmt.Errorf("monkey: %s is not patched", SymbolName(g.target.Pointer()))
+	}
+	from := p.from
+	return Disassembly{
+		Func:     SymbolName(from),
+		Addr:     from,
+		Stub:     uintptr(unsafe.Pointer(&p.stub[0])),
+		Original: append([]byte(nil), p.original...),
+		Jump:     append([]byte(nil), raw.Memory(from, raw.JmpSize)...),
+		StubCode: append([]byte(nil), p.stub...),
+	}, nil
+}
+
+// String lists the instructions of d in Intel syntax.
+func (d Disassembly) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s at %#x, originally:\n", d.Func, d.Addr)
+	disassemble(&b, d.Original, d.Addr)
+	fmt.Fprintf(&b, "now:\n")
+	disassemble(&b, d.Jump, d.Addr)
+	fmt.Fprintf(&b, "stub at %#x:\n", d.Stub)
+	disassemble(&b, d.StubCode, d.Stub)
+	return b.String()
+}
+
+func disassemble(b *strings.Builder, code []byte, pc uintptr) {
+	for s := 0; s < len(code); {
+		i, err := x86asm.Decode(code[s:], 64)
+		if err != nil {
+			fmt.Fprintf(b, "\t%#x\t% x\t(%v)\n", pc+uintptr(s), code[s:s+1], err)
+			s++
+			continue
+		}
+		fmt.Fprintf(b, "\t%#x\t% x\t%s\n", pc+uintptr(s), code[s:s+i.Len], x86asm.IntelSyntax(i, uint64(pc)+uint64(s), symbolize))
+		s += i.Len
+	}
+}
+
+// symbolize names the functions jumped to in the listing.
+func symbolize(addr uint64) (string, uint64) {
+	f := runtime.FuncForPC(uintptr(addr))
+	if f == nil {
+		return "", 0
+	}
+	return f.Name(), uint64(f.Entry())
+}
@@ -0,0 +1,25 @@
+//go:build monkey_debug && !monkey_disabled
+// +build monkey_debug,!monkey_disabled
+
+package monkey_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-kiss/monkey"
+)
+
+func TestDisassembly(t *testing.T) {
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	d, err := g.Disassembly()
+	assert(t, err == nil, err)
+	assert(t, d.Func == "github.com/go-kiss/monkey_test.no", d.Func)
+	assert(t, bytes.Equal(d.StubCode, monkey.StubBytes(no)))
+	assert(t, !bytes.Equal(d.Original, d.Jump[:len(d.Original)]))
+	s := d.String()
+	assert(t, strings.Contains(s, "jmp r12"), s)
+	t.Log(s)
+}
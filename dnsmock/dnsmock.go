@@ -0,0 +1,144 @@
+// Package dnsmock answers DNS lookups of the net package from a table, so
+// code resolving names can be tested without a network:
+//
+//	r := dnsmock.New(t).
+//		Host("api.example.com", "127.0.0.1").
+//		Timeout("slow.example.com")
+//	resp, err := http.Get(srv.URL) // dials api.example.com at 127.0.0.1
+//
+// Lookups are answered for the goroutines that installed the table, names
+// missing from it don't exist. Build tests with -gcflags=all=-l so lookups
+// inlined into the net package are caught as well.
+package dnsmock
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kiss/monkey"
+)
+
+// Resolver is a table of DNS answers.
+type Resolver struct {
+	session *monkey.Session
+
+	lock  sync.Mutex
+	hosts map[string][]net.IP
+	errs  map[string]error
+}
+
+// New returns an empty table installed on the calling goroutine until t
+// ends.
+func New(t testing.TB) *Resolver {
+	r := &Resolver{
+		session: monkey.SessionT(t),
+		hosts:   make(map[string][]net.IP),
+		errs:    make(map[string]error),
+	}
+	r.Install()
+	return r
+}
+
+// Install makes r answer the lookups of the calling goroutine as well.
+func (r *Resolver) Install() {
+	r.session.Track(monkey.PatchStd("net.(*Resolver).lookupIPAddr", r.lookupIPAddr))
+	r.session.Track(monkey.PatchStd("net.(*Resolver).lookupHost", r.lookupHost))
+}
+
+// Host makes name resolve to ips.
+func (r *Resolver) Host(name string, ips ...string) *Resolver {
+	var parsed []net.IP
+	for _, ip := range ips {
+		p := net.ParseIP(ip)
+		if p == nil {
+			panic("dnsmock: invalid IP " + ip)
+		}
+		parsed = append(parsed, p)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	name = canonical(name)
+	r.hosts[name] = parsed
+	delete(r.errs, name)
+	return r
+}
+
+// NXDomain makes looking up name fail as if it didn't exist, which is also
+// what happens to names not in the table.
+func (r *Resolver) NXDomain(name string) *Resolver {
+	return r.Fail(name, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true})
+}
+
+// Timeout makes looking up name time out.
+func (r *Resolver) Timeout(name string) *Resolver {
+	return r.Fail(name, &net.DNSError{Err: "i/o timeout", Name: name, IsTimeout: true})
+}
+
+// Fail makes looking up name return err.
+func (r *Resolver) Fail(name string, err error) *Resolver {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	name = canonical(name)
+	r.errs[name] = err
+	delete(r.hosts, name)
+	return r
+}
+
+// lookup returns the addresses of host usable on network, e.g. "ip" or
+// "tcp4".
+func (r *Resolver) lookup(network, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	name := canonical(host)
+	if err, ok := r.errs[name]; ok {
+		return nil, err
+	}
+	only4, only6 := strings.HasSuffix(network, "4"), strings.HasSuffix(network, "6")
+	var ips []net.IP
+	for _, ip := range r.hosts[name] {
+		v4 := ip.To4() != nil
+		if !only4 && !only6 || only4 && v4 || only6 && !v4 {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return ips, nil
+}
+
+func (r *Resolver) lookupIPAddr(_ *net.Resolver, _ context.Context, network, host string) ([]net.IPAddr, error) {
+	ips, err := r.lookup(network, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]net.IPAddr, len(ips))
+	for i, ip := range ips {
+		addrs[i] = net.IPAddr{IP: ip}
+	}
+	return addrs, nil
+}
+
+func (r *Resolver) lookupHost(_ *net.Resolver, _ context.Context, host string) ([]string, error) {
+	ips, err := r.lookup("ip", host)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		addrs[i] = ip.String()
+	}
+	return addrs, nil
+}
+
+func canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,55 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package dnsmock_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/go-kiss/monkey/dnsmock"
+)
+
+func TestResolver(t *testing.T) {
+	dnsmock.New(t).
+		Host("api.example.com", "127.0.0.1", "::1").
+		NXDomain("gone.example.com").
+		Timeout("slow.example.com")
+
+	addrs, err := net.LookupHost("API.example.com.")
+	if err != nil || len(addrs) != 2 || addrs[0] != "127.0.0.1" {
+		t.Fatal(addrs, err)
+	}
+	ips, err := net.DefaultResolver.LookupIP(context.Background(), "ip6", "api.example.com")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.IPv6loopback) {
+		t.Fatal(ips, err)
+	}
+
+	var dnsErr *net.DNSError
+	_, err = net.LookupHost("gone.example.com")
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Fatal(err)
+	}
+	_, err = net.LookupIP("slow.example.com")
+	if !errors.As(err, &dnsErr) || !dnsErr.Timeout() {
+		t.Fatal(err)
+	}
+	_, err = net.LookupIP("unknown.example.com")
+	if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	c, err := net.Dial("tcp4", "api.example.com:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
@@ -0,0 +1,32 @@
+// Package monkey replaces functions at run time, for the calls made by the
+// goroutine making the patch unless options widen it, see Scope.
+//
+// # When patches are seen
+//
+// A target is patched by writing a jump to a stub at its start. The stub
+// hands the table of the patch, holding an entry per goroutine and one
+// shared by all, to the dispatcher, which runs the entry of the calling
+// goroutine, else the shared one, else the original. Tables are never
+// modified: every change stores a new one with a fence, and once the code of
+// a target is written every thread is made to serialize, see Quiesce. What a
+// call runs is decided when the dispatcher reads the table, and doesn't
+// change until the call returns. So, whatever GOMAXPROCS is:
+//
+//   - A patch applies to every call its goroutine makes after Patch returns,
+//     on whichever thread the goroutine runs. It never applies to calls of
+//     other goroutines, even those already in the stub, which run the same
+//     code whether they read the table from before or after the patch.
+//   - A patch applying to other goroutines, e.g. with ForLabels, applies to
+//     their calls that start after Patch returns in the sense of the Go
+//     memory model: after receiving from a channel the patching goroutine
+//     sent to afterwards, locking a mutex it unlocked, and so on. Calls
+//     running meanwhile, or not synchronized with it, run either the patch
+//     or what was there before, never a mix of both. Quiesce makes every
+//     call starting after it returns see the patch without synchronizing.
+//   - Unpatch is the same the other way around: calls already dispatched
+//     to the replacement finish in it.
+//
+// So a report of the original sometimes running is a call that wasn't
+// synchronized with Patch, or that was made on another goroutine than the
+// patch applies to, or a target inlined into its caller, see Verify.
+package monkey
@@ -0,0 +1,25 @@
+package monkey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/go-kiss/monkey/raw"
+)
+
+// ErrCodeChanged is returned when the code at the start of a target isn't
+// what the package left there, because something else, like a debugger or
+// another patching library, wrote to it since.
+var ErrCodeChanged = errors.New("monkey: code of target changed behind the package")
+
+// checkCode returns an error unless the target starts with want, what the
+// package last wrote there or found there. Writing over code changed since
+// would mix stale bytes with the new ones.
+func (p *patch) checkCode(want []byte) error {
+	found := raw.Memory(p.from, len(want))
+	if bytes.Equal(found, want) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s at %#x starts with % x, want % x", ErrCodeChanged, SymbolName(p.from), p.from, found, want)
+}
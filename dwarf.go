@@ -0,0 +1,345 @@
+package monkey
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// errNoDWARF is returned when the running binary has no DWARF, e.g. when it
+// is a test run by go test, which drops it unless given -ldflags=-w=0.
+var errNoDWARF = errors.New("monkey: no DWARF in the binary")
+
+// dwarfFunc is the signature of a function as found in DWARF.
+type dwarfFunc struct {
+	name            string
+	params, results []dwarfVar
+	// file and line tell where the function is declared, if known.
+	file string
+	line int64
+}
+
+// dwarfVar is a parameter or a result of a dwarfFunc.
+type dwarfVar struct {
+	name string
+	typ  dwarf.Type
+}
+
+var dwarfFuncs struct {
+	once sync.Once
+	data *dwarf.Data
+	// funcs maps the names of functions to their entries.
+	funcs map[string]*dwarfEntries
+	err   error
+}
+
+type dwarfEntries struct {
+	// code is the entry of the code of the function, named the one
+	// holding its name and where it is declared, in unit.
+	code, named dwarf.Offset
+	unit        *dwarf.Entry
+}
+
+// loadDWARF reads the DWARF of the running binary and indexes its
+// functions.
+func loadDWARF() {
+	d := &dwarfFuncs
+	d.data, d.err = openDWARF()
+	if d.err != nil {
+		return
+	}
+	d.funcs = make(map[string]*dwarfEntries)
+	// Functions also inlined somewhere are described once in an abstract
+	// entry holding their name, which the entry of their code refers to.
+	// Only the latter lists unnamed results.
+	concrete := make(map[dwarf.Offset]dwarf.Offset)
+	var unit *dwarf.Entry
+	r := d.data.Reader()
+	for {
+		e, err := r.Next()
+		if err != nil {
+			d.err = err
+			return
+		}
+		if e == nil {
+			break
+		}
+		if e.Tag == dwarf.TagCompileUnit {
+			unit = e
+		}
+		if e.Tag != dwarf.TagSubprogram {
+			continue
+		}
+		if name, ok := e.Val(dwarf.AttrName).(string); ok {
+			d.funcs[name] = &dwarfEntries{code: e.Offset, named: e.Offset, unit: unit}
+		} else if o, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ok {
+			concrete[o] = e.Offset
+		}
+		r.SkipChildren()
+	}
+	for _, f := range d.funcs {
+		if c, ok := concrete[f.named]; ok {
+			f.code = c
+		}
+	}
+}
+
+func openDWARF() (*dwarf.Data, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	var d *dwarf.Data
+	if f, err := elf.Open(exe); err == nil {
+		defer f.Close()
+		d, _ = f.DWARF()
+	} else if f, err := macho.Open(exe); err == nil {
+		defer f.Close()
+		d, _ = f.DWARF()
+	} else if f, err := pe.Open(exe); err == nil {
+		defer f.Close()
+		d, _ = f.DWARF()
+	}
+	if d == nil {
+		return nil, errNoDWARF
+	}
+	return d, nil
+}
+
+// lookupDWARF returns the signature of the function with the fully
+// qualified name.
+func lookupDWARF(name string) (*dwarfFunc, error) {
+	d := &dwarfFuncs
+	d.once.Do(loadDWARF)
+	if d.err != nil {
+		return nil, d.err
+	}
+	entries, ok := d.funcs[name]
+	if !ok {
+		return nil, fmt.Errorf("monkey: %s not found in DWARF", name)
+	}
+
+	f := &dwarfFunc{name: name}
+	named, err := entryAt(d.data, entries.named)
+	if err != nil {
+		return nil, err
+	}
+	f.line, _ = named.Val(dwarf.AttrDeclLine).(int64)
+	if i, ok := named.Val(dwarf.AttrDeclFile).(int64); ok {
+		f.file = unitFile(d.data, entries.unit, i)
+	}
+
+	r := d.data.Reader()
+	r.Seek(entries.code)
+	e, err := r.Next()
+	if err != nil {
+		return nil, err
+	}
+	if e.Val(dwarf.AttrInline) != nil {
+		// The abstract entry leaves out unnamed results.
+		return nil, fmt.Errorf("monkey: %s is only inlined", name)
+	}
+	if !e.Children {
+		return f, nil
+	}
+	for {
+		e, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if e == nil || e.Tag == 0 {
+			return f, nil
+		}
+		if e.Tag != dwarf.TagFormalParameter {
+			r.SkipChildren()
+			continue
+		}
+		if o, ok := e.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ok {
+			if e, err = entryAt(d.data, o); err != nil {
+				return nil, err
+			}
+		}
+		v := dwarfVar{}
+		v.name, _ = e.Val(dwarf.AttrName).(string)
+		if t, ok := e.Val(dwarf.AttrType).(dwarf.Offset); ok {
+			if v.typ, err = d.data.Type(t); err != nil {
+				return nil, err
+			}
+		}
+		if result, _ := e.Val(dwarf.AttrVarParam).(bool); result {
+			f.results = append(f.results, v)
+		} else {
+			f.params = append(f.params, v)
+		}
+	}
+}
+
+func entryAt(d *dwarf.Data, off dwarf.Offset) (*dwarf.Entry, error) {
+	r := d.Reader()
+	r.Seek(off)
+	return r.Next()
+}
+
+// unitFile returns the name of the file i of unit.
+func unitFile(d *dwarf.Data, unit *dwarf.Entry, i int64) string {
+	if unit == nil {
+		return ""
+	}
+	lr, err := d.LineReader(unit)
+	if err != nil || lr == nil {
+		return ""
+	}
+	files := lr.Files()
+	if i < 0 || i >= int64(len(files)) || files[i] == nil {
+		return ""
+	}
+	return files[i].Name
+}
+
+// Position returns where f is declared as "file:line", or "" if unknown.
+func (f *dwarfFunc) Position() string {
+	if f.file == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", f.file, f.line)
+}
+
+// describeFunc describes the function at pc of type t, with the names of
+// its parameters and where it is declared when DWARF has them.
+func describeFunc(pc uintptr, t reflect.Type) string {
+	name := SymbolName(pc)
+	f, err := lookupDWARF(name)
+	if err != nil {
+		return fmt.Sprintf("%s %s", name, t)
+	}
+	if p := f.Position(); p != "" {
+		return fmt.Sprintf("%s %s declared at %s", name, f, p)
+	}
+	return fmt.Sprintf("%s %s", name, f)
+}
+
+// String formats f as a func type with the names of its parameters, e.g.
+// "func(ctx context.Context, id string) error".
+func (f *dwarfFunc) String() string {
+	var b strings.Builder
+	b.WriteString("func(")
+	for i, p := range f.params {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if p.name != "" {
+			b.WriteString(p.name + " ")
+		}
+		b.WriteString(typeName(p.typ))
+	}
+	b.WriteString(")")
+	switch len(f.results) {
+	case 0:
+	case 1:
+		b.WriteString(" " + typeName(f.results[0].typ))
+	default:
+		b.WriteString(" (")
+		for i, r := range f.results {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(typeName(r.typ))
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+// check returns an error unless a function of type t can stand for f.
+// Types are compared by name where reflect can tell the name the compiler
+// gave them in DWARF, and by size otherwise.
+func (f *dwarfFunc) check(t reflect.Type) error {
+	ok := t.NumIn() == len(f.params) && t.NumOut() == len(f.results)
+	for i := 0; ok && i < t.NumIn(); i++ {
+		ok = sameType(t.In(i), f.params[i].typ)
+	}
+	for i := 0; ok && i < t.NumOut(); i++ {
+		ok = sameType(t.Out(i), f.results[i].typ)
+	}
+	if !ok && f.file != "" {
+		return fmt.Errorf("monkey: replacement for %s has to be a %s, not %s, see %s", f.name, f, t, f.Position())
+	}
+	if !ok {
+		return fmt.Errorf("monkey: replacement for %s has to be a %s, not %s", f.name, f, t)
+	}
+	return nil
+}
+
+func typeName(t dwarf.Type) string {
+	switch t := t.(type) {
+	case nil:
+		return "?"
+	case *dwarf.StructType:
+		// Strings, slices and interfaces are described as structs.
+		return t.StructName
+	case *dwarf.PtrType:
+		if t.Name == "" {
+			return "*" + typeName(t.Type)
+		}
+	}
+	return t.Common().Name
+}
+
+func sameType(t reflect.Type, d dwarf.Type) bool {
+	if d == nil {
+		return false
+	}
+	if name, ok := dwarfName(t); ok {
+		return name == typeName(d)
+	}
+	return int64(t.Size()) == d.Size()
+}
+
+// dwarfName returns the name the compiler gives t in DWARF, where package
+// paths qualify names instead of package names.
+func dwarfName(t reflect.Type) (string, bool) {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name(), true
+		}
+		return t.PkgPath() + "." + t.Name(), true
+	}
+	elem := func(prefix string) (string, bool) {
+		n, ok := dwarfName(t.Elem())
+		return prefix + n, ok
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return elem("*")
+	case reflect.Slice:
+		return elem("[]")
+	case reflect.Array:
+		return elem(fmt.Sprintf("[%d]", t.Len()))
+	case reflect.Chan:
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			return elem("<-chan ")
+		case reflect.SendDir:
+			return elem("chan<- ")
+		}
+		return elem("chan ")
+	case reflect.Map:
+		k, ok := dwarfName(t.Key())
+		if !ok {
+			return "", false
+		}
+		return elem("map[" + k + "]")
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface {}", true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,34 @@
+package monkey
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// ErrDynamicFunc is returned when patching a function made by
+// reflect.MakeFunc or a method value obtained through reflect.Value.Method.
+// All such functions run the same code and are told apart by their data,
+// so patching one would patch all of them.
+var ErrDynamicFunc = errors.New("monkey: cannot patch a function made by reflect, its code is shared with every other such function")
+
+var (
+	dynamicOnce sync.Once
+	// dynamicCode holds the code pointers shared by functions made by
+	// reflect.
+	dynamicCode [2]uintptr
+)
+
+type dynamicProbe struct{}
+
+func (dynamicProbe) M() {}
+
+func isDynamicFunc(pc uintptr) bool {
+	dynamicOnce.Do(func() {
+		f := reflect.MakeFunc(reflect.TypeOf(func() {}), func([]reflect.Value) []reflect.Value { return nil })
+		m := reflect.ValueOf(dynamicProbe{}).Method(0)
+		dynamicCode[0] = reflect.ValueOf(f.Interface()).Pointer()
+		dynamicCode[1] = reflect.ValueOf(m.Interface()).Pointer()
+	})
+	return pc == dynamicCode[0] || pc == dynamicCode[1]
+}
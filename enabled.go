@@ -0,0 +1,6 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+const disabled = false
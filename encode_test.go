@@ -0,0 +1,135 @@
+//go:build go1.18 && !monkey_disabled
+// +build go1.18,!monkey_disabled
+
+package monkey
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// fuzzFrom is where the code fuzzed is taken to be.
+const fuzzFrom = 0x400000
+
+func FuzzRelocate(f *testing.F) {
+	f.Add(append([]byte(nil), raw.Memory(reflect.ValueOf(relocate).Pointer(), 32)...))
+	// cmp rsp,[r14+16]; jbe +0x20; push rbp; mov rbp,rsp; sub rsp,0x20
+	f.Add([]byte{0x49, 0x3B, 0x66, 0x10, 0x76, 0x20, 0x55, 0x48, 0x89, 0xE5, 0x48, 0x83, 0xEC, 0x20})
+	// a jcc rel32 and a jmp rel8 back into the prologue
+	f.Add([]byte{0x0F, 0x86, 0x00, 0x01, 0x00, 0x00, 0x90, 0x90, 0x90, 0x90, 0x90, 0x90, 0xEB, 0xF2})
+	// mov rax,[rip+0x10]; lea r12,[rip-0x20], as PIE code reaches globals
+	f.Add([]byte{0x48, 0x8B, 0x05, 0x10, 0x00, 0x00, 0x00, 0x4C, 0x8D, 0x25, 0xE0, 0xFF, 0xFF, 0xFF})
+	f.Fuzz(func(t *testing.T, code []byte) {
+		code = append(code, bytes.Repeat([]byte{0xCC}, 32)...)[:32]
+		original, err := alignPrologue(code, fuzzFrom)
+		if err != nil {
+			return
+		}
+		if len(original) < raw.JmpSize || !bytes.Equal(original, code[:len(original)]) {
+			t.Fatalf("prologue % x of % x", original, code)
+		}
+		moved, err := relocate(original, fuzzFrom)
+		if err != nil {
+			return
+		}
+		checkMoved(t, original, moved)
+	})
+}
+
+// checkMoved checks moved runs like original would at fuzzFrom: relative
+// instructions of original are absolute jumps to the same place out of the
+// rewritten bytes, or conditional skips over such jumps.
+func checkMoved(t *testing.T, original, moved []byte) {
+	var targets []uintptr
+	for s := 0; s < len(original); {
+		i, _ := x86asm.Decode(original[s:], 64)
+		s += i.Len
+		if rel, ok := i.Args[0].(x86asm.Rel); ok {
+			targets = append(targets, fuzzFrom+uintptr(s)+uintptr(int64(rel)))
+		}
+	}
+	for s := 0; s < len(moved); {
+		i, err := x86asm.Decode(moved[s:], 64)
+		if err != nil {
+			t.Fatalf("moved code % x at %d: %v", moved, s, err)
+		}
+		for _, a := range i.Args {
+			if m, ok := a.(x86asm.Mem); ok && m.Base == x86asm.RIP {
+				t.Fatalf("moved code % x is relative to rip: %v", moved, i)
+			}
+		}
+		if rel, ok := i.Args[0].(x86asm.Rel); ok && int(rel) != raw.JmpSize {
+			t.Fatalf("moved code % x has a relative jump: %v", moved, i)
+		}
+		if isJump(moved[s:]) {
+			to := uintptr(binary.LittleEndian.Uint64(moved[s+2:]))
+			if to > fuzzFrom && to < fuzzFrom+uintptr(len(original)) {
+				t.Fatalf("moved code % x jumps into the rewritten bytes", moved)
+			}
+			if len(targets) == 0 || targets[0] != to {
+				t.Fatalf("moved code % x jumps to %#x, want %v", moved, to, targets)
+			}
+			targets = targets[1:]
+			s += raw.JmpSize
+			continue
+		}
+		s += i.Len
+	}
+	if len(targets) > 0 {
+		t.Fatalf("moved code % x lost the jumps to %#x", moved, targets)
+	}
+}
+
+// isJump reports whether code starts with a jump assembled by JmpStub.
+func isJump(code []byte) bool {
+	if len(code) < raw.JmpSize {
+		return false
+	}
+	to := uintptr(binary.LittleEndian.Uint64(code[2:]))
+	return bytes.Equal(code[:raw.JmpSize], raw.JmpStub(to))
+}
+
+func FuzzMarshal(f *testing.F) {
+	f.Add([]byte{3, 1, 2}, false)
+	f.Add([]byte{7}, true)
+	f.Fuzz(func(t *testing.T, gids []byte, shared bool) {
+		var trampoline byte
+		p := &patch{trampoline: unsafe.Pointer(&trampoline)}
+		entries := make(map[uintptr]*entry)
+		for i, b := range gids {
+			entries[uintptr(b)+1] = &entry{seq: uint64(len(gids) - i)}
+		}
+		if shared {
+			entries[anyG] = &entry{seq: 0}
+		}
+		p.setEntries(entries)
+
+		table := p.Marshal()
+		if len(table) != len(entries)+1 {
+			t.Fatalf("%d entries for %d patches", len(table), len(entries))
+		}
+		last := table[len(table)-1]
+		if last.g != 0 || last.e != p.trampoline {
+			t.Fatalf("table ends with %+v", last)
+		}
+		for i, d := range table[:len(table)-1] {
+			e := (*entry)(d.e)
+			switch {
+			case d.g == 0:
+				t.Fatalf("entry %d ends the table early", i)
+			case entries[d.g] != e:
+				t.Fatalf("entry %d is not the one of goroutine %#x", i, d.g)
+			case d.g == anyG && i != len(table)-2:
+				t.Fatalf("shared entry at %d of %d", i, len(table))
+			case i > 0 && d.g != anyG && (*entry)(table[i-1].e).seq > e.seq:
+				t.Fatalf("entry %d added before entry %d", i, i-1)
+			}
+		}
+	})
+}
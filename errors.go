@@ -0,0 +1,84 @@
+package monkey
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// PatchError is returned, or panicked with, when a patch can't be made or
+// removed. It tells which function, where from and on which goroutine, and
+// where the patch in the way was made if there is one. errors.Is and
+// errors.As see through it to the cause, e.g. ErrIntrinsic.
+type PatchError struct {
+	// Op is what failed, "patch" or "unpatch".
+	Op string
+	// Target is the fully qualified name of the function, e.g.
+	// "net/http.(*Client).Do".
+	Target string
+	// Goroutine is the ID of the goroutine making the call.
+	Goroutine uint64
+	// Site is the file and line of the call, the first one outside of
+	// this package.
+	Site string
+	// Conflict is where the patch in the way was made, if any, and
+	// ConflictStack the stack then, see PatchGuard.CreationStack.
+	Conflict      string
+	ConflictStack string
+	Err           error
+}
+
+func (e *PatchError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "monkey: %s %s at %s on goroutine %d: %v", e.Op, e.Target, e.Site, e.Goroutine, e.Err)
+	if e.Conflict != "" {
+		fmt.Fprintf(&b, ", patched at %s", e.Conflict)
+	}
+	if e.ConflictStack != "" {
+		fmt.Fprintf(&b, " by:\n%s", e.ConflictStack)
+	}
+	return b.String()
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// patchError returns err as a PatchError for op on target, filling in a
+// PatchError holding only a Conflict and its Err. ErrDisabled is returned
+// as is, like nil.
+func patchError(op, target string, err error) error {
+	if err == nil || err == ErrDisabled {
+		return err
+	}
+	e, ok := err.(*PatchError)
+	if ok && e.Op != "" {
+		return err
+	}
+	if !ok {
+		e = &PatchError{Err: err}
+	}
+	e.Op, e.Target, e.Site = op, target, siteString(callSite())
+	e.Goroutine, _ = stackGoid()
+	return e
+}
+
+// siteString formats f as file:line.
+func siteString(f runtime.Frame) string {
+	if f.File == "" {
+		return "unknown location"
+	}
+	return fmt.Sprintf("%s:%d", f.File, f.Line)
+}
+
+// describeTarget names the target of g for errors, even when it isn't a
+// function.
+func (g *PatchGuard) describeTarget() string {
+	if name := g.TargetName(); name != "" {
+		return name
+	}
+	if !g.target.IsValid() {
+		return "nil"
+	}
+	return g.target.Type().String()
+}
@@ -0,0 +1,112 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
+)
+
+// ErrUnsupportedRuntime is returned by every patching function when the
+// dispatcher can't tell goroutines apart on the running platform and Go
+// release, which happens when the runtime moves g somewhere else.
+var ErrUnsupportedRuntime = errors.New("monkey: unsupported runtime")
+
+// gLoader is how the dispatcher finds the g of the running goroutine. Each
+// platform and range of Go releases defines its own as gLoad.
+type gLoader struct {
+	// name tells where g is read from.
+	name string
+	// code loads g into r12, leaving every other register alone.
+	code []byte
+}
+
+var (
+	// dispatch is the address of the dispatcher shared by all stubs,
+	// dispatchOwn that of its code handling the entries of the current
+	// goroutine, which stubs dispatching directly jump to.
+	dispatch    uintptr
+	dispatchOwn uintptr
+	// dispatchErr is why the dispatcher can't be used, once it failed
+	// its check.
+	dispatchErr error
+	// canaryPatch patches canary while the dispatcher is checked. The
+	// stub of canary refers to it, so it is kept forever.
+	canaryPatch *patch
+)
+
+// loadDispatcher puts the dispatcher in executable memory once, and checks
+// it before any real target jumps to it.
+func loadDispatcher() error {
+	if dispatchErr != nil || dispatch != 0 {
+		return dispatchErr
+	}
+	ret, err := guardReturn()
+	if err != nil {
+		return err
+	}
+	code, own := dispatcher(ret)
+	b, err := raw.AllocExecutable(len(code))
+	if err != nil {
+		return err
+	}
+	copy(b, code)
+	dispatch = uintptr(unsafe.Pointer(&b[0]))
+	dispatchOwn = uintptr(unsafe.Pointer(&b[own]))
+	addStub(dispatch, len(b), "monkey dispatcher")
+
+	if err := checkDispatch(); err != nil {
+		dispatchErr = fmt.Errorf("%w: %s on %s/%s, reading g from %s: %v",
+			ErrUnsupportedRuntime, runtime.Version(), runtime.GOOS, runtime.GOARCH, gLoad.name, err)
+	}
+	return dispatchErr
+}
+
+//go:noinline
+func canary(n int) int {
+	return canaryStep(n) + 1
+}
+
+//go:noinline
+func canaryStep(n int) int {
+	return n * 2
+}
+
+// checkDispatch patches canary for the current goroutine only, and makes
+// sure calls are sent to the replacement on this goroutine and to the
+// original on another one. The g of go-tls is checked first, if used.
+func checkDispatch() error {
+	if gSource == GoTLS {
+		if err := checkGoTLS(); err != nil {
+			return err
+		}
+	}
+	if canaryPatch == nil {
+		canaryPatch = &patch{from: reflect.ValueOf(canary).Pointer()}
+	}
+	p := canaryPatch
+	replacement := reflect.ValueOf(func(n int) int { return -n })
+	e := &entry{to: uintptr(getPtr(replacement)), fn: replacement}
+	gid := curG()
+	p.setEntries(map[uintptr]*entry{gid: e})
+	if err := p.Apply(); err != nil {
+		return err
+	}
+	defer func() {
+		p.setEntries(nil)
+		p.store(p.Marshal())
+	}()
+
+	if v := canary(1); v != -1 {
+		return fmt.Errorf("patched call returned %d, want -1", v)
+	}
+	c := make(chan int)
+	go func() { c <- canary(1) }()
+	if v := <-c; v != 3 {
+		return fmt.Errorf("call on another goroutine returned %d, want 3", v)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package monkey
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+)
+
+// HandleFatalSignals makes the signals ending the process, like SIGTERM
+// sent by a CI runner killing a stuck test or SIGQUIT, first put back the
+// original code of every patched function and write the patches in place to
+// w. The signal is then raised again, so that the process dies as it would
+// have, but with a goroutine dump and a core showing the program as built,
+// and a list telling which functions were not. It returns a function
+// removing the handler.
+//
+// Faults in patched code, like SIGSEGV or SIGILL, are handled by the runtime
+// before any handler runs and are not covered: see SetStubMapFile to tell
+// the addresses of stubs in their reports.
+func HandleFatalSignals(w io.Writer) (stop func()) {
+	c := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(c, fatalSignals...)
+	go func() {
+		select {
+		case sig := <-c:
+			l := ListPatches()
+			restoreAll()
+			fmt.Fprintf(w, "monkey: %v received, original code restored, %d functions were patched:\n", sig, len(l))
+			for _, p := range l {
+				fmt.Fprintf(w, "\t%s\n", p)
+			}
+			if f, ok := w.(interface{ Sync() error }); ok {
+				f.Sync()
+			}
+			signal.Stop(c)
+			reraise(sig)
+		case <-done:
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+	}
+}
+
+// restoreAll puts back the original code of every patched function, leaving
+// the patches in place otherwise, for a process about to die.
+func restoreAll() {
+	lock.Lock()
+	defer lock.Unlock()
+	for from, p := range loadRegistry().patches {
+		if p.active {
+			if err := unpatch(from, p); err != nil {
+				logf("restoring %s: %v", SymbolName(from), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build !windows && !monkey_disabled
+// +build !windows,!monkey_disabled
+
+package monkey_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kiss/monkey"
+	"github.com/go-kiss/monkey/raw"
+)
+
+// restoredWriter fails the write unless fn has its original code back.
+type restoredWriter struct {
+	fn       uintptr
+	original []byte
+}
+
+func (w restoredWriter) Write(b []byte) (int, error) {
+	if !bytes.Equal(raw.Memory(w.fn, len(w.original)), w.original) {
+		os.Stderr.WriteString("code not restored\n")
+	}
+	return os.Stderr.Write(b)
+}
+
+func TestHandleFatalSignals(t *testing.T) {
+	if os.Getenv("MONKEY_TEST_FATAL") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHandleFatalSignals$")
+		cmd.Env = append(os.Environ(), "MONKEY_TEST_FATAL=1")
+		out, err := cmd.CombinedOutput()
+		status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus)
+		assert(t, ok && status.Signaled() && status.Signal() == syscall.SIGTERM, err, string(out))
+		assert(t, strings.Contains(string(out), "monkey: terminated received, original code restored, 1 functions were patched:"), string(out))
+		assert(t, strings.Contains(string(out), "monkey_test.no"), string(out))
+		assert(t, !strings.Contains(string(out), "code not restored"), string(out))
+		return
+	}
+
+	fn := reflect.ValueOf(no).Pointer()
+	w := restoredWriter{fn, append([]byte(nil), raw.Memory(fn, raw.JmpSize)...)}
+	defer monkey.HandleFatalSignals(w)()
+	monkey.Patch(no, yes)
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	time.Sleep(10 * time.Second)
+	t.Fatal("still alive")
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package monkey
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var fatalSignals = []os.Signal{syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGINT, syscall.SIGHUP, syscall.SIGABRT}
+
+// reraise sends sig to the process again, with its default handling.
+func reraise(sig os.Signal) {
+	signal.Reset(sig)
+	syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+}
@@ -0,0 +1,11 @@
+package monkey
+
+import "os"
+
+var fatalSignals = []os.Signal{os.Interrupt}
+
+// reraise exits like the runtime does on an interrupt, which can't be sent
+// again to the process.
+func reraise(os.Signal) {
+	os.Exit(2)
+}
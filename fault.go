@@ -0,0 +1,144 @@
+package monkey
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fault is a failure injected into a function by RunFaultMatrix.
+type Fault struct {
+	// Name identifies the fault in subtest names.
+	Name string
+	// Err, if set, is returned as the last result, all other results
+	// being zero.
+	Err error
+	// Panic, if set, is panicked with.
+	Panic interface{}
+	// Delay is waited before the call goes on, to the original function
+	// unless Err or Panic is set.
+	Delay time.Duration
+}
+
+// ErrorFault makes the function return err.
+func ErrorFault(err error) Fault {
+	return Fault{Name: "error", Err: err}
+}
+
+// PanicFault makes the function panic with v.
+func PanicFault(v interface{}) Fault {
+	return Fault{Name: "panic", Panic: v}
+}
+
+// DelayFault makes the function wait for d before running.
+func DelayFault(d time.Duration) Fault {
+	return Fault{Name: "delay", Delay: d}
+}
+
+// FaultSpec lists the faults RunFaultMatrix injects into Target.
+type FaultSpec struct {
+	Target interface{}
+	Faults []Fault
+}
+
+// RunFaultMatrix runs body as a subtest for every combination of faults,
+// each target getting either one of its faults or none. The combination
+// where no fault is injected is skipped. Patches only last for their
+// subtest.
+func RunFaultMatrix(t *testing.T, targets []FaultSpec, body func(t *testing.T)) {
+	t.Helper()
+
+	// pick[i] is the fault injected into targets[i], 0 meaning none.
+	pick := make([]int, len(targets))
+	for next(pick, targets) {
+		var names []string
+		for i, p := range pick {
+			if p > 0 {
+				names = append(names, shortName(targets[i].Target)+"="+targets[i].Faults[p-1].Name)
+			}
+		}
+
+		pick := append([]int(nil), pick...)
+		t.Run(strings.Join(names, ","), func(t *testing.T) {
+			s := NewSession()
+			defer s.Unpatch()
+			for i, p := range pick {
+				if p > 0 {
+					if err := injectFault(s, targets[i].Target, targets[i].Faults[p-1]); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			body(t)
+		})
+	}
+}
+
+// next advances pick to the following combination, like an odometer.
+func next(pick []int, targets []FaultSpec) bool {
+	for i := range pick {
+		pick[i]++
+		if pick[i] <= len(targets[i].Faults) {
+			return true
+		}
+		pick[i] = 0
+	}
+	return false
+}
+
+func shortName(target interface{}) string {
+	return path.Base(SymbolName(reflect.ValueOf(target).Pointer()))
+}
+
+func injectFault(s *Session, target interface{}, f Fault) error {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return fmt.Errorf("fault target %v has to be a Func", target)
+	}
+	typ := t.Type()
+	if f.Err != nil && (typ.NumOut() == 0 || typ.Out(typ.NumOut()-1) != errorType) {
+		return fmt.Errorf("cannot inject error into %s, its last result is not an error", shortName(target))
+	}
+
+	var g *PatchGuard
+	r := reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		time.Sleep(f.Delay)
+		if f.Panic != nil {
+			panic(f.Panic)
+		}
+		if f.Err != nil {
+			out := zeroResults(typ)
+			out[len(out)-1] = reflect.ValueOf(f.Err)
+			return out
+		}
+		return callOriginal(g, in)
+	})
+
+	g, err := TryPatch(target, r.Interface())
+	if err != nil {
+		return err
+	}
+	s.Track(g)
+	return nil
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func zeroResults(t reflect.Type) []reflect.Value {
+	out := make([]reflect.Value, t.NumOut())
+	for i := range out {
+		out[i] = reflect.Zero(t.Out(i))
+	}
+	return out
+}
+
+// call calls fn with in as passed to a function made by reflect.MakeFunc.
+func call(fn reflect.Value, in []reflect.Value) []reflect.Value {
+	if fn.Type().IsVariadic() {
+		return fn.CallSlice(in)
+	}
+	return fn.Call(in)
+}
@@ -0,0 +1,168 @@
+package monkey
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// PatchSpec describes a patch by data instead of a replacement written in
+// Go, so that fixtures of patches can be written to a file and loaded by
+// other test binaries, see Session.Export and LoadFixture. A patched call
+// waits for Delay, then panics with Panic if set, returns Error as its last
+// result if set, returns Returns if set, and runs the original otherwise.
+type PatchSpec struct {
+	// Func is the fully qualified name of the target, as printed by
+	// SymbolName. Session.Declare fills it in.
+	Func string `json:"func"`
+	// Returns are the results of the target in JSON.
+	Returns []json.RawMessage `json:"returns,omitempty"`
+	// Error is the message of the error returned, with the other results
+	// from Returns or zero.
+	Error string `json:"error,omitempty"`
+	// Panic is the string panicked with.
+	Panic string `json:"panic,omitempty"`
+	// Delay is in the format of time.ParseDuration, e.g. "150ms".
+	Delay string `json:"delay,omitempty"`
+}
+
+// ReturnSpec returns a spec making the target return vals, which are
+// marshaled to JSON.
+func ReturnSpec(vals ...interface{}) (PatchSpec, error) {
+	s := PatchSpec{Returns: make([]json.RawMessage, len(vals))}
+	for i, v := range vals {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return PatchSpec{}, fmt.Errorf("monkey: result %d: %w", i, err)
+		}
+		s.Returns[i] = b
+	}
+	return s, nil
+}
+
+// Declare patches target as described by spec and records the patch in s.
+func (s *Session) Declare(target interface{}, spec PatchSpec, opts ...PatchOption) (*PatchGuard, error) {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return nil, errors.New("target has to be a Func")
+	}
+	spec.Func = SymbolName(t.Pointer())
+	r, err := specReplacement(t.Type(), spec)
+	if err != nil {
+		return nil, err
+	}
+	var g *PatchGuard
+	wrapped := reflect.MakeFunc(t.Type(), func(in []reflect.Value) []reflect.Value {
+		if out := r(); out != nil {
+			return out
+		}
+		return callOriginal(g, in)
+	})
+	g, err = TryPatch(target, wrapped.Interface(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	g.spec = &spec
+	return s.Track(g), nil
+}
+
+// specReplacement returns the behavior described by spec for a function of
+// type typ. It returns nil results when the original has to be called.
+func specReplacement(typ reflect.Type, spec PatchSpec) (func() []reflect.Value, error) {
+	var delay time.Duration
+	if spec.Delay != "" {
+		d, err := time.ParseDuration(spec.Delay)
+		if err != nil {
+			return nil, fmt.Errorf("monkey: delay of %s: %w", spec.Func, err)
+		}
+		delay = d
+	}
+	n := typ.NumOut()
+	if spec.Error != "" && (n == 0 || typ.Out(n-1) != errorType) {
+		return nil, fmt.Errorf("cannot inject error into %s, its last result is not an error", spec.Func)
+	}
+	var out []reflect.Value
+	if len(spec.Returns) > 0 || spec.Error != "" {
+		out = zeroResults(typ)
+	}
+	if len(spec.Returns) > 0 {
+		want := n
+		if spec.Error != "" {
+			want--
+		}
+		if len(spec.Returns) != want {
+			return nil, fmt.Errorf("monkey: %d results for %s, want %d", len(spec.Returns), spec.Func, want)
+		}
+		for i, b := range spec.Returns {
+			v := reflect.New(typ.Out(i))
+			if err := json.Unmarshal(b, v.Interface()); err != nil {
+				return nil, fmt.Errorf("monkey: result %d of %s: %w", i, spec.Func, err)
+			}
+			out[i] = v.Elem()
+		}
+	}
+	if spec.Error != "" {
+		out[n-1] = reflect.ValueOf(errors.New(spec.Error))
+	}
+	return func() []reflect.Value {
+		time.Sleep(delay)
+		if spec.Panic != "" {
+			panic(spec.Panic)
+		}
+		return out
+	}, nil
+}
+
+// Export returns the specs of the patches recorded in s, in the order they
+// were made. It fails if one of them was not made by Declare.
+func (s *Session) Export() ([]PatchSpec, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	specs := make([]PatchSpec, 0, len(s.guards))
+	for _, g := range s.guards {
+		if g.spec == nil {
+			return nil, fmt.Errorf("monkey: patch of %s has no spec, see Declare", g.TargetName())
+		}
+		specs = append(specs, *g.spec)
+	}
+	return specs, nil
+}
+
+// WriteFixture writes the specs of the patches recorded in s to w in JSON,
+// for LoadFixture.
+func (s *Session) WriteFixture(w io.Writer) error {
+	specs, err := s.Export()
+	if err != nil {
+		return err
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(specs)
+}
+
+// LoadFixture reads specs written by WriteFixture from r and declares them
+// in s. The functions patched have to be among targets, which give their
+// types: names are not enough to call a function safely.
+func LoadFixture(s *Session, r io.Reader, targets ...interface{}) error {
+	var specs []PatchSpec
+	if err := json.NewDecoder(r).Decode(&specs); err != nil {
+		return fmt.Errorf("monkey: reading fixture: %w", err)
+	}
+	byName := make(map[string]interface{}, len(targets))
+	for _, t := range targets {
+		byName[SymbolName(reflect.ValueOf(t).Pointer())] = t
+	}
+	for _, spec := range specs {
+		t, ok := byName[spec.Func]
+		if !ok {
+			return fmt.Errorf("monkey: fixture patches %s, which is not among the targets", spec.Func)
+		}
+		if _, err := s.Declare(t, spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
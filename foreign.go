@@ -0,0 +1,45 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-kiss/monkey/raw"
+)
+
+// ErrForeignPatch is returned when patching a function another patching
+// library already jumps out of, without ChainForeignPatches.
+var ErrForeignPatch = errors.New("monkey: target is patched by another library")
+
+// ChainForeignPatches lets the patch apply over one made by another
+// library, like bouk/monkey or gomonkey, whose jump is then run in place of
+// the original function: calls the patch lets through go to the replacement
+// of the other library. Unpatching writes its jump back. Unpatch with the
+// other library last, or it writes the original code over the jump of this
+// package, which then fails to unpatch with ErrCodeChanged.
+func ChainForeignPatches() PatchOption {
+	return func(c *patchConfig) {
+		c.chain = true
+	}
+}
+
+// foreignPrologue returns the code to save and the code to move into the
+// trampoline of the function at from when another library jumps out of it,
+// or ok false if none does.
+func (p *patch) foreignPrologue() (original, moved []byte, ok bool, err error) {
+	code := raw.Memory(p.from, 16)
+	n, by := foreignJump(code)
+	if n == 0 {
+		return nil, nil, false, nil
+	}
+	if !p.chain {
+		return nil, nil, false, fmt.Errorf("%w: %s starts with the jump of %s, pass ChainForeignPatches to go through it", ErrForeignPatch, SymbolName(p.from), by)
+	}
+	size := n
+	if size < raw.JmpSize {
+		size = raw.JmpSize
+	}
+	// The jump never falls through to what follows it, the leftover of
+	// the original prologue, so that it doesn't have to be moved.
+	return append([]byte(nil), code[:size]...), append([]byte(nil), code[:n]...), true, nil
+}
@@ -0,0 +1,13 @@
+package monkey
+
+import (
+	"flag"
+)
+
+// IsFuzzWorker reports whether the process is a worker started by
+// go test -fuzz. Workers run the fuzz function in their own process, so
+// patches made by the coordinating process never reach them.
+func IsFuzzWorker() bool {
+	f := flag.Lookup("test.fuzzworker")
+	return f != nil && f.Value.String() == "true"
+}
@@ -0,0 +1,35 @@
+//go:build go1.18
+// +build go1.18
+
+package monkey
+
+import (
+	"reflect"
+)
+
+// FuzzBody wraps the function given to (*testing.F).Fuzz so that setup runs
+// at the start of every fuzz iteration, on the goroutine running it, and
+// its patches are removed when the iteration ends:
+//
+//	f.Fuzz(monkey.FuzzBody(func(s *monkey.Session) {
+//		s.Patch(time.Now, fakeNow)
+//	}, func(t *testing.T, data []byte) {
+//		...
+//	}))
+//
+// Patches made directly in the FuzzXxx function only apply to its own
+// goroutine, never to the iterations, which run on other goroutines and
+// with -fuzz in worker processes.
+func FuzzBody[F any](setup func(s *Session), body F) F {
+	fn := reflect.ValueOf(body)
+	if fn.Kind() != reflect.Func || fn.Type().NumIn() == 0 {
+		panic("body has to be a fuzz function taking *testing.T first")
+	}
+
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		s := NewSession()
+		defer s.Unpatch()
+		setup(s)
+		return fn.Call(in)
+	}).Interface().(F)
+}
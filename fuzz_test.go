@@ -0,0 +1,30 @@
+//go:build go1.18 && !monkey_disabled
+// +build go1.18,!monkey_disabled
+
+package monkey_test
+
+import (
+	"testing"
+
+	"github.com/go-kiss/monkey"
+)
+
+//go:noinline
+func checksum(b []byte) int {
+	n := 0
+	for _, c := range b {
+		n += int(c)
+	}
+	return n
+}
+
+func FuzzFuzzBody(f *testing.F) {
+	f.Add([]byte("seed"))
+	f.Fuzz(monkey.FuzzBody(func(s *monkey.Session) {
+		s.Patch(checksum, func(b []byte) int { return -len(b) })
+	}, func(t *testing.T, b []byte) {
+		if checksum(b) != -len(b) {
+			t.Fatal("patch not applied in fuzz iteration")
+		}
+	}))
+}
@@ -0,0 +1,203 @@
+//go:build ignore
+// +build ignore
+
+// gen_shapes writes shapes_test.go, which patches functions of many shapes
+// and checks arguments and results go through the stub and the trampoline
+// unharmed. Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+// kind is a type functions of the matrix take or return, with the literals
+// passed as argument, returned by the replacement and by the original.
+type kind struct {
+	typ           string
+	arg, ret, org string
+}
+
+var kinds = []kind{
+	{"int8", "-3", "7", "1"},
+	{"uint16", "65535", "2", "3"},
+	{"int64", "-1 << 40", "1 << 50", "4"},
+	{"float32", "1.5", "-0.25", "8"},
+	{"float64", "-2.25", "1e300", "16"},
+	{"complex128", "complex(1, -2)", "complex(-3, 4)", "0"},
+	{"string", `"monkey"`, `"patched"`, `"original"`},
+	{"bool", "true", "true", "false"},
+	{"[3]int64", "[3]int64{1, 2, 3}", "[3]int64{4, 5, 6}", "[3]int64{}"},
+	{"shapeBig", "shapeBig{1, 2, 3}", "shapeBig{-1, -2, -3}", "shapeBig{}"},
+	{"shapeMixed", "shapeMixed{0.5, -1, true}", "shapeMixed{2.5, 3, false}", "shapeMixed{}"},
+	{"[]byte", `[]byte("in")`, `[]byte("out")`, "nil"},
+	{"*int", "&shapeInt", "&shapeInt", "nil"},
+	{"interface{}", "shapeBig{7, 8, 9}", `"any"`, "nil"},
+	{"error", "errShapeIn", "errShapeOut", "nil"},
+}
+
+const (
+	maxArgs    = 6
+	maxResults = 4
+	rotations  = 3
+)
+
+func main() {
+	var b bytes.Buffer
+	b.WriteString(`// Code generated by gen_shapes.go; DO NOT EDIT.
+
+//go:generate go run gen_shapes.go
+
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-kiss/monkey"
+)
+
+type shapeBig struct{ A, B, C int64 }
+
+type shapeMixed struct {
+	F float64
+	I int32
+	B bool
+}
+
+//go:noinline
+func shapeCall(*[4]int64) {}
+
+var (
+	shapeInt    = 42
+	errShapeIn  = errors.New("in")
+	errShapeOut = errors.New("out")
+)
+
+`)
+	var tests []string
+	n := 0
+	for args := 0; args <= maxArgs; args++ {
+		for results := 0; results <= maxResults; results++ {
+			for r := 0; r < rotations; r++ {
+				if args+results == 0 && r > 0 {
+					continue
+				}
+				var in, out []kind
+				for i := 0; i < args; i++ {
+					in = append(in, kinds[(n+i*5+r)%len(kinds)])
+				}
+				for i := 0; i < results; i++ {
+					out = append(out, kinds[(n+i*3+r*7+1)%len(kinds)])
+				}
+				name := fmt.Sprintf("shape%d", n)
+				writeShape(&b, name, in, out)
+				tests = append(tests, name)
+				n++
+			}
+		}
+	}
+
+	b.WriteString("var shapeTests = []struct {\n\tname string\n\ttest func(t *testing.T, opts ...monkey.PatchOption)\n}{\n")
+	for _, name := range tests {
+		fmt.Fprintf(&b, "\t{%q, test%s},\n", name, strings.Title(name))
+	}
+	b.WriteString(`}
+
+// TestShapes calls the replacements through reflect, as the reentry guard
+// does, and directly.
+func TestShapes(t *testing.T) {
+	for _, s := range shapeTests {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			t.Run("guarded", func(t *testing.T) { s.test(t) })
+			t.Run("direct", func(t *testing.T) { s.test(t, monkey.AllowReentry()) })
+		})
+	}
+}
+`)
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalf("formatting: %v\n%s", err, b.Bytes())
+	}
+	if err := ioutil.WriteFile("shapes_test.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// writeShape writes the function name, taking in and returning out, and the
+// test patching it.
+func writeShape(b *bytes.Buffer, name string, in, out []kind) {
+	params := make([]string, len(in))
+	args := make([]string, len(in))
+	got := make([]string, len(in))
+	for i, k := range in {
+		params[i] = fmt.Sprintf("a%d %s", i, k.typ)
+		args[i] = fmt.Sprintf("%s(%s)", conv(k.typ), k.arg)
+		got[i] = fmt.Sprintf("a%d", i)
+	}
+	types := make([]string, len(out))
+	rets := make([]string, len(out))
+	orgs := make([]string, len(out))
+	vars := make([]string, len(out))
+	for i, k := range out {
+		types[i] = k.typ
+		rets[i] = fmt.Sprintf("%s(%s)", conv(k.typ), k.ret)
+		orgs[i] = fmt.Sprintf("%s(%s)", conv(k.typ), k.org)
+		vars[i] = fmt.Sprintf("r%d", i)
+	}
+	sig := fmt.Sprintf("func(%s) (%s)", strings.Join(params, ", "), strings.Join(types, ", "))
+
+	// The call and the local give the original a prologue of more than
+	// the 13 bytes rewritten: a call, or an instruction relative to rip
+	// as small leaf functions may start with, can't be moved.
+	fmt.Fprintf(b, "//go:noinline\nfunc %s%s {\n\tvar frame [4]int64\n\tshapeCall(&frame)\n", name, sig[len("func"):])
+	if len(out) > 0 {
+		fmt.Fprintf(b, "\treturn %s\n", strings.Join(orgs, ", "))
+	}
+	b.WriteString("}\n\n")
+
+	fmt.Fprintf(b, "func test%s(t *testing.T, opts ...monkey.PatchOption) {\n", strings.Title(name))
+	fmt.Fprintf(b, "\twant := []interface{}{%s}\n", strings.Join(args, ", "))
+	if len(out) > 0 {
+		fmt.Fprintf(b, "\tret := []interface{}{%s}\n", strings.Join(rets, ", "))
+		fmt.Fprintf(b, "\torg := []interface{}{%s}\n", strings.Join(orgs, ", "))
+	}
+	fmt.Fprintf(b, "\tg := monkey.Patch(%s, %s {\n", name, sig)
+	fmt.Fprintf(b, "\t\tif got := []interface{}{%s}; !reflect.DeepEqual(got, want) {\n", strings.Join(got, ", "))
+	b.WriteString("\t\t\tt.Errorf(\"replacement got %v, want %v\", got, want)\n\t\t}\n")
+	if len(out) > 0 {
+		fmt.Fprintf(b, "\t\treturn %s\n", strings.Join(rets, ", "))
+	}
+	b.WriteString("\t}, opts...)\n\tdefer g.Unpatch()\n")
+	call := fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+	if len(out) == 0 {
+		fmt.Fprintf(b, "\t%s\n", call)
+		b.WriteString("\tdone := make(chan struct{})\n\tgo func() {\n")
+		fmt.Fprintf(b, "\t\t%s\n\t\tclose(done)\n\t}()\n\t<-done\n", call)
+		b.WriteString("\tassert(t, g.Hits() == 1, g.Hits())\n}\n\n")
+		return
+	}
+	fmt.Fprintf(b, "\t%s := %s\n", strings.Join(vars, ", "), call)
+	fmt.Fprintf(b, "\tassert(t, reflect.DeepEqual([]interface{}{%s}, ret), %s)\n", strings.Join(vars, ", "), strings.Join(vars, ", "))
+	b.WriteString("\tc := make(chan []interface{})\n\tgo func() {\n")
+	fmt.Fprintf(b, "\t\t%s := %s\n", strings.Join(vars, ", "), call)
+	fmt.Fprintf(b, "\t\tc <- []interface{}{%s}\n\t}()\n", strings.Join(vars, ", "))
+	b.WriteString("\tassert(t, reflect.DeepEqual(<-c, org))\n}\n\n")
+}
+
+// conv returns how to convert a literal to typ, parenthesized when needed.
+func conv(typ string) string {
+	if strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") {
+		return "(" + typ + ")"
+	}
+	return typ
+}
@@ -1,11 +1,12 @@
-//go:build darwin
-// +build darwin
+//go:build darwin && !go1.19
+// +build darwin,!go1.19
 
 package monkey
 
-func getg() []byte {
-	return []byte{
+var gLoad = gLoader{
+	name: "thread local storage at gs:0x30",
+	code: []byte{
 		// mov r12,QWORD PTR gs:0x30
 		0x65, 0x4C, 0x8B, 0x24, 0x25, 0x30, 0x00, 0x00, 0x00,
-	}
+	},
 }
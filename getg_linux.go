@@ -1,11 +1,12 @@
-//go:build linux
-// +build linux
+//go:build linux && !go1.19
+// +build linux,!go1.19
 
 package monkey
 
-func getg() []byte {
-	return []byte{
+var gLoad = gLoader{
+	name: "thread local storage at fs:-8",
+	code: []byte{
 		// mov r12,QWORD PTR fs:0xfffffffffffffff8
 		0x64, 0x4C, 0x8B, 0x24, 0x25, 0xF8, 0xFF, 0xFF, 0xFF,
-	}
+	},
 }
@@ -0,0 +1,14 @@
+//go:build go1.19
+// +build go1.19
+
+package monkey
+
+// gLoad reads g from r14, where the register ABI keeps it on entry to every
+// Go function, instead of from thread local storage.
+var gLoad = gLoader{
+	name: "register r14 of the register ABI",
+	code: []byte{
+		// mov r12,r14
+		0x4D, 0x89, 0xF4,
+	},
+}
@@ -1,13 +1,14 @@
-//go:build windows
-// +build windows
+//go:build windows && !go1.19
+// +build windows,!go1.19
 
 package monkey
 
-func getg() []byte {
-	return []byte{
-		// mov r13,QWORD PTR gs:0x28
-		0x65, 0x4C, 0x8B, 0x2C, 0x25, 0x28, 0x00, 0x00, 0x00,
-		// mov r12,QWORD PTR [r13]
-		0x4D, 0x8B, 0x65, 0x00,
-	}
+var gLoad = gLoader{
+	name: "the TLS slot at gs:0x28",
+	code: []byte{
+		// mov r12,QWORD PTR gs:0x28
+		0x65, 0x4C, 0x8B, 0x24, 0x25, 0x28, 0x00, 0x00, 0x00,
+		// mov r12,QWORD PTR [r12]
+		0x4D, 0x8B, 0x24, 0x24,
+	},
 }
@@ -0,0 +1,76 @@
+package monkey
+
+import (
+	"fmt"
+)
+
+// GinkgoSession returns a session whose patches are removed when the current
+// spec ends. Pass ginkgo.DeferCleanup, which also makes it usable in
+// BeforeEach. Ginkgo runs each node of a spec on its own goroutine, and
+// patches only apply to the goroutine making them, so patch in the It
+// running the code under test:
+//
+//	var s *monkey.Session
+//	BeforeEach(func() {
+//		s = monkey.GinkgoSession(DeferCleanup)
+//	})
+//	It("uses the fake clock", func() {
+//		s.Patch(time.Now, fakeNow)
+//	})
+func GinkgoSession(deferCleanup func(args ...interface{})) *Session {
+	s := NewSession()
+	deferCleanup(s.Unpatch)
+	return s
+}
+
+// HaveBeenCalledTimes returns a Gomega matcher succeeding when the
+// replacement of a *PatchGuard was called exactly n times:
+//
+//	Expect(guard).To(monkey.HaveBeenCalledTimes(2))
+func HaveBeenCalledTimes(n int) *CallsMatcher {
+	return &CallsMatcher{times: n}
+}
+
+// HaveBeenCalled returns a Gomega matcher succeeding when the replacement
+// of a *PatchGuard was called at least once.
+func HaveBeenCalled() *CallsMatcher {
+	return &CallsMatcher{times: -1}
+}
+
+// CallsMatcher implements the Gomega matcher interface over a *PatchGuard.
+type CallsMatcher struct {
+	times int
+}
+
+// Match reports whether actual, a *PatchGuard, was called as expected.
+func (m *CallsMatcher) Match(actual interface{}) (bool, error) {
+	g, ok := actual.(*PatchGuard)
+	if !ok {
+		return false, fmt.Errorf("HaveBeenCalled matchers expect a *monkey.PatchGuard, got %T", actual)
+	}
+	if m.times < 0 {
+		return g.Hits() > 0, nil
+	}
+	return g.Hits() == uint64(m.times), nil
+}
+
+// FailureMessage explains why Match failed.
+func (m *CallsMatcher) FailureMessage(actual interface{}) string {
+	return m.message(actual, "to have been called")
+}
+
+// NegatedFailureMessage explains why the negated Match failed.
+func (m *CallsMatcher) NegatedFailureMessage(actual interface{}) string {
+	return m.message(actual, "not to have been called")
+}
+
+func (m *CallsMatcher) message(actual interface{}, expected string) string {
+	g, ok := actual.(*PatchGuard)
+	if !ok {
+		return fmt.Sprintf("Expected a *monkey.PatchGuard, got %T", actual)
+	}
+	if m.times >= 0 {
+		expected = fmt.Sprintf("%s %d times", expected, m.times)
+	}
+	return fmt.Sprintf("Expected patch of %s %s, it was called %d times", g.TargetName(), expected, g.Hits())
+}
@@ -0,0 +1,205 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
+	"github.com/huandu/go-tls/g"
+)
+
+// GoroutineSource is where the g of the running goroutine is read from to
+// key patches with, or its ID, see GoroutineID. It has to agree with the
+// dispatcher, see gLoader.
+type GoroutineSource int
+
+const (
+	// GoTLS asks github.com/huandu/go-tls/g, the default.
+	GoTLS GoroutineSource = iota
+	// Loader runs the code the dispatcher reads g with, which agrees with
+	// it by construction, at the cost of a call into the code built for
+	// it.
+	Loader
+	// GoroutineID keys patches by the ID of goroutines instead of their
+	// g, which the runtime reuses for new goroutines once one exits: a
+	// patch left by an exited goroutine would apply to the next one
+	// getting its g. The dispatcher reads the ID out of g, where it is
+	// found when selected, so it has to be selected before the first
+	// patch of the process.
+	GoroutineID
+)
+
+const goTLSPath = "github.com/huandu/go-tls"
+
+var (
+	gSource = GoTLS
+	// loadG runs the code of gLoad, once built by buildLoader.
+	loadG func() unsafe.Pointer
+	// goidOffset is where g holds the ID of its goroutine, once found
+	// by findGoid.
+	goidOffset uintptr
+)
+
+// SetGoroutineSource selects where g is read from. Call it before the first
+// patch, e.g. in an init function: patches in place are keyed by the former
+// source, and it fails then. The dispatcher checks GoTLS agrees with
+// it when first used, and fails every patch otherwise, telling to select
+// Loader.
+func SetGoroutineSource(s GoroutineSource) error {
+	lock.Lock()
+	defer lock.Unlock()
+	if live > 0 {
+		return errors.New("monkey: goroutine source selected after patching")
+	}
+	switch s {
+	case GoTLS:
+	case Loader:
+		if err := buildLoader(); err != nil {
+			return err
+		}
+	case GoroutineID:
+		if dispatch != 0 {
+			return errors.New("monkey: goroutine IDs selected after the dispatcher was built")
+		}
+		if err := findGoid(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("monkey: unknown goroutine source %d", s)
+	}
+	gSource = s
+	return nil
+}
+
+// curG returns the g of the running goroutine, read from the selected
+// source.
+func curG() uintptr {
+	switch gSource {
+	case Loader:
+		return uintptr(loadG())
+	case GoroutineID:
+		return uintptr(*(*uint64)(unsafe.Pointer(uintptr(loadG()) + goidOffset)))
+	}
+	return uintptr(g.G())
+}
+
+// gKey is the code turning the g in r12 into the key of the patches of its
+// goroutine, for the dispatcher.
+func gKey() []byte {
+	if gSource != GoroutineID {
+		return nil
+	}
+	off := goidOffset
+	return []byte{
+		// mov r12,QWORD PTR [r12+off]
+		0x4D, 0x8B, 0xA4, 0x24, byte(off), byte(off >> 8), byte(off >> 16), byte(off >> 24),
+	}
+}
+
+// findGoid finds where g holds the ID of its goroutine, as the first word
+// holding the ID printed in the stack trace of this goroutine, and of
+// another one.
+func findGoid() error {
+	if goidOffset != 0 {
+		return nil
+	}
+	if err := buildLoader(); err != nil {
+		return err
+	}
+	const maxOffset = 256
+	type seen struct {
+		id    uint64
+		words [maxOffset / 8]uint64
+	}
+	look := func() (s seen, err error) {
+		s.id, err = stackGoid()
+		p := loadG()
+		for i := range s.words {
+			s.words[i] = *(*uint64)(unsafe.Pointer(uintptr(p) + uintptr(i)*8))
+		}
+		return s, err
+	}
+	here, err := look()
+	if err != nil {
+		return err
+	}
+	c := make(chan seen)
+	go func() {
+		s, _ := look()
+		c <- s
+	}()
+	there := <-c
+	for i := range here.words {
+		if here.words[i] == here.id && there.words[i] == there.id {
+			goidOffset = uintptr(i) * 8
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: goroutine ID not found in g", ErrUnsupportedRuntime)
+}
+
+// stackGoid returns the ID of the running goroutine, from its stack trace.
+func stackGoid() (uint64, error) {
+	b := make([]byte, 64)
+	b = b[:runtime.Stack(b, false)]
+	var id uint64
+	if _, err := fmt.Sscanf(string(b), "goroutine %d ", &id); err != nil {
+		return 0, fmt.Errorf("monkey: reading goroutine ID: %w", err)
+	}
+	return id, nil
+}
+
+// buildLoader makes the code of gLoad callable as loadG, once.
+func buildLoader() error {
+	if loadG != nil {
+		return nil
+	}
+	code := append(append([]byte(nil), gLoad.code...), gReturn...)
+	b, err := raw.AllocExecutable(len(code))
+	if err != nil {
+		return err
+	}
+	copy(b, code)
+	addr := uintptr(unsafe.Pointer(&b[0]))
+	addStub(addr, len(b), "monkey g loader")
+	loadG = funcValue(addr, reflect.TypeOf(loadG)).Interface().(func() unsafe.Pointer)
+	return nil
+}
+
+// checkGoTLS makes sure go-tls finds the same g as the dispatcher, on this
+// goroutine and another one.
+func checkGoTLS() error {
+	if err := buildLoader(); err != nil {
+		return err
+	}
+	check := func() error {
+		if tls, loaded := uintptr(g.G()), uintptr(loadG()); tls != loaded {
+			return fmt.Errorf("%s %s reads g as %#x, the dispatcher as %#x, select Loader with SetGoroutineSource",
+				goTLSPath, moduleVersion(goTLSPath), tls, loaded)
+		}
+		return nil
+	}
+	if err := check(); err != nil {
+		return err
+	}
+	c := make(chan error)
+	go func() { c <- check() }()
+	return <-c
+}
+
+// moduleVersion returns the version of the module path the binary is built
+// with, or "(unknown version)".
+func moduleVersion(path string) string {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, m := range bi.Deps {
+			if m.Path == path {
+				return m.Version
+			}
+		}
+	}
+	return "(unknown version)"
+}
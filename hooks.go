@@ -0,0 +1,129 @@
+package monkey
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Hooks are called around patching and around calls to replacements, to let
+// frameworks built on this package log, measure or veto them. Any field may
+// be nil. Hooks run outside of the lock of the package, and calls call hooks
+// make to the target run the original function.
+type Hooks struct {
+	// BeforeApply is called before g is applied, by Patch and the like
+	// or by Restore. Returning an error cancels the patch.
+	BeforeApply func(g *PatchGuard) error
+	// AfterApply is called once g is applied, or failed to with err.
+	AfterApply func(g *PatchGuard, err error)
+	// BeforeCall is called before the replacement of g runs, with its
+	// arguments.
+	BeforeCall func(g *PatchGuard, args []reflect.Value)
+	// AfterCall is called once the replacement of g returned results.
+	AfterCall func(g *PatchGuard, args, results []reflect.Value)
+}
+
+var (
+	hooksLock sync.Mutex
+	// hooks holds a []*Hooks, replaced as a whole on every change.
+	hooks atomic.Value
+)
+
+// AddHooks installs h and returns a function removing it. Call hooks only
+// apply to patches made while some are installed, as they cost a trip
+// through reflect on every call.
+func AddHooks(h Hooks) (remove func()) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+	p := &h
+	hs := currentHooks()
+	hooks.Store(append(hs[:len(hs):len(hs)], p))
+	return func() {
+		hooksLock.Lock()
+		defer hooksLock.Unlock()
+		var hs []*Hooks
+		for _, o := range currentHooks() {
+			if o != p {
+				hs = append(hs, o)
+			}
+		}
+		hooks.Store(hs)
+	}
+}
+
+func currentHooks() []*Hooks {
+	hs, _ := hooks.Load().([]*Hooks)
+	return hs
+}
+
+func beforeApply(g *PatchGuard) error {
+	for _, h := range currentHooks() {
+		if h.BeforeApply != nil {
+			if err := h.BeforeApply(g); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func afterApply(g *PatchGuard, err error) {
+	for _, h := range currentHooks() {
+		if h.AfterApply != nil {
+			h.AfterApply(g, err)
+		}
+	}
+}
+
+// hookCalls returns fn calling the call hooks around it, or fn itself if no
+// call hooks are installed now.
+func hookCalls(g *PatchGuard, fn reflect.Value) reflect.Value {
+	found := false
+	for _, h := range currentHooks() {
+		found = found || h.BeforeCall != nil || h.AfterCall != nil
+	}
+	if !found {
+		return fn
+	}
+	// Calls the hooks make to the target go to the original function:
+	// the entry is turned off meanwhile, or for patches shared by every
+	// goroutine, which that would turn off for all of them, the goroutine
+	// running the hooks is tracked instead, as by guardSharedReentry.
+	shared := g.config.when != nil
+	var hooking sync.Map
+	quiet := func(run func()) {
+		if shared {
+			gid := curG()
+			hooking.Store(gid, true)
+			defer hooking.Delete(gid)
+		} else {
+			off := atomic.SwapUint32(&g.entry.off, 1)
+			defer atomic.StoreUint32(&g.entry.off, off)
+		}
+		run()
+	}
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		if _, ok := hooking.Load(curG()); shared && ok {
+			return passOn(g, in)
+		}
+		hs := currentHooks()
+		quiet(func() {
+			for _, h := range hs {
+				if h.BeforeCall != nil {
+					h.BeforeCall(g, in)
+				}
+			}
+		})
+
+		out := call(fn, in)
+
+		quiet(func() {
+			for _, h := range hs {
+				if h.AfterCall != nil {
+					h.AfterCall(g, in, out)
+				}
+			}
+		})
+		return out
+	})
+}
@@ -0,0 +1,107 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+)
+
+// Interceptor rewrites the arguments or the results of a call. It is
+// passed the values as the function receives or returns them and returns
+// those to use instead, in the same number and types.
+type Interceptor func(values []reflect.Value) []reflect.Value
+
+// Intercept patches target to run the original function, with the arguments
+// passed through args and the results through results first. Either hook
+// may be nil. Hooks may also panic to reject a call.
+func Intercept(target interface{}, args, results Interceptor, opts ...PatchOption) *PatchGuard {
+	g, err := TryIntercept(target, args, results, opts...)
+	if err != nil {
+		return inertGuard(reflect.ValueOf(target), reflect.Value{}, err)
+	}
+	return g
+}
+
+// TryIntercept is like Intercept but returns an error instead of panicking.
+func TryIntercept(target interface{}, args, results Interceptor, opts ...PatchOption) (*PatchGuard, error) {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return nil, errors.New("target has to be a Func")
+	}
+
+	var g *PatchGuard
+	r := reflect.MakeFunc(t.Type(), func(in []reflect.Value) []reflect.Value {
+		if args != nil {
+			in = args(in)
+		}
+		out := callOriginal(g, in)
+		if results != nil {
+			out = results(out)
+		}
+		return out
+	})
+
+	g, err := TryPatch(target, r.Interface(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// PatchResults patches target to run the original function and pass its
+// results through hook before they reach the caller. hook takes the results
+// of target as arguments and returns them, e.g. a func([]Item, error)
+// ([]Item, error) for a target returning ([]Item, error), to truncate a real
+// list or to turn a success into an error.
+func PatchResults(target, hook interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatchResults(target, hook, opts...)
+	if err != nil {
+		return inertGuard(reflect.ValueOf(target), reflect.Value{}, err)
+	}
+	return g
+}
+
+// TryPatchResults is like PatchResults but returns an error instead of
+// panicking.
+func TryPatchResults(target, hook interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	t, h := reflect.ValueOf(target), reflect.ValueOf(hook)
+	if t.Kind() != reflect.Func {
+		return nil, errors.New("target has to be a Func")
+	}
+	out := make([]reflect.Type, t.Type().NumOut())
+	for i := range out {
+		out[i] = t.Type().Out(i)
+	}
+	want := reflect.FuncOf(out, out, false)
+	if h.Kind() != reflect.Func || h.Type() != want {
+		return nil, fmt.Errorf("hook has to be a %s, not %T", want, hook)
+	}
+	return TryIntercept(target, nil, func(results []reflect.Value) []reflect.Value {
+		return h.Call(results)
+	}, opts...)
+}
+
+// passOn is callOriginal for a call the replacement doesn't handle, which
+// is then not counted in the hits of g.
+func passOn(g *PatchGuard, in []reflect.Value) []reflect.Value {
+	atomic.AddUint64(&g.entry.calls, ^uint64(0))
+	return callOriginal(g, in)
+}
+
+// callOriginal calls the function patched by g with the replacement
+// disabled for the duration of the call.
+func callOriginal(g *PatchGuard, in []reflect.Value) []reflect.Value {
+	if out := checkQuarantine(g.target.Pointer()); out != nil {
+		return out
+	}
+	if e := g.entry; e.original.IsValid() {
+		// The entry is shared with other goroutines, which would
+		// see it disabled as well.
+		atomic.AddUint64(&e.passed, 1)
+		return call(e.original, in)
+	}
+	off := atomic.SwapUint32(&g.entry.off, 1)
+	defer atomic.StoreUint32(&g.entry.off, off)
+	return call(g.target, in)
+}
@@ -0,0 +1,82 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrIntrinsic is returned when patching a function the compiler replaces
+// with a few instructions wherever it is called, without AllowIntrinsics.
+var ErrIntrinsic = errors.New("monkey: target is a compiler intrinsic")
+
+// AllowIntrinsics lets the patch apply to a function the compiler turns
+// into inline instructions at its call sites, like math.Floor or the
+// functions of sync/atomic, which is refused otherwise. Only the calls made
+// through a function value, e.g. f := math.Floor; f(2), or by reflect reach
+// the patched function, the others never did call it.
+//
+// Functions merely calling an intrinsic, like math.Sqrt, are inlined rather
+// than intrinsified and can be patched as usual with -gcflags=-l.
+func AllowIntrinsics() PatchOption {
+	return func(c *patchConfig) {
+		c.intrinsic = true
+	}
+}
+
+// intrinsics holds the names of the exported functions intrinsified on
+// amd64, from cmd/compile/internal/ssagen.
+var intrinsics = map[string]bool{
+	"runtime.KeepAlive": true,
+
+	// Only on CPUs with SSE4.1, the others still call them.
+	"math.Floor":       true,
+	"math.Ceil":        true,
+	"math.Trunc":       true,
+	"math.RoundToEven": true,
+	"math.FMA":         true,
+
+	"math/bits.Len":            true,
+	"math/bits.OnesCount":      true,
+	"math/bits.RotateLeft":     true,
+	"math/bits.ReverseBytes32": true,
+	"math/bits.ReverseBytes64": true,
+	"math/bits.Add":            true,
+	"math/bits.Add64":          true,
+	"math/bits.Sub":            true,
+	"math/bits.Sub64":          true,
+	"math/bits.Mul":            true,
+	"math/bits.Mul64":          true,
+	"math/bits.Div":            true,
+	"math/bits.Div64":          true,
+}
+
+func init() {
+	for _, f := range []string{"TrailingZeros", "Len", "OnesCount", "RotateLeft"} {
+		for _, size := range []string{"8", "16", "32", "64"} {
+			intrinsics["math/bits."+f+size] = true
+		}
+	}
+	for _, op := range []string{"Load", "Store", "Swap", "CompareAndSwap"} {
+		for _, t := range []string{"Int32", "Int64", "Uint32", "Uint64", "Uintptr", "Pointer"} {
+			intrinsics["sync/atomic."+op+t] = true
+		}
+	}
+	for _, op := range []string{"Add", "And", "Or"} {
+		for _, t := range []string{"Int32", "Int64", "Uint32", "Uint64", "Uintptr"} {
+			intrinsics["sync/atomic."+op+t] = true
+		}
+	}
+}
+
+// checkIntrinsic returns an error if the function named name is an
+// intrinsic and c doesn't allow those.
+func checkIntrinsic(name string, c *patchConfig) error {
+	if !intrinsics[name] {
+		return nil
+	}
+	if !c.intrinsic {
+		return fmt.Errorf("%w: %s, calls to it are compiled to inline instructions and won't see the patch, pass AllowIntrinsics to patch it for calls through function values", ErrIntrinsic, name)
+	}
+	logf("patching intrinsic %s, only calls through function values will see it", name)
+	return nil
+}
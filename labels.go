@@ -0,0 +1,42 @@
+package monkey
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// ForLabels makes the patch apply to every goroutine carrying the pprof
+// labels given as key and value pairs, as set by pprof.Do or
+// pprof.SetGoroutineLabels, instead of only to the goroutine making it.
+// Goroutines inherit the labels of the goroutine starting them, so in a test
+// of a server wrapping its handlers in pprof.Do, the patch is scoped to the
+// requests of one handler. Calls on other goroutines run the original.
+//
+// With no pairs the patch applies to every goroutine. A target can be
+// patched several times this way, see Scope for which patch runs.
+func ForLabels(keyvals ...string) PatchOption {
+	if len(keyvals)%2 != 0 {
+		panic("monkey: uneven number of arguments to ForLabels")
+	}
+	return func(c *patchConfig) {
+		if len(keyvals) == 0 {
+			c.narrow(ScopeGlobal)
+		} else {
+			c.narrow(ScopeLabels)
+		}
+		c.addWhen(func([]reflect.Value) bool {
+			for i := 0; i < len(keyvals); i += 2 {
+				if v, ok := goroutineLabel(keyvals[i]); !ok || v != keyvals[i+1] {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
+
+// getProfLabel returns the labels of the running goroutine, in the format
+// of the release of runtime/pprof, see goroutineLabel.
+//
+//go:linkname getProfLabel runtime/pprof.runtime_getProfLabel
+func getProfLabel() unsafe.Pointer
@@ -0,0 +1,25 @@
+//go:build go1.24
+// +build go1.24
+
+package monkey
+
+// profLabels is how runtime/pprof stores the labels of a goroutine since Go
+// 1.24, sorted by key.
+type profLabels struct {
+	list []struct{ key, value string }
+}
+
+// goroutineLabel returns the value of the pprof label key of the running
+// goroutine.
+func goroutineLabel(key string) (string, bool) {
+	l := (*profLabels)(getProfLabel())
+	if l == nil {
+		return "", false
+	}
+	for _, kv := range l.list {
+		if kv.key == key {
+			return kv.value, true
+		}
+	}
+	return "", false
+}
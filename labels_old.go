@@ -0,0 +1,15 @@
+//go:build !go1.24
+// +build !go1.24
+
+package monkey
+
+// goroutineLabel returns the value of the pprof label key of the running
+// goroutine. Before Go 1.24, runtime/pprof stores labels in a map.
+func goroutineLabel(key string) (string, bool) {
+	l := (*map[string]string)(getProfLabel())
+	if l == nil {
+		return "", false
+	}
+	v, ok := (*l)[key]
+	return v, ok
+}
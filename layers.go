@@ -0,0 +1,248 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/trace"
+	"sync/atomic"
+	"time"
+)
+
+// layer is a behavior stacked on the calls of a goroutine to a target,
+// around its patch or the original function, such as those added by Use,
+// TraceCalls and InjectFault. The layers of a goroutine and its patch share
+// its entry, so that they combine instead of competing for the target.
+type layer struct {
+	wrap Middleware
+	// entry holds the layer, once added.
+	entry *entry
+	// off is set by Disable, calls is the number of calls run through
+	// the layer.
+	off   uint32
+	calls uint64
+}
+
+// TraceCalls wraps the calls to target made by the calling goroutine in a
+// runtime/trace region named after it, whether they go to a patch or to the
+// original function. Unlike the Trace option, it doesn't take the place of
+// the patch of the goroutine: both can be combined in any order. Unpatch
+// the returned guard to remove the region alone.
+func TraceCalls(target interface{}) *PatchGuard {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return inertGuard(t, reflect.Value{}, patchError("patch", fmt.Sprint(target), errors.New("target has to be a Func")))
+	}
+	name := "monkey: " + SymbolName(t.Pointer())
+	withCtx := t.Type().NumIn() > 0 && t.Type().In(0) == contextType
+	return addLayer(t, &patchConfig{}, func(in []reflect.Value, next func([]reflect.Value) []reflect.Value) []reflect.Value {
+		if !trace.IsEnabled() {
+			return next(in)
+		}
+		defer trace.StartRegion(traceContext(withCtx, in), name).End()
+		return next(in)
+	})
+}
+
+// InjectFault makes the calls to target made by the calling goroutine wait
+// for f.Delay, then panic with f.Panic or return f.Err if set, or else go
+// on to the patch of the goroutine or the original function. Like
+// TraceCalls, it combines with the patch instead of replacing it. Unpatch
+// the returned guard to remove the fault alone.
+func InjectFault(target interface{}, f Fault) *PatchGuard {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return inertGuard(t, reflect.Value{}, patchError("patch", fmt.Sprint(target), errors.New("target has to be a Func")))
+	}
+	typ := t.Type()
+	if f.Err != nil && (typ.NumOut() == 0 || typ.Out(typ.NumOut()-1) != errorType) {
+		return inertGuard(t, reflect.Value{}, patchError("patch", SymbolName(t.Pointer()), errors.New("cannot inject error, the last result is not an error")))
+	}
+	return addLayer(t, &patchConfig{}, func(in []reflect.Value, next func([]reflect.Value) []reflect.Value) []reflect.Value {
+		time.Sleep(f.Delay)
+		if f.Panic != nil {
+			panic(f.Panic)
+		}
+		if f.Err != nil {
+			out := zeroResults(typ)
+			out[len(out)-1] = reflect.ValueOf(f.Err)
+			return out
+		}
+		return next(in)
+	})
+}
+
+// addLayer adds a layer running wrap to the calls to target of the calling
+// goroutine, recording them if c says so.
+func addLayer(target reflect.Value, c *patchConfig, wrap Middleware) *PatchGuard {
+	pg := &PatchGuard{
+		target: target,
+		config: c,
+		layer:  &layer{wrap: wrap},
+		site:   callSite(),
+		stack:  callStack(),
+	}
+	if c.record {
+		r := &recorder{}
+		pg.recorder = r
+		pg.layer.wrap = func(in []reflect.Value, next func([]reflect.Value) []reflect.Value) []reflect.Value {
+			return r.record(in, func(in []reflect.Value) []reflect.Value {
+				return wrap(in, next)
+			})
+		}
+	}
+	if err := patchValue(pg); err != nil {
+		return inertGuard(target, reflect.Value{}, err)
+	}
+	return pg
+}
+
+// applyLayer adds the layer of pg to the entry of gid, creating it if the
+// goroutine has none. lock must be held.
+func (p *patch) applyLayer(pg *PatchGuard, gid uintptr) error {
+	l := pg.layer
+	if gid == anyG {
+		return errors.New("monkey: layers only apply to the goroutine adding them")
+	}
+	e, ok := p.entries()[gid]
+	if ok {
+		for _, o := range e.layers {
+			if o == l {
+				return fmt.Errorf("monkey: %s already has this layer", SymbolName(p.from))
+			}
+		}
+	} else {
+		if o := CurrentOptions(); o.MaxPatches > 0 && live >= o.MaxPatches {
+			return fmt.Errorf("%w: %d in place", ErrMaxPatches, live)
+		}
+		e = &entry{
+			pkg:   funcPackage(pg.site.Function),
+			site:  pg.CreatedAt(),
+			stack: pg.stack,
+		}
+	}
+	e.layers = append(e.layers, l)
+	l.entry = e
+	e.rebuild(p, pg.target.Type())
+	pg.gid = gid
+	if !ok {
+		p.Add(gid, e)
+	}
+	if err := p.installUnlocked(); err != nil {
+		p.removeLayer(gid, l)
+		return err
+	}
+	logf("layered %s", SymbolName(p.from))
+	return nil
+}
+
+// removeLayer removes l from the entry of gid, and the entry along with
+// its last layer unless a patch holds it. lock must be held.
+func (p *patch) removeLayer(gid uintptr, l *layer) {
+	e := l.entry
+	if e == nil || p.entries()[gid] != e {
+		return
+	}
+	layers := make([]*layer, 0, len(e.layers))
+	for _, o := range e.layers {
+		if o != l {
+			layers = append(layers, o)
+		}
+	}
+	e.layers = layers
+	if len(layers) == 0 && e.guard == nil {
+		p.Del(gid, e)
+		return
+	}
+	e.rebuild(p, e.fn.Type())
+}
+
+// unpatchEntry removes the patch of gid, held by e unless e is nil, and
+// leaves its layers in place. lock must be held.
+func (p *patch) unpatchEntry(gid uintptr, e *entry) bool {
+	found, ok := p.entries()[gid]
+	if !ok || e != nil && found != e {
+		return false
+	}
+	if len(found.layers) == 0 {
+		return p.Del(gid, found)
+	}
+	if found.guard == nil {
+		return false
+	}
+	found.guard = nil
+	found.rebuild(p, found.fn.Type())
+	logf("unpatched %s", SymbolName(p.from))
+	return true
+}
+
+// adopt makes e, holding a patch, the entry of gid, in place of the one
+// holding its layers if they differ. lock must be held.
+func (p *patch) adopt(gid uintptr, e *entry, typ reflect.Type) {
+	found := p.entries()[gid]
+	if found != e {
+		p.fold(found)
+		e.layers = found.layers
+		for _, l := range e.layers {
+			l.entry = e
+		}
+		added++
+		e.seq = added
+		p.setEntries(p.withEntry(gid, e))
+	}
+	e.rebuild(p, typ)
+}
+
+// rebuild makes e run its layers, the first one outermost, around the
+// replacement of its patch, or else what runs without e, see passBy. Unless
+// the patch allows reentry, all of it is guarded, by guardCall or else
+// guardReentry. lock must be held.
+func (e *entry) rebuild(p *patch, typ reflect.Type) {
+	fn := e.base
+	if e.guard == nil {
+		fn = p.passBy(typ)
+	}
+	for i := len(e.layers) - 1; i >= 0; i-- {
+		fn = e.layers[i].around(fn)
+	}
+	e.fn, e.slowFn = fn, reflect.Value{}
+	if g := e.guard; g != nil && !g.config.reentrant && g.config.when == nil {
+		e.slowFn = e.guardReentry(fn)
+		if !inRegisters(typ) {
+			e.fn, e.slowFn = e.slowFn, reflect.Value{}
+		}
+	}
+	var slow uintptr
+	if e.slowFn.IsValid() {
+		slow = uintptr(getPtr(e.slowFn))
+	}
+	atomic.StoreUintptr(&e.slow, slow)
+	atomic.StoreUintptr(&e.to, uintptr(getPtr(e.fn)))
+}
+
+// around returns next run through l.
+func (l *layer) around(next reflect.Value) reflect.Value {
+	run := func(in []reflect.Value) []reflect.Value {
+		return call(next, in)
+	}
+	return reflect.MakeFunc(next.Type(), func(in []reflect.Value) []reflect.Value {
+		if atomic.LoadUint32(&l.off) != 0 {
+			return run(in)
+		}
+		atomic.AddUint64(&l.calls, 1)
+		return l.wrap(in, run)
+	})
+}
+
+// passBy returns a function of type typ running what a call to the target
+// of p runs without the entry of the calling goroutine: the patch shared
+// by every goroutine holding for the call, or else the original function.
+func (p *patch) passBy(typ reflect.Type) reflect.Value {
+	return reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		if g := p.match(in); g != nil {
+			atomic.AddUint64(&g.entry.calls, 1)
+			return call(g.entry.fn, in)
+		}
+		return call(funcValue(uintptr(p.trampoline), typ), in)
+	})
+}
@@ -0,0 +1,2 @@
+// This file lets the package declare functions without a body, which are
+// provided by the runtime through go:linkname.
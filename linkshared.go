@@ -0,0 +1,38 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// ErrLinkShared is returned when patching a function of a Go shared
+// library the binary was built against with -linkshared.
+var ErrLinkShared = errors.New("monkey: target is in a shared library of a -linkshared build")
+
+var (
+	linkSharedOnce sync.Once
+	// linkShared tells whether the runtime itself is in a shared library.
+	linkShared bool
+)
+
+// checkLinkShared returns an error if the binary was built with -linkshared
+// and the function at from is in one of its shared libraries. Calls to
+// those go through the PLT and GOT of each module, which the jump written
+// at the start of the function doesn't account for, and the code of the
+// library is shared with every other binary using it. Plugins loaded by
+// binaries built otherwise are patched as usual.
+func checkLinkShared(from uintptr) error {
+	linkSharedOnce.Do(func() {
+		_, linkShared = sharedObject(reflect.ValueOf(runtime.GC).Pointer())
+	})
+	if !linkShared {
+		return nil
+	}
+	if lib, ok := sharedObject(from); ok {
+		return fmt.Errorf("%w: %s is in %s, build without -linkshared to patch it", ErrLinkShared, SymbolName(from), lib)
+	}
+	return nil
+}
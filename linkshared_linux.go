@@ -0,0 +1,53 @@
+package monkey
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sharedObject returns the file mapped at pc if it isn't the executable.
+func sharedObject(pc uintptr) (string, bool) {
+	maps, err := ioutil.ReadFile("/proc/self/maps")
+	if err != nil {
+		return "", false
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return "", false
+	}
+	if p, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = p
+	}
+	return mappedFile(maps, pc, exe)
+}
+
+// mappedFile returns the file mapped at pc in maps, formatted like
+// /proc/self/maps, unless it is exe or pc isn't in a file mapping.
+func mappedFile(maps []byte, pc uintptr, exe string) (string, bool) {
+	for _, line := range bytes.Split(maps, []byte("\n")) {
+		// start-end perms offset dev inode path
+		f := bytes.Fields(line)
+		if len(f) < 6 {
+			continue
+		}
+		r := bytes.SplitN(f[0], []byte("-"), 2)
+		if len(r) != 2 {
+			continue
+		}
+		start, err1 := strconv.ParseUint(string(r[0]), 16, 64)
+		end, err2 := strconv.ParseUint(string(r[1]), 16, 64)
+		if err1 != nil || err2 != nil || uint64(pc) < start || uint64(pc) >= end {
+			continue
+		}
+		path := strings.TrimSuffix(string(bytes.Join(f[5:], []byte(" "))), " (deleted)")
+		if path == exe || path[0] != '/' {
+			return "", false
+		}
+		return path, true
+	}
+	return "", false
+}
@@ -0,0 +1,40 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestMappedFile(t *testing.T) {
+	maps := []byte(`00400000-00600000 r-xp 00000000 08:01 1234 /tmp/go-build/app.test
+7f0000000000-7f0000200000 r-xp 00000000 08:01 5678 /usr/local/go/pkg/linux_amd64_dynlink/libstd.so
+7f0000400000-7f0000500000 rw-p 00000000 00:00 0 
+7ffd00000000-7ffd00002000 r-xp 00000000 00:00 0 [vdso]
+`)
+	for _, c := range []struct {
+		pc   uintptr
+		want string
+	}{
+		{0x401000, ""},
+		{0x7f0000001000, "/usr/local/go/pkg/linux_amd64_dynlink/libstd.so"},
+		{0x7f0000400000, ""},
+		{0x7ffd00000100, ""},
+		{0x300000, ""},
+	} {
+		got, ok := mappedFile(maps, c.pc, "/tmp/go-build/app.test")
+		if got != c.want || ok != (c.want != "") {
+			t.Errorf("mappedFile(%#x) = %q, %v, want %q", c.pc, got, ok, c.want)
+		}
+	}
+
+	if lib, ok := sharedObject(reflect.ValueOf(runtime.GC).Pointer()); ok {
+		t.Skipf("built with -linkshared, runtime is in %s", lib)
+	}
+	if err := checkLinkShared(reflect.ValueOf(runtime.GC).Pointer()); err != nil {
+		t.Fatal(err)
+	}
+}
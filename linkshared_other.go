@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package monkey
+
+// sharedObject can't tell on this platform, where -linkshared isn't
+// supported anyway.
+func sharedObject(pc uintptr) (string, bool) {
+	return "", false
+}
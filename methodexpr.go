@@ -0,0 +1,31 @@
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// methodExpr tells whether target is a method expression, like T.Method or
+// (*T).Method, and returns its receiver type and method name, so that Patch
+// resolves it as PatchInstanceMethod does. Unexported methods, which
+// reflect doesn't list, are patched as plain functions.
+func methodExpr(target reflect.Value) (reflect.Type, string, bool, error) {
+	if target.Kind() != reflect.Func || target.IsNil() || target.Type().NumIn() == 0 {
+		return nil, "", false, nil
+	}
+	t := target.Type().In(0)
+	pc := target.Pointer()
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if t.Kind() == reflect.Interface {
+			if t.Name() != "" && SymbolName(pc) == t.String()+"."+m.Name {
+				return nil, "", false, fmt.Errorf("monkey: %s is a method of the interface %s, patch the method of its implementation instead", SymbolName(pc), t)
+			}
+			continue
+		}
+		if m.Func.Pointer() == pc {
+			return t, m.Name, true, nil
+		}
+	}
+	return nil, "", false, nil
+}
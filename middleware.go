@@ -0,0 +1,53 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Middleware runs around a call to a target, see Use. It gets the arguments
+// of the call and next, running the rest of the chain, and returns the
+// results of the call: usually those of next, or its own without calling
+// next to replace the rest of the chain.
+type Middleware func(in []reflect.Value, next func([]reflect.Value) []reflect.Value) []reflect.Value
+
+// Use appends mw to the chain of middlewares the calls to target made by
+// the calling goroutine run through, before they reach the patch of the
+// goroutine or the original function. Middlewares run in the order they
+// were added, the first one outermost:
+//
+//	defer monkey.Use(fetch, monkey.Delay(time.Second)).Unpatch()
+//	rec := monkey.Use(fetch, nil, monkey.Record())
+//	defer rec.Unpatch()
+//	defer monkey.Patch(fetch, fakeFetch).Unpatch()
+//
+// makes every call wait a second, then be recorded, then run fakeFetch. A
+// nil mw only hands calls on, for the options it is used with. Of those,
+// Record records the calls reaching mw, see PatchGuard.Calls, and options
+// applying a patch to other goroutines are refused.
+//
+// Unpatch the returned guard to take mw alone out of the chain, Disable it
+// to skip mw until Enable is called. Hits returns the number of calls mw
+// ran. Like Patch, it panics or logs on error, see Options.PanicOnError.
+func Use(target interface{}, mw Middleware, opts ...PatchOption) *PatchGuard {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return inertGuard(t, reflect.Value{}, patchError("patch", fmt.Sprint(target), errors.New("target has to be a Func")))
+	}
+	if mw == nil {
+		mw = func(in []reflect.Value, next func([]reflect.Value) []reflect.Value) []reflect.Value {
+			return next(in)
+		}
+	}
+	return addLayer(t, newPatchConfig(opts), mw)
+}
+
+// Delay returns a middleware making calls wait d before they go on.
+func Delay(d time.Duration) Middleware {
+	return func(in []reflect.Value, next func([]reflect.Value) []reflect.Value) []reflect.Value {
+		time.Sleep(d)
+		return next(in)
+	}
+}
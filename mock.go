@@ -0,0 +1,84 @@
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// BindMock patches the methods of typ that mock implements so calls made on
+// the current goroutine go to mock, dropping the receiver. This lets a
+// gomock generated mock stand in for a concrete type, with its expectations
+// checked by gomock as usual:
+//
+//	ctrl := gomock.NewController(t)
+//	m := NewMockClient(ctrl)
+//	m.EXPECT().Do(gomock.Any()).Return(nil, io.EOF)
+//	monkey.BindMock(ctrl, reflect.TypeOf(&http.Client{}), m)
+//
+// When ctrl, usually a *gomock.Controller, has a T field with a Cleanup
+// method the patches are removed when the test ends. Otherwise remove them
+// with the returned session.
+func BindMock(ctrl interface{}, typ reflect.Type, mock interface{}) *Session {
+	s := NewSession()
+	mv := reflect.ValueOf(mock)
+	for i := 0; i < typ.NumMethod(); i++ {
+		m := typ.Method(i)
+		mm := mv.MethodByName(m.Name)
+		if !mm.IsValid() {
+			continue
+		}
+		if err := checkMockMethod(m, mm.Type()); err != nil {
+			s.Unpatch()
+			check(err)
+			return s
+		}
+		s.PatchInstanceMethod(typ, m.Name, forward(m.Type, mm).Interface())
+	}
+
+	if c := mockCleanup(ctrl); c != nil {
+		c.Cleanup(s.Unpatch)
+	}
+	return s
+}
+
+func checkMockMethod(m reflect.Method, mt reflect.Type) error {
+	t := m.Type
+	ok := t.NumIn() == mt.NumIn()+1 && t.NumOut() == mt.NumOut() && t.IsVariadic() == mt.IsVariadic()
+	for i := 0; ok && i < mt.NumIn(); i++ {
+		ok = t.In(i+1) == mt.In(i)
+	}
+	for i := 0; ok && i < mt.NumOut(); i++ {
+		ok = t.Out(i) == mt.Out(i)
+	}
+	if !ok {
+		return fmt.Errorf("mock method %s has type %s, which does not match %s", m.Name, mt, t)
+	}
+	return nil
+}
+
+// forward returns a function of type t calling fn without the receiver.
+func forward(t reflect.Type, fn reflect.Value) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		return call(fn, in[1:])
+	})
+}
+
+type cleaner interface {
+	Cleanup(func())
+}
+
+func mockCleanup(ctrl interface{}) cleaner {
+	v := reflect.ValueOf(ctrl)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	f := v.FieldByName("T")
+	if !f.IsValid() || !f.CanInterface() {
+		return nil
+	}
+	c, _ := f.Interface().(cleaner)
+	return c
+}
@@ -1,53 +1,297 @@
 package monkey
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
-	"github.com/huandu/go-tls/g"
+	"github.com/go-kiss/monkey/raw"
 )
 
 var (
+	// lock serializes the changes to patches, see registry.go for
+	// reading them.
 	lock = sync.Mutex{}
 
-	patches = make(map[uintptr]*patch)
+	// live counts the entries of all patches, see Options.MaxPatches.
+	live int
+	// added numbers entries in the order they are added.
+	added uint64
 )
 
+// ErrDisabled is returned by every patching function when the package is
+// built with the monkey_disabled tag.
+var ErrDisabled = raw.ErrDisabled
+
 type PatchGuard struct {
 	target      reflect.Value
 	replacement reflect.Value
+	config      *patchConfig
+	entry       *entry
+	// gid is the goroutine the entry was last added for.
+	gid      uintptr
+	recorder *recorder
+	script   *script
+	// spec describes the replacement, for guards made by Declare.
+	spec *PatchSpec
+	// layer is what the guard runs around the calls instead of a
+	// replacement, see TraceCalls.
+	layer *layer
+	// site is where the guard was created, outside of this package,
+	// stack the whole stack then, see CreationStack.
+	site  runtime.Frame
+	stack []uintptr
+	// err is why the guard is inert, see Err.
+	err error
 }
 
+// Unpatch removes the patch made by g. It may be called from any goroutine,
+// the patch is removed for the goroutine that made it.
 func (g *PatchGuard) Unpatch() {
-	unpatchValue(g.target)
+	if g.entry == nil && g.layer == nil {
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	p, ok := lookupPatch(g.target.Pointer())
+	if !ok {
+		return
+	}
+	if g.layer != nil {
+		p.removeLayer(g.gid, g.layer)
+		return
+	}
+	if g.gid == anyG {
+		if p.delShared(g) {
+			logf("unpatched %s", SymbolName(p.from))
+		}
+		return
+	}
+	p.unpatchEntry(g.gid, g.entry)
+}
+
+// UnpatchOn removes the patch made by g once ch is closed or receives a
+// value, so that closing a single channel revokes every patch bound to it.
+// A goroutine waits for ch until then, even if g is unpatched before.
+func (g *PatchGuard) UnpatchOn(ch <-chan struct{}) *PatchGuard {
+	if g.entry != nil || g.layer != nil {
+		go func() {
+			<-ch
+			g.Unpatch()
+		}()
+	}
+	return g
 }
 
 func (g *PatchGuard) Restore() {
-	patchValue(g.target, g.replacement)
+	if g.err != nil {
+		return
+	}
+	check(patchValue(g))
+}
+
+// Err returns why the patch could not be made. Only guards returned with
+// Options.PanicOnError off can have one, they do nothing.
+func (g *PatchGuard) Err() error {
+	return g.err
+}
+
+// Disable lets calls through to the original function until Enable is
+// called, without touching the code of the target. Disabling the guard of
+// a layer only lets calls through that layer.
+func (g *PatchGuard) Disable() {
+	if g.layer != nil {
+		atomic.StoreUint32(&g.layer.off, 1)
+	} else if g.entry != nil {
+		atomic.StoreUint32(&g.entry.off, 1)
+	}
+}
+
+// Enable dispatches calls to the replacement again after Disable.
+func (g *PatchGuard) Enable() {
+	if g.layer != nil {
+		atomic.StoreUint32(&g.layer.off, 0)
+	} else if g.entry != nil {
+		atomic.StoreUint32(&g.entry.off, 0)
+	}
+}
+
+// Hits returns how many calls were dispatched to the replacement. Calls a
+// conditional replacement, like those of PatchReceiver, hands on to the
+// original function are not counted. For guards of layers, like those of
+// TraceCalls, it is the number of calls run through the layer.
+func (g *PatchGuard) Hits() uint64 {
+	if g.layer != nil {
+		return atomic.LoadUint64(&g.layer.calls)
+	}
+	if g.entry == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&g.entry.calls)
+}
+
+// PassThroughs returns how many calls on the goroutine of the patch ran the
+// original function: while the patch was disabled, made from the
+// replacement itself, or handed on by a conditional replacement. Calls
+// from other goroutines are not counted, unless the patch applies to
+// them, see ForLabels.
+func (g *PatchGuard) PassThroughs() uint64 {
+	if g.entry == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&g.entry.passed)
+}
+
+// CreatedAt returns the file and line of the call that made g, the first
+// one outside of this package.
+func (g *PatchGuard) CreatedAt() string {
+	return siteString(g.site)
+}
+
+// Target returns the patched function.
+func (g *PatchGuard) Target() reflect.Value {
+	return g.target
+}
+
+// Replacement returns the function called instead of the target.
+func (g *PatchGuard) Replacement() reflect.Value {
+	return g.replacement
+}
+
+// TargetName returns the fully qualified name of the target,
+// e.g. "net/http.(*Client).Do".
+func (g *PatchGuard) TargetName() string {
+	if g.target.Kind() != reflect.Func {
+		return ""
+	}
+	return SymbolName(g.target.Pointer())
+}
+
+// ReplacementName returns the fully qualified name of the replacement.
+func (g *PatchGuard) ReplacementName() string {
+	if g.replacement.Kind() != reflect.Func || g.replacement.IsNil() {
+		return ""
+	}
+	return SymbolName(g.replacement.Pointer())
 }
 
 // Patch replaces a function with another
-func Patch(target, replacement interface{}) *PatchGuard {
-	t := reflect.ValueOf(target)
-	r := reflect.ValueOf(replacement)
-	patchValue(t, r)
+//
+// A method expression, like T.Method or (*T).Method, is patched as by
+// PatchInstanceMethod with the receiver type and the name of the method.
+func Patch(target, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatch(target, replacement, opts...)
+	if err != nil {
+		return inertGuard(reflect.ValueOf(target), reflect.ValueOf(replacement), err)
+	}
+	return g
+}
 
-	return &PatchGuard{t, r}
+// TryPatch is like Patch but returns an error instead of panicking.
+func TryPatch(target, replacement interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	v := reflect.ValueOf(target)
+	t, name, ok, err := methodExpr(v)
+	if err != nil {
+		return nil, patchError("patch", SymbolName(v.Pointer()), err)
+	}
+	if ok {
+		return TryPatchInstanceMethod(t, name, replacement, opts...)
+	}
+	return patchGuard(v, reflect.ValueOf(replacement), opts)
 }
 
 // PatchInstanceMethod replaces an instance method methodName for the type target with replacement
 // Replacement should expect the receiver (of type target) as the first argument
-func PatchInstanceMethod(target reflect.Type, methodName string, replacement interface{}) *PatchGuard {
+//
+// A method promoted from an embedded field is only a wrapper calling the
+// method of the field, which calls through the field don't go through. So a
+// replacement taking the receiver of the embedded type, e.g. *Inner for a
+// method of Inner promoted to *Outer, patches the method of Inner itself,
+// and then every type embedding Inner and every call on an Inner are
+// affected. A replacement taking target fails with ErrPromoted, unless the
+// FollowWrapper option is given.
+func PatchInstanceMethod(target reflect.Type, methodName string, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatchInstanceMethod(target, methodName, replacement, opts...)
+	if err != nil {
+		return inertMethodGuard(target, methodName, replacement, err)
+	}
+	return g
+}
+
+// TryPatchInstanceMethod is like PatchInstanceMethod but returns an error
+// instead of panicking.
+func TryPatchInstanceMethod(target reflect.Type, methodName string, replacement interface{}, opts ...PatchOption) (*PatchGuard, error) {
 	m, ok := target.MethodByName(methodName)
 	if !ok {
-		panic(fmt.Sprintf("unknown method %s", methodName))
+		return nil, patchError("patch", target.String()+"."+methodName, errors.New("unknown method"))
+	}
+	u, promoted, err := promotedMethod(target, m)
+	if err != nil {
+		return nil, patchError("patch", SymbolName(m.Func.Pointer()), err)
 	}
 	r := reflect.ValueOf(replacement)
-	patchValue(m.Func, r)
+	if promoted && r.Kind() == reflect.Func && r.Type().NumIn() > 0 && r.Type().In(0) == u.Type.In(0) {
+		return patchGuard(u.Func, r, opts)
+	}
+	if promoted && !newPatchConfig(opts).follow {
+		return nil, patchError("patch", SymbolName(m.Func.Pointer()), fmt.Errorf("%w: %s.%s calls %s, patch it with a replacement taking a %s, or pass FollowWrapper",
+			ErrPromoted, target, methodName, SymbolName(u.Func.Pointer()), u.Type.In(0)))
+	}
+	return patchGuard(m.Func, r, opts)
+}
+
+func patchGuard(target, replacement reflect.Value, opts []PatchOption) (*PatchGuard, error) {
+	g := &PatchGuard{
+		target:      target,
+		replacement: replacement,
+		config:      newPatchConfig(opts),
+		site:        callSite(),
+		stack:       callStack(),
+	}
+	if err := patchValue(g); err != nil {
+		return nil, err
+	}
+
+	return g, nil
+}
+
+// check reports err as configured by Options.PanicOnError.
+func check(err error) {
+	if err == nil {
+		return
+	}
+	if CurrentOptions().PanicOnError {
+		panic(err)
+	}
+	logf("%v", err)
+}
 
-	return &PatchGuard{m.Func, r}
+// inertGuard checks err and returns a guard holding it.
+func inertGuard(target, replacement reflect.Value, err error) *PatchGuard {
+	check(err)
+	return &PatchGuard{target: target, replacement: replacement, config: &patchConfig{}, err: err, site: callSite(), stack: callStack()}
+}
+
+// pkgPrefix starts the names of the functions of this package.
+const pkgPrefix = "github.com/go-kiss/monkey."
+
+// callSite returns the innermost frame of the stack outside of this
+// package.
+func callSite() runtime.Frame {
+	pc := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, pkgPrefix) || !more {
+			return f
+		}
+	}
 }
 
 // See reflect.Value
@@ -60,49 +304,216 @@ func getPtr(v reflect.Value) unsafe.Pointer {
 	return (*value)(unsafe.Pointer(&v)).ptr
 }
 
-func patchValue(target, replacement reflect.Value) {
+func patchValue(pg *PatchGuard) error {
+	if auditPatch(pg) {
+		return nil
+	}
+	if disabled {
+		return ErrDisabled
+	}
+
+	// Errors of BeforeApply hooks are theirs, and returned as is.
+	err := beforeApply(pg)
+	if err == nil {
+		err = patchError("patch", pg.describeTarget(), applyGuard(pg))
+	}
+	afterApply(pg, err)
+	return err
+}
+
+func applyGuard(pg *PatchGuard) error {
+	target, replacement, c := pg.target, pg.replacement, pg.config
+
 	lock.Lock()
 	defer lock.Unlock()
 
+	if err := checkPolicy(); err != nil {
+		return err
+	}
+	if err := claimName(pg); err != nil {
+		return err
+	}
+
 	if target.Kind() != reflect.Func {
-		panic("target has to be a Func")
+		return errors.New("target has to be a Func")
 	}
 
-	if replacement.Kind() != reflect.Func {
-		panic("replacement has to be a Func")
+	if pg.layer == nil && replacement.Kind() != reflect.Func {
+		return errors.New("replacement has to be a Func")
 	}
 
-	if target.Type() != replacement.Type() {
-		panic(fmt.Sprintf("target and replacement have to have the same type %s != %s", target.Type(), replacement.Type()))
+	if pg.layer == nil && target.Type() != replacement.Type() {
+		return fmt.Errorf("target and replacement have to have the same type %s != %s, target is %s", target.Type(), replacement.Type(), describeFunc(target.Pointer(), target.Type()))
 	}
 
-	p, ok := patches[target.Pointer()]
+	if c.onContext && (target.Type().NumIn() == 0 || target.Type().In(0) != contextType) {
+		return fmt.Errorf("monkey: OnContext needs a target taking a context.Context first, not %s", target.Type())
+	}
+
+	from := target.Pointer()
+	if isDynamicFunc(from) {
+		return fmt.Errorf("%w: %s", ErrDynamicFunc, target.Type())
+	}
+	if c.follow {
+		from = followTailCalls(from)
+		if from != target.Pointer() {
+			setAlias(target.Pointer(), from)
+		}
+	}
+	if err := checkLinkShared(from); err != nil {
+		return err
+	}
+	if err := checkIntrinsic(SymbolName(from), c); err != nil {
+		return err
+	}
+	if isAssembly(from) {
+		if err := checkAssembly(from, target.Type(), c); err != nil {
+			return err
+		}
+	}
+
+	gid := curG()
+	if c.when != nil {
+		gid = anyG
+	}
+	p, ok := loadRegistry().patches[from]
+	if !ok || !p.active && p.installing == nil {
+		// The jump is yet to be written.
+		if err := checkBreakpoint(from, c); err != nil {
+			return err
+		}
+	}
 	if !ok {
-		p = &patch{from: target.Pointer()}
-		patches[target.Pointer()] = p
+		if err := checkMaxTargets(); err != nil {
+			return err
+		}
+		p = &patch{from: from}
+		setPatch(p)
+	}
+	if p.stub == nil && c.chain {
+		p.chain = true
+	}
+	if pg.layer != nil {
+		return p.applyLayer(pg, gid)
 	}
 	if !replacement.IsNil() {
-		p.Add((uintptr)(getPtr(replacement)))
+		// The entry of the goroutine may only hold layers so far, the
+		// patch then takes it over.
+		layered, ok := p.entries()[gid]
+		ok = ok && gid != anyG
+		if gid == anyG && pg.entry != nil && p.holds(pg) {
+			return fmt.Errorf("monkey: %s is already patched for every goroutine by this guard", SymbolName(from))
+		} else if ok && (layered.guard != nil || len(layered.layers) == 0) {
+			return &PatchError{Conflict: layered.site, ConflictStack: formatStack(layered.stack), Err: fmt.Errorf("monkey: %s is already patched on this goroutine", SymbolName(from))}
+		}
+		o := CurrentOptions()
+		if o.MaxPatches > 0 && live >= o.MaxPatches {
+			return fmt.Errorf("%w: %d in place", ErrMaxPatches, live)
+		}
+		if o.MaxGoroutinesPerTarget > 0 && len(p.entries()) >= o.MaxGoroutinesPerTarget {
+			return fmt.Errorf("%w: %s is patched for %d", ErrMaxGoroutines, SymbolName(from), len(p.entries()))
+		}
+		if pg.entry == nil {
+			e := &entry{}
+			if ok {
+				e = layered
+			}
+			fn := replacement
+			if c.record {
+				pg.recorder = &recorder{}
+				fn = pg.recorder.wrap(fn)
+			}
+			if c.trace {
+				fn = traceRegions(pg, fn)
+			}
+			if c.unpatchOnPanic {
+				fn = unpatchOnPanic(pg, fn)
+			}
+			fn = hookCalls(pg, fn)
+			if !c.reentrant && c.when != nil {
+				fn = pg.guardSharedReentry(fn)
+			}
+			e.base = fn
+			e.pkg = funcPackage(pg.site.Function)
+			e.site, e.stack = pg.CreatedAt(), pg.stack
+			pg.entry = e
+		} else if !ok {
+			// Layers left with the entry when it was removed are gone.
+			pg.entry.layers = nil
+		}
+		pg.entry.guard = pg
+		pg.gid = gid
+		if gid == anyG {
+			pg.entry.rebuild(p, target.Type())
+			p.addShared(pg, target.Type())
+		} else if ok {
+			p.adopt(gid, pg.entry, target.Type())
+		} else {
+			pg.entry.rebuild(p, target.Type())
+			p.Add(gid, pg.entry)
+		}
+	}
+	if err := p.installUnlocked(); err != nil {
+		if gid == anyG && p.holds(pg) {
+			p.unaddShared(pg)
+		} else if e := p.entries()[gid]; e == pg.entry && len(e.layers) > 0 {
+			e.guard = nil
+			e.rebuild(p, target.Type())
+		} else if e == pg.entry {
+			p.setEntries(p.withEntry(gid, nil))
+			live--
+		}
+		return err
+	}
+	if gid == anyG {
+		pg.entry.original = funcValue(uintptr(p.trampoline), target.Type())
+		if r := p.entries()[anyG]; !r.original.IsValid() {
+			r.original = pg.entry.original
+		}
 	}
-	p.Apply()
+	if c.name != "" {
+		names[c.name] = pg
+	}
+	logf("patched %s", SymbolName(from))
+	return nil
 }
 
 // PatchEmpty patches target with empty patch.
 // Call the target will run the original func.
 func PatchEmpty(target interface{}) {
+	check(patchError("patch", SymbolName(reflect.ValueOf(target).Pointer()), patchEmpty(target)))
+}
+
+func patchEmpty(target interface{}) error {
+	if auditing() {
+		return nil
+	}
+	if disabled {
+		return ErrDisabled
+	}
+
 	lock.Lock()
 	defer lock.Unlock()
 
+	if err := checkPolicy(); err != nil {
+		return err
+	}
+
 	t := reflect.ValueOf(target).Pointer()
+	if isDynamicFunc(t) {
+		return ErrDynamicFunc
+	}
+	if err := checkLinkShared(t); err != nil {
+		return err
+	}
 
-	p, ok := patches[t]
-	if ok {
-		return
+	if _, ok := loadRegistry().patches[t]; ok {
+		return nil
 	}
 
-	p = &patch{from: t}
-	patches[t] = p
-	p.Apply()
+	p := &patch{from: t}
+	setPatch(p)
+	return p.installUnlocked()
 }
 
 // Unpatch removes any monkey patches on target
@@ -116,109 +527,473 @@ func Unpatch(target interface{}) bool {
 func UnpatchInstanceMethod(target reflect.Type, methodName string) bool {
 	m, ok := target.MethodByName(methodName)
 	if !ok {
-		panic(fmt.Sprintf("unknown method %s", methodName))
+		panic(patchError("unpatch", target.String()+"."+methodName, errors.New("unknown method")))
 	}
-	return unpatchValue(m.Func)
+	if unpatchValue(m.Func) {
+		return true
+	}
+	// The method it is promoted from may have been patched instead.
+	if u, promoted, _ := promotedMethod(target, m); promoted {
+		return unpatchValue(u.Func)
+	}
+	return false
+}
+
+// Goroutines returns the number of goroutines target is currently patched
+// for. A patch applying to every goroutine, see ForLabels, counts as one.
+func Goroutines(target interface{}) int {
+	if p, ok := lookupPatch(reflect.ValueOf(target).Pointer()); ok {
+		return len(p.entries())
+	}
+	return 0
 }
 
 // UnpatchAll removes all applied monkeypatches
 func UnpatchAll() {
 	lock.Lock()
 	defer lock.Unlock()
-	for _, p := range patches {
-		p.patches = nil
-		p.Apply()
+	for _, p := range loadRegistry().patches {
+		for _, e := range p.entries() {
+			p.fold(e)
+		}
+		live -= len(p.entries())
+		if n := len(p.sharedGuards()); n > 1 {
+			live -= n - 1
+		}
+		p.shared.Store([]*PatchGuard(nil))
+		p.setEntries(nil)
+		check(patchError("unpatch", SymbolName(p.from), p.Apply()))
+		p.release()
 	}
+	logf("unpatched everything")
 }
 
+// UnpatchAllForCurrentGoroutine removes the patches of the calling
+// goroutine from every function, leaving those of other goroutines in place.
+func UnpatchAllForCurrentGoroutine() {
+	lock.Lock()
+	defer lock.Unlock()
+	gid := curG()
+	for _, p := range loadRegistry().patches {
+		p.Del(gid, nil)
+	}
+}
+
+// Quiesce waits for the patches being made or removed by other goroutines,
+// then makes every thread see the code and tables as they are now. Calls
+// starting after Quiesce returns, on any goroutine, observe the current
+// state. Without it, a thread already past the jump into a stub could
+// still pick the previous table.
+func Quiesce() {
+	lock.Lock()
+	defer lock.Unlock()
+	// Stubs are built and jumps written without lock, see installUnlocked.
+	for waited := true; waited; {
+		waited = false
+		for _, p := range loadRegistry().patches {
+			if ch := p.installing; ch != nil {
+				lock.Unlock()
+				<-ch
+				lock.Lock()
+				waited = true
+			}
+		}
+	}
+	if err := raw.SyncCores(); err != nil {
+		logf("syncing cores: %v", err)
+	}
+}
+
+// syncCores makes every thread see the code written to the text segment so
+// far, logging the first failure only, as the platform or the kernel lacking
+// a way to do it won't change.
+func syncCores() {
+	if err := raw.SyncCores(); err != nil {
+		syncCoresFailed.Do(func() { logf("syncing cores: %v", err) })
+	}
+}
+
+var syncCoresFailed sync.Once
+
 // Unpatch removes a monkeypatch from the specified function
 // returns whether the function was patched in the first place
 func unpatchValue(target reflect.Value) bool {
 	lock.Lock()
 	defer lock.Unlock()
-	patch, ok := patches[target.Pointer()]
+	patch, ok := lookupPatch(target.Pointer())
 	if !ok {
 		return false
 	}
 
-	return patch.Del()
+	return patch.Del(curG(), nil)
 }
 
-func unpatch(target uintptr, p *patch) {
-	copyToLocation(target, p.original)
+func unpatch(target uintptr, p *patch) error {
+	return raw.CopyToText(target, p.original)
 }
 
 type patch struct {
 	from uintptr
 
 	original []byte
-	patch    []byte
+	// stub is the code the target jumps to, followed by the trampoline:
+	// the original prologue relocated and a jump back into the target.
+	stub       []byte
+	trampoline unsafe.Pointer
+	// installing is closed once the goroutine installing the patch
+	// without holding lock is done, see installUnlocked.
+	installing chan struct{}
+	// chain lets the patch go through the jump of another library, see
+	// ChainForeignPatches.
+	chain bool
+	// active tells whether the target jumps to the stub, which it stops
+	// doing once its last entry is removed, see release.
+	active bool
+
+	// table points to the first element of the dispatchEntry array read
+	// by the dispatcher. It is replaced as a whole on every change.
+	table unsafe.Pointer
+	// route is where the stub jumps: to its own direct code while a
+	// single goroutine has patched the target, and to the dispatcher
+	// for good once another one or every goroutine does, see store.
+	route uintptr
+	// tables keeps the tables installed since the last Compact but one
+	// reachable, the current one last, as a thread may still be reading
+	// an old one in the dispatcher. Those before settled were replaced
+	// before the last Compact, see retireTables.
+	tables  [][]dispatchEntry
+	settled int
+
+	// byG holds the entries by goroutine, see entries, shared the
+	// guards of the patches applying to every goroutine, see
+	// sharedGuards.
+	byG    atomic.Value
+	shared atomic.Value
+
+	// installs counts every replacement added, calls the calls served by
+	// entries already removed. Both are read without lock, like peak,
+	// the highest number of entries the patch had at once.
+	installs uint64
+	calls    uint64
+	peak     uint64
+}
 
-	patched bool
+// dispatchEntry is an element of the table of a patch. The last element has
+// a zero g and e set to the trampoline.
+type dispatchEntry struct {
+	g uintptr
+	e unsafe.Pointer
+}
 
-	// g pointer => patch func pointer
-	patches map[uintptr]uintptr
+// entry is a replacement installed for a single goroutine.
+type entry struct {
+	to uintptr
+	// off and busy are checked together by the dispatcher, which runs
+	// the original function instead of "to" while either is not zero.
+	// off is set by Disable, busy while the replacement runs, see
+	// AllowReentry: to 1 by guardCall, to 2 by guardReentry.
+	off  uint32
+	busy uint32
+	// slow is set for the entries going through guardCall, to the
+	// replacement guarded by guardReentry instead, for the calls left
+	// with too little stack for guardCall.
+	slow uintptr
+	// calls is incremented by the stub each time it dispatches to "to",
+	// passed each time it finds the entry and runs the original instead.
+	calls  uint64
+	passed uint64
+	// folded is the part of calls already added to patch.calls.
+	folded uint64
+	// fn and slowFn keep the replacements reachable while the stub
+	// refers to them.
+	fn     reflect.Value
+	slowFn reflect.Value
+	// seq orders entries in tables by when they were last added.
+	seq uint64
+	// pkg is the package the entry was made from, see UnpatchAllFrom,
+	// site and stack where, see PatchGuard.CreatedAt and CreationStack.
+	pkg   string
+	site  string
+	stack []uintptr
+	// original calls the trampoline, for entries shared by every
+	// goroutine, which can't be disabled to call the target.
+	original reflect.Value
+	// guard made the entry, nil for the one resolving the patches shared
+	// by every goroutine, see addShared, and for those only holding
+	// layers.
+	guard *PatchGuard
+	// base is the replacement of guard, which layers run around, first
+	// one outermost, see rebuild.
+	base   reflect.Value
+	layers []*layer
 }
 
-func (p *patch) Add(to uintptr) {
-	if p.patches == nil {
-		p.patches = make(map[uintptr]uintptr)
+func (p *patch) Add(gid uintptr, e *entry) {
+	if found, ok := p.entries()[gid]; ok {
+		panic(patchError("patch", SymbolName(p.from), &PatchError{Conflict: found.site, ConflictStack: formatStack(found.stack), Err: errors.New("patch exists")}))
 	}
 
-	gid := (uintptr)(g.G())
-
-	if _, ok := p.patches[gid]; ok {
-		panic("patch exists")
+	added++
+	e.seq = added
+	p.setEntries(p.withEntry(gid, e))
+	atomic.AddUint64(&p.installs, 1)
+	live++
+	if n := uint64(len(p.entries())); n > atomic.LoadUint64(&p.peak) {
+		atomic.StoreUint64(&p.peak, n)
 	}
-
-	p.patches[gid] = to
 }
 
-func (p *patch) Del() bool {
-	if p.patches == nil {
+// Del removes the entry of the goroutine gid, only if it is e unless e is
+// nil.
+func (p *patch) Del(gid uintptr, e *entry) bool {
+	found, ok := p.entries()[gid]
+	if !ok || e != nil && found != e {
 		return false
 	}
+	e = found
+	p.fold(e)
+	p.setEntries(p.withEntry(gid, nil))
+	live--
+	check(patchError("unpatch", SymbolName(p.from), p.Apply()))
+	p.release()
+	logf("unpatched %s", SymbolName(p.from))
+	return true
+}
 
-	gid := (uintptr)(g.G())
-	if _, ok := p.patches[gid]; !ok {
-		return false
+// fold adds the calls served by e since it was last folded to p.calls.
+func (p *patch) fold(e *entry) {
+	n := atomic.LoadUint64(&e.calls)
+	atomic.AddUint64(&p.calls, n-atomic.LoadUint64(&e.folded))
+	atomic.StoreUint64(&e.folded, n)
+}
+
+func (p *patch) Apply() error {
+	if p.stub == nil {
+		if p.installing != nil {
+			// The table is stored once the stub is built.
+			return nil
+		}
+		if len(p.entries()) == 0 {
+			// Nothing to dispatch, e.g. the stub could not be built
+			// for the first patch.
+			return nil
+		}
+		return p.install()
 	}
-	delete(p.patches, gid)
-	p.Apply()
-	return true
+	p.store(p.Marshal())
+	if !p.active && len(p.entries()) > 0 {
+		if err := p.writeJump(); err != nil {
+			return err
+		}
+		p.active = true
+	}
+	return nil
 }
 
-func (p *patch) Apply() {
-	p.patch = p.Marshal()
+// release writes the original code of the target back once no goroutine
+// has an entry left, so that fully unpatched functions don't go through the
+// stub anymore. The stub is kept for the next patch.
+func (p *patch) release() {
+	if !p.active || len(p.entries()) > 0 || p.installing != nil {
+		return
+	}
+	if err := p.restore(); err != nil {
+		logf("restoring %s: %v", SymbolName(p.from), err)
+		return
+	}
+	p.active = false
+}
 
-	v := reflect.ValueOf(p.patch)
-	allowExec(v.Pointer(), len(p.patch))
+// install builds the stub of the patch and makes the target jump to it.
+func (p *patch) install() error {
+	if err := loadDispatcher(); err != nil {
+		return err
+	}
+	original, b, direct, n, err := p.build()
+	if err != nil {
+		return err
+	}
+	p.original, p.stub = original, b
+	p.trampoline = unsafe.Pointer(&b[n])
+	p.route = uintptr(unsafe.Pointer(&b[direct]))
+	p.store(p.Marshal())
+	if err := p.writeJump(); err != nil {
+		p.stub = nil
+		return err
+	}
+	p.active = true
+	addStub(uintptr(unsafe.Pointer(&b[0])), len(b), "stub for "+SymbolName(p.from))
+	return nil
+}
 
-	if p.patched {
-		data := littleEndian(v.Pointer())
-		copyToLocation(p.from+2, data)
-	} else {
-		jumpData := jmpToFunctionValue(v.Pointer())
-		copyToLocation(p.from, jumpData)
-		p.patched = true
+// installUnlocked is Apply for callers holding lock, which it releases while
+// building the stub and writing the jump, which take milliseconds, so that
+// other functions can be patched meanwhile. Goroutines applying p while it
+// is being installed wait for the first one.
+func (p *patch) installUnlocked() error {
+	if ch := p.installing; ch != nil {
+		lock.Unlock()
+		<-ch
+		lock.Lock()
+		return p.Apply()
+	}
+	if p.stub != nil {
+		return p.Apply()
+	}
+	if err := loadDispatcher(); err != nil {
+		return err
 	}
+	ch := make(chan struct{})
+	p.installing = ch
+	defer func() {
+		p.installing = nil
+		close(ch)
+	}()
+
+	lock.Unlock()
+	original, b, direct, n, err := p.build()
+	lock.Lock()
+	if err != nil {
+		return err
+	}
+	// Publish the table before the jump is written, calls may go
+	// through the stub right away.
+	p.original, p.stub = original, b
+	p.trampoline = unsafe.Pointer(&b[n])
+	p.route = uintptr(unsafe.Pointer(&b[direct]))
+	p.store(p.Marshal())
+
+	lock.Unlock()
+	err = p.writeJump()
+	lock.Lock()
+	if err != nil {
+		p.stub = nil
+		return err
+	}
+	p.active = true
+	addStub(uintptr(unsafe.Pointer(&b[0])), len(b), "stub for "+SymbolName(p.from))
+	return nil
 }
 
-func (p *patch) Marshal() (patch []byte) {
-	if p.original == nil {
-		p.original = alginPatch(p.from)
+// build checks the prologue of the target can be moved and assembles the
+// stub in executable memory: the n bytes passing the table of the patch to
+// the dispatcher or to its direct code, which starts at direct, then the
+// trampoline.
+func (p *patch) build() (original, b []byte, direct, n int, err error) {
+	original, moved, chained, err := p.foreignPrologue()
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	if !chained {
+		original, err = alginPatch(p.from)
+		if err != nil {
+			return nil, nil, 0, 0, err
+		}
+	}
+	if err := checkLength(p.from, len(original)); err != nil {
+		return nil, nil, 0, 0, err
+	}
+	if isAssembly(p.from) {
+		if err := checkJumpsInto(p.from, len(original)); err != nil {
+			return nil, nil, 0, 0, err
+		}
+	}
+	if err := checkActive(p.from, len(original)); err != nil {
+		return nil, nil, 0, 0, err
+	}
+	if !chained {
+		moved, err = relocate(original, p.from)
+		if err != nil {
+			return nil, nil, 0, 0, err
+		}
+		moved = append(moved, raw.JmpStub(p.from+uintptr(len(original)))...)
 	}
 
-	patch = getg()
+	code, direct := stub(uintptr(unsafe.Pointer(&p.table)), uintptr(unsafe.Pointer(&p.route)), dispatchOwn)
+	n = len(code)
+	code = append(code, moved...)
+	b, err = raw.AllocExecutable(len(code))
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	copy(b, code)
+	return original, b, direct, n, nil
+}
 
-	for g, to := range p.patches {
-		t := jmpTable(g, to)
-		patch = append(patch, t...)
+// writeJump makes the target jump to the stub.
+func (p *patch) writeJump() error {
+	if err := p.checkCode(p.original); err != nil {
+		return err
 	}
+	jumpData := raw.JmpStub(uintptr(unsafe.Pointer(&p.stub[0])))
+	err := raw.WriteText(p.from, len(jumpData), func(b []byte) {
+		raw.StoreJump(b, jumpData)
+	})
+	if err == nil {
+		// A goroutine may go on on another thread, which could
+		// otherwise still run the prologue it fetched before.
+		syncCores()
+	}
+	return err
+}
+
+// store makes t the table read by the dispatcher. The store is a locked
+// instruction, which orders it after the writes filling t and the entries,
+// and the dispatcher reads the pointer before them, so it never sees a table
+// partly written.
+//
+// The stub is routed to the dispatcher first if t holds more than the entry
+// of a single goroutine. A call already in the direct code may still read
+// t, and then runs the original function unless its goroutine has the first
+// entry. That one is the entry of the goroutine that used to be alone, as
+// entries come in the order they were added, and the goroutine adding the
+// new one isn't in the middle of a call: only calls of other goroutines
+// racing with a patch for every goroutine miss it, as calls racing with
+// any change may, see Quiesce.
+func (p *patch) store(t []dispatchEntry) {
+	if len(t) > 2 || t[0].g == anyG {
+		atomic.StoreUintptr(&p.route, dispatch)
+	}
+	p.tables = append(p.tables, t)
+	atomic.StorePointer(&p.table, unsafe.Pointer(&t[0]))
+}
 
-	patch = append(patch, p.original...)
-	old := jmpToFunctionValue(p.from + uintptr(len(p.original)))
-	patch = append(patch, old...)
+// Marshal builds the table of the patch. Neither the stub nor the
+// dispatcher ever push a frame, so tracebacks taken inside a replacement go
+// straight from the replacement to the caller of the target, with no
+// address the runtime can't symbolize in between.
+//
+// Entries are ordered by when they were added, so that the same patches
+// always give the same table, except for the one shared by every goroutine,
+// which comes last.
+func (p *patch) Marshal() []dispatchEntry {
+	entries := p.entries()
+	t := make([]dispatchEntry, 0, len(entries)+1)
+	for g, e := range entries {
+		t = append(t, dispatchEntry{g: g, e: unsafe.Pointer(e)})
+	}
+	sort.Slice(t, func(i, j int) bool {
+		// The dispatcher takes the first entry for anyG, which has to
+		// come after those of single goroutines.
+		if (t[i].g == anyG) != (t[j].g == anyG) {
+			return t[j].g == anyG
+		}
+		return (*entry)(t[i].e).seq < (*entry)(t[j].e).seq
+	})
+	return append(t, dispatchEntry{e: p.trampoline})
+}
 
-	return
+// StubBytes returns a copy of the code target jumps to since it was
+// patched, or nil if it never was: the stub passing the table of target to
+// the dispatcher, or to the code following it while a single goroutine has
+// patched target, then the prologue of target relocated and a jump back
+// into target. It doesn't change until Compact forgets target.
+func StubBytes(target interface{}) []byte {
+	lock.Lock()
+	defer lock.Unlock()
+	p, ok := lookupPatch(reflect.ValueOf(target).Pointer())
+	if !ok || p.stub == nil {
+		return nil
+	}
+	return append([]byte(nil), p.stub...)
 }
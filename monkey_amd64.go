@@ -1,88 +1,500 @@
 package monkey
 
 import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
 	"golang.org/x/arch/x86/x86asm"
 )
 
-// Assembles a jump to a function value
-func jmpToFunctionValue(to uintptr) []byte {
-	return []byte{
+// stub assembles the code a patched target jumps to. It passes the address
+// of the table of the patch in r13 to the code route points to: the
+// dispatcher, or the direct code following, for targets patched by a single
+// goroutine, which also returns its offset. The direct code doesn't look
+// through the table: it compares the current goroutine with its first
+// element, the single entry, and goes on to the code of the dispatcher
+// handling entries of the current goroutine at own if they are the same,
+// or else to the trampoline, which comes right after it.
+func stub(table, route, own uintptr) (code []byte, direct int) {
+	code = []byte{
+		0x49, 0xBD,
+		byte(table),
+		byte(table >> 8),
+		byte(table >> 16),
+		byte(table >> 24),
+		byte(table >> 32),
+		byte(table >> 40),
+		byte(table >> 48),
+		byte(table >> 56), // movabs r13,table
+		0x49, 0xBC,
+		byte(route),
+		byte(route >> 8),
+		byte(route >> 16),
+		byte(route >> 24),
+		byte(route >> 32),
+		byte(route >> 40),
+		byte(route >> 48),
+		byte(route >> 56), // movabs r12,route
+		// jmp QWORD PTR [r12]
+		0x41, 0xFF, 0x24, 0x24,
+	}
+	direct = len(code)
+	code = append(code,
+		// mov r13,QWORD PTR [r13]
+		0x4D, 0x8B, 0x6D, 0x00,
+	)
+	code = append(append(code, gLoad.code...), gKey()...)
+	return append(code,
+		// cmp r12,QWORD PTR [r13]
+		0x4D, 0x3B, 0x65, 0x00,
+		// jne trampoline
+		0x75, 0x0D,
+		// movabs r12,own
+		0x49, 0xBC,
+		byte(own),
+		byte(own>>8),
+		byte(own>>16),
+		byte(own>>24),
+		byte(own>>32),
+		byte(own>>40),
+		byte(own>>48),
+		byte(own>>56),
+		// jmp r12
+		0x41, 0xFF, 0xE4,
+	), direct
+}
+
+// dispatcher assembles the routine shared by every stub. It looks up the
+// entry of the current goroutine in the table r13 points to, or else the
+// entry shared by every goroutine under anyG, and jumps to its replacement,
+// or to the trampoline held by the last element of the table if there is
+// none or it is disabled. The off and busy fields of the entry are tested
+// at once. Calls are counted with a lock prefix only in the entry shared by
+// every goroutine, as only its own goroutine updates the others. Targets
+// patched by a single goroutine, the usual case, skip the lookup, see
+// stub: their stubs join the dispatcher at own, returned along with the
+// code, with r13 pointing to the element of the goroutine already. Only
+// r12 and r13 are used, and rdx once the call is going to the replacement,
+// which is a closure. Goroutines are told apart by g, or by ID, see gKey.
+//
+// Entries guarded against reentry, which have slow set, go through
+// guardCall, at ret, with the entry left below the stack pointer, or
+// through slow if the stack is too short for guardCall. A busy entry
+// passes the call on, unless guardCall set busy and the frame pointers of
+// the caller lead to no frame of guardCall running the entry, as when the
+// replacement panicked: rax is saved below the stack pointer as well to
+// walk them.
+func dispatcher(ret uintptr) (code []byte, own int) {
+	var e entry
+	off := byte(unsafe.Offsetof(e.off))
+	busy := byte(unsafe.Offsetof(e.busy))
+	slow := byte(unsafe.Offsetof(e.slow))
+	calls := byte(unsafe.Offsetof(e.calls))
+	passed := byte(unsafe.Offsetof(e.passed))
+	to := byte(unsafe.Offsetof(e.to))
+	stack := -int32(guardStack)
+
+	b := append(append([]byte(nil), gLoad.code...), gKey()...)
+	b = append(b,
+		// mov r13,QWORD PTR [r13]
+		0x4D, 0x8B, 0x6D, 0x00,
+		// loop:
+		// cmp QWORD PTR [r13],0
+		0x49, 0x83, 0x7D, 0x00, 0x00,
+		// je original
+		0x74, 0x13,
+		// cmp r12,QWORD PTR [r13]
+		0x4D, 0x3B, 0x65, 0x00,
+		// je own
+		0x74, 0x3D,
+		// cmp QWORD PTR [r13],anyG
+		0x49, 0x83, 0x7D, 0x00, 0xFF,
+		// je shared
+		0x74, 0x0A,
+		// add r13,16
+		0x49, 0x83, 0xC5, 0x10,
+		// jmp loop
+		0xEB, 0xE6,
+		// original:
+		// jmp QWORD PTR [r13+8]
+		0x41, 0xFF, 0x65, 0x08,
+		// shared:
+		// mov r12,QWORD PTR [r13+8]
+		0x4D, 0x8B, 0x65, 0x08,
+		// cmp QWORD PTR [r12+off],0
+		0x49, 0x83, 0x7C, 0x24, off, 0x00,
+		// jne passthrough
+		0x75, 0x0D,
+		// lock inc QWORD PTR [r12+calls]
+		//
+		// Entries for anyG are found by every goroutine, hence the
+		// lock prefix.
+		0xF0, 0x49, 0xFF, 0x44, 0x24, calls,
+		// mov rdx,QWORD PTR [r12+to]
+		0x49, 0x8B, 0x54, 0x24, to,
+		// jmp QWORD PTR [rdx]
+		0xFF, 0x22,
+		// passthrough:
+		// lock inc QWORD PTR [r12+passed]
+		0xF0, 0x49, 0xFF, 0x44, 0x24, passed,
+		// skip:
+		// add r13,16
+		0x49, 0x83, 0xC5, 0x10,
+		// cmp QWORD PTR [r13],0
+		0x49, 0x83, 0x7D, 0x00, 0x00,
+		// jne skip
+		0x75, 0xF5,
+		// jmp original
+		0xEB, 0xD0,
+	)
+	own = len(b)
+	return append(b,
+		// own:
+		// mov r12,QWORD PTR [r13+8]
+		0x4D, 0x8B, 0x65, 0x08,
+		// cmp QWORD PTR [r12+off],0, covering busy as well
+		0x49, 0x83, 0x7C, 0x24, off, 0x00,
+		// jne busy
+		0x75, 0x42,
+		// run:
+		// inc QWORD PTR [r12+calls]
+		//
+		// Only the goroutine of the entry counts its calls, and it
+		// runs on a single thread at a time, so the lock prefix, most
+		// of the cost of dispatching, is left out.
+		0x49, 0xFF, 0x44, 0x24, calls,
+		// mov rdx,QWORD PTR [r12+to]
+		0x49, 0x8B, 0x54, 0x24, to,
+		// cmp QWORD PTR [r12+slow],0
+		0x49, 0x83, 0x7C, 0x24, slow, 0x00,
+		// jne guarded
+		0x75, 0x02,
+		// jmp QWORD PTR [rdx]
+		0xFF, 0x22,
+		// guarded:
+		// lea r13,[rsp-guardStack]
+		0x4C, 0x8D, 0xAC, 0x24, byte(stack), byte(stack>>8), byte(stack>>16), byte(stack>>24),
+		// cmp r13,QWORD PTR [r14+16], the stack guard of g, which
+		// r14 holds under the register ABI
+		0x4D, 0x3B, 0x6E, 0x10,
+		// jbe short
+		0x76, 0x12,
+		// mov QWORD PTR [rsp-16],r12
+		0x4C, 0x89, 0x64, 0x24, 0xF0,
+		// movabs r13,guardCallPC
 		0x49, 0xBD,
-		byte(to),
-		byte(to >> 8),
-		byte(to >> 16),
-		byte(to >> 24),
-		byte(to >> 32),
-		byte(to >> 40),
-		byte(to >> 48),
-		byte(to >> 56),   // movabs r13,to
-		0x41, 0xFF, 0xE5, // jmp r13
+		byte(guardCallPC),
+		byte(guardCallPC>>8),
+		byte(guardCallPC>>16),
+		byte(guardCallPC>>24),
+		byte(guardCallPC>>32),
+		byte(guardCallPC>>40),
+		byte(guardCallPC>>48),
+		byte(guardCallPC>>56),
+		// jmp r13
+		0x41, 0xFF, 0xE5,
+		// short:
+		// mov rdx,QWORD PTR [r12+slow]
+		0x49, 0x8B, 0x54, 0x24, slow,
+		// jmp QWORD PTR [rdx]
+		0xFF, 0x22,
+		// ownpassthrough:
+		// inc QWORD PTR [r12+passed]
+		0x49, 0xFF, 0x44, 0x24, passed,
+		// jmp skip
+		0xEB, 0xA5,
+		// busy:
+		// cmp DWORD PTR [r12+off],0
+		0x41, 0x83, 0x7C, 0x24, off, 0x00,
+		// jne ownpassthrough
+		0x75, 0xF1,
+		// cmp DWORD PTR [r12+busy],1, set by guardCall
+		0x41, 0x83, 0x7C, 0x24, busy, 0x01,
+		// jne ownpassthrough
+		0x75, 0xE9,
+		// mov QWORD PTR [rsp-8],r13
+		0x4C, 0x89, 0x6C, 0x24, 0xF8,
+		// mov QWORD PTR [rsp-16],rax
+		0x48, 0x89, 0x44, 0x24, 0xF0,
+		// mov r13,rbp
+		0x49, 0x89, 0xED,
+		// walk:
+		// test r13,r13
+		0x4D, 0x85, 0xED,
+		// je stale
+		0x74, 0x2C,
+		// movabs rax,ret
+		0x48, 0xB8,
+		byte(ret),
+		byte(ret>>8),
+		byte(ret>>16),
+		byte(ret>>24),
+		byte(ret>>32),
+		byte(ret>>40),
+		byte(ret>>48),
+		byte(ret>>56),
+		// cmp rax,QWORD PTR [r13+8], the frame returning into
+		// guardCall
+		0x49, 0x3B, 0x45, 0x08,
+		// jne next
+		0x75, 0x0A,
+		// mov rax,QWORD PTR [r13+0], the frame of guardCall
+		0x49, 0x8B, 0x45, 0x00,
+		// cmp r12,QWORD PTR [rax-8], its entry
+		0x4C, 0x3B, 0x60, 0xF8,
+		// je nested
+		0x74, 0x06,
+		// next:
+		// mov r13,QWORD PTR [r13+0]
+		0x4D, 0x8B, 0x6D, 0x00,
+		// jmp walk
+		0xEB, 0xDB,
+		// nested:
+		// mov rax,QWORD PTR [rsp-16]
+		0x48, 0x8B, 0x44, 0x24, 0xF0,
+		// mov r13,QWORD PTR [rsp-8]
+		0x4C, 0x8B, 0x6C, 0x24, 0xF8,
+		// jmp ownpassthrough
+		0xEB, 0xAB,
+		// stale:
+		// mov rax,QWORD PTR [rsp-16]
+		0x48, 0x8B, 0x44, 0x24, 0xF0,
+		// mov DWORD PTR [r12+busy],0
+		0x41, 0xC7, 0x44, 0x24, busy, 0x00, 0x00, 0x00, 0x00,
+		// jmp run
+		0xE9, 0x5D, 0xFF, 0xFF, 0xFF,
+	), own
+}
+
+func alginPatch(from uintptr) (original []byte, err error) {
+	return alignPrologue(raw.Memory(from, 32), from)
+}
+
+// alignPrologue returns the whole instructions at the start of f, the code
+// at from, covering the jump written over them.
+func alignPrologue(f []byte, from uintptr) (original []byte, err error) {
+	s := 0
+	for {
+		i, err := x86asm.Decode(f[s:], 64)
+		if err == nil && i.Op == 0 {
+			// Prefixes of encodings the decoder doesn't know, like VEX,
+			// decoded alone.
+			err = fmt.Errorf("unknown instruction % x", f[s:s+i.Len])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("monkey: decoding prologue at %#x: %w", from+uintptr(s), err)
+		}
+		original = append(original, f[s:s+i.Len]...)
+		s += i.Len
+		if s >= raw.JmpSize {
+			return original, nil
+		}
 	}
 }
 
-func littleEndian(to uintptr) []byte {
-	return []byte{
-		byte(to),
-		byte(to >> 8),
-		byte(to >> 16),
-		byte(to >> 24),
-		byte(to >> 32),
-		byte(to >> 40),
-		byte(to >> 48),
-		byte(to >> 56),
+// checkLength returns an error unless the n bytes at the start of the
+// function at from belong to it.
+func checkLength(from uintptr, n int) error {
+	f := runtime.FuncForPC(from + uintptr(n) - 1)
+	if f == nil || f.Entry() != from {
+		return fmt.Errorf("monkey: %s is shorter than the %d bytes the jump covers", SymbolName(from), n)
 	}
+	return nil
 }
 
-// Assembles a jump to a function value
-func jmpToGoFn(to uintptr) []byte {
-	return []byte{
-		0x48, 0xBA,
-		byte(to),
-		byte(to >> 8),
-		byte(to >> 16),
-		byte(to >> 24),
-		byte(to >> 32),
-		byte(to >> 40),
-		byte(to >> 48),
-		byte(to >> 56), // movabs rdx,to
-		0xFF, 0x22,     // jmp QWORD PTR [rdx]
+// checkJumpsInto returns an error if the rest of the code of the function at
+// from jumps into its first n bytes, as far as it can be decoded. Go
+// functions start with their stack check and loops come after it, but
+// assembly ones may loop right from their first instruction.
+func checkJumpsInto(from uintptr, n int) error {
+	sym, err := raw.LookupSymbol(SymbolName(from))
+	if err != nil || sym.Addr != from {
+		return nil
 	}
+
+	code := raw.Memory(from, int(sym.Size))
+	for s := n; s < len(code); {
+		i, err := x86asm.Decode(code[s:], 64)
+		if err != nil || i.Op == 0 {
+			s++
+			continue
+		}
+		s += i.Len
+		if rel, ok := i.Args[0].(x86asm.Rel); ok {
+			to := from + uintptr(s) + uintptr(int64(rel))
+			if to > from && to < from+uintptr(n) {
+				return fmt.Errorf("monkey: %v at %#x jumps into the %d bytes the jump covers in %s", i, from+uintptr(s-i.Len), n, SymbolName(from))
+			}
+		}
+	}
+	return nil
 }
 
-func jmpTable(g, to uintptr) []byte {
-	b := []byte{
-		// movq r13, g
-		0x49, 0xBD,
-		byte(g),
-		byte(g >> 8),
-		byte(g >> 16),
-		byte(g >> 24),
-		byte(g >> 32),
-		byte(g >> 40),
-		byte(g >> 48),
-		byte(g >> 56),
-		// cmp r12, r13
-		0x4D, 0x39, 0xEC,
-		// jne $+(2+12)
-		0x75, 0x0c,
+// breakpointAt returns the address of the first int3 starting an
+// instruction in the bytes of from the jump covers, where a debugger set a
+// breakpoint. Functions short enough to return within them are followed by
+// int3 padding, which isn't looked at.
+func breakpointAt(from uintptr) (uintptr, bool) {
+	code := raw.Memory(from, 32)
+	for s := 0; s < raw.JmpSize; {
+		if code[s] == 0xCC {
+			return from + uintptr(s), true
+		}
+		i, err := x86asm.Decode(code[s:], 64)
+		if err != nil || i.Op == x86asm.RET || i.Op == x86asm.JMP {
+			return 0, false
+		}
+		s += i.Len
+	}
+	return 0, false
+}
+
+// foreignJump recognizes the jumps other patching libraries write at the
+// start of a function, and returns their length and who writes them.
+func foreignJump(code []byte) (n int, by string) {
+	switch {
+	case len(code) >= 12 && code[0] == 0x48 && code[1] == 0xBA && code[10] == 0xFF && code[11] == 0x22:
+		// movabs rdx,replacement; jmp QWORD PTR [rdx]
+		return 12, "bouk/monkey or gomonkey"
+	case len(code) >= raw.JmpSize && bytes.Equal(code[:2], []byte{0x49, 0xBD}) && bytes.Equal(code[10:13], []byte{0x41, 0xFF, 0xE5}):
+		// The jump of JmpStub, written by another copy of this
+		// package.
+		return raw.JmpSize, "another copy of github.com/go-kiss/monkey"
 	}
-	b = append(b, jmpToGoFn(to)...)
-	return b
+	return 0, ""
 }
 
-func alginPatch(from uintptr) (original []byte) {
-	f := rawMemoryAccess(from, 32)
+// relocate returns a copy of the instructions in code, originally located at
+// from, that can run from anywhere. Relative jumps are turned into absolute
+// ones, so that e.g. the stack check of the prologue still reaches the
+// morestack call of the original function when run from the stub.
+func relocate(code []byte, from uintptr) ([]byte, error) {
+	var moved []byte
 
 	s := 0
-	for {
+	for s < len(code) {
+		i, err := x86asm.Decode(code[s:], 64)
+		if err != nil {
+			return nil, err
+		}
+		b := code[s : s+i.Len]
+		s += i.Len
+
+		if m, ok := ripRelative(i); ok {
+			abs, ok := absolute(i, from+uintptr(s)+uintptr(m.Disp))
+			if !ok {
+				return nil, fmt.Errorf("monkey: cannot move rip relative instruction %v at %#x", i, from+uintptr(s))
+			}
+			moved = append(moved, abs...)
+			continue
+		}
+
+		rel, ok := i.Args[0].(x86asm.Rel)
+		if !ok {
+			moved = append(moved, b...)
+			continue
+		}
+		to := from + uintptr(s) + uintptr(int64(rel))
+		if to > from && to < from+uintptr(len(code)) {
+			// It would land in the middle of the jump written over code.
+			return nil, fmt.Errorf("monkey: cannot move jump into the prologue %v at %#x", i, from+uintptr(s))
+		}
+
+		switch {
+		case i.Op == x86asm.JMP:
+			moved = append(moved, raw.JmpStub(to)...)
+		case b[0] >= 0x70 && b[0] <= 0x7F:
+			// Jcc rel8, skip the absolute jump when cc does not hold.
+			moved = append(moved, b[0]^1, 13)
+			moved = append(moved, raw.JmpStub(to)...)
+		case b[0] == 0x0F && b[1] >= 0x80 && b[1] <= 0x8F:
+			// Jcc rel32
+			moved = append(moved, 0x70|(b[1]&0x0F)^1, 13)
+			moved = append(moved, raw.JmpStub(to)...)
+		default:
+			return nil, fmt.Errorf("monkey: cannot move relative instruction %v at %#x", i, from+uintptr(s))
+		}
+	}
+	return moved, nil
+}
+
+// ripRelative returns the memory operand of i addressed relative to rip.
+func ripRelative(i x86asm.Inst) (x86asm.Mem, bool) {
+	for _, a := range i.Args {
+		if m, ok := a.(x86asm.Mem); ok && m.Base == x86asm.RIP {
+			return m, true
+		}
+	}
+	return x86asm.Mem{}, false
+}
+
+// absolute rewrites i, which loads from or takes the address addr relative
+// to rip, to use addr itself. Code built as PIE or with -shared reaches
+// globals, and on older releases the TLS offset of g in the prologue, this
+// way. Only loads into and addresses of 64 bit registers are supported, the
+// register receiving the address first.
+func absolute(i x86asm.Inst, addr uintptr) ([]byte, bool) {
+	dst, ok := i.Args[0].(x86asm.Reg)
+	if !ok || dst < x86asm.RAX || dst > x86asm.R15 {
+		return nil, false
+	}
+	r := byte(dst - x86asm.RAX)
+	rex := byte(0x48)
+	if r >= 8 {
+		rex |= 0x01
+	}
+	b := []byte{rex, 0xB8 | r&7}
+	for n := 0; n < 8; n++ {
+		b = append(b, byte(addr>>(8*n)))
+	} // movabs dst,addr
+
+	switch {
+	case i.Op == x86asm.LEA:
+		return b, true
+	case i.Op == x86asm.MOV && i.MemBytes == 8:
+		if r >= 8 {
+			rex |= 0x04
+		}
+		switch r & 7 {
+		case 4:
+			// rsp and r12 as a base need a SIB byte.
+			return append(b, rex, 0x8B, r&7<<3|4, 0x24), true
+		case 5:
+			// rbp and r13 as a base need a displacement.
+			return append(b, rex, 0x8B, 0x40|r&7<<3|5, 0), true
+		}
+		return append(b, rex, 0x8B, r&7<<3|r&7), true // mov dst,[dst]
+	}
+	return nil, false
+}
+
+// tailCallTarget returns where the function at from jumps to when its body is
+// a tail call leaving the arguments untouched. Nil checks of the receiver
+// are allowed before the jump.
+func tailCallTarget(from uintptr) (uintptr, bool) {
+	f := raw.Memory(from, 32)
+
+	s := 0
+	for s < len(f) {
 		i, err := x86asm.Decode(f[s:], 64)
 		if err != nil {
-			panic(err)
+			return 0, false
 		}
-		original = append(original, f[s:s+i.Len]...)
 		s += i.Len
-		if s >= 13 {
-			return
+		switch i.Op {
+		case x86asm.TEST, x86asm.NOP:
+		case x86asm.JMP:
+			rel, ok := i.Args[0].(x86asm.Rel)
+			if !ok {
+				return 0, false
+			}
+			return from + uintptr(s) + uintptr(int64(rel)), true
+		default:
+			return 0, false
 		}
 	}
+	return 0, false
 }
@@ -1,13 +1,38 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
 package monkey_test
 
 import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"reflect"
 	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
+	"unsafe"
 
 	"github.com/go-kiss/monkey"
+	"github.com/go-kiss/monkey/raw"
 )
 
 func no() bool  { return false }
@@ -59,6 +84,78 @@ func TestGuard(t *testing.T) {
 	monkey.Unpatch(no)
 }
 
+func TestGuardAccessors(t *testing.T) {
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	assert(t, g.Target().Pointer() == reflect.ValueOf(no).Pointer())
+	assert(t, g.Replacement().Pointer() == reflect.ValueOf(yes).Pointer())
+	assert(t, g.TargetName() == "github.com/go-kiss/monkey_test.no", g.TargetName())
+	assert(t, g.ReplacementName() == "github.com/go-kiss/monkey_test.yes", g.ReplacementName())
+}
+
+func TestSession(t *testing.T) {
+	s := monkey.NewSession()
+	s.Patch(no, yes)
+	s.Patch(foo, bar)
+	assert(t, no() && foo(1, 2) == -1)
+	s.Unpatch()
+	assert(t, !no() && foo(1, 2) == 3)
+}
+
+func TestGinkgoSession(t *testing.T) {
+	var cleanups []interface{}
+	s := monkey.GinkgoSession(func(args ...interface{}) { cleanups = append(cleanups, args...) })
+	g := s.Patch(no, yes)
+	assert(t, len(cleanups) == 1)
+
+	m := monkey.HaveBeenCalledTimes(2)
+	no()
+	ok, err := m.Match(g)
+	assert(t, err == nil && !ok)
+	assert(t, strings.Contains(m.FailureMessage(g), "called 1 times"), m.FailureMessage(g))
+	no()
+	ok, _ = m.Match(g)
+	assert(t, ok)
+	ok, _ = monkey.HaveBeenCalled().Match(g)
+	assert(t, ok)
+
+	cleanups[0].(func())()
+	assert(t, !no())
+	assert(t, g.Hits() == 2)
+}
+
+// TestGinkgoSessionNodes follows the pattern documented by GinkgoSession,
+// with each node on its own goroutine as Ginkgo runs them.
+func TestGinkgoSessionNodes(t *testing.T) {
+	var cleanups []interface{}
+	var s *monkey.Session
+	node := func(f func()) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			f()
+		}()
+		<-done
+	}
+	node(func() {
+		s = monkey.GinkgoSession(func(args ...interface{}) { cleanups = append(cleanups, args...) })
+	})
+
+	patched, cleaned := make(chan bool), make(chan struct{})
+	unpatched := make(chan bool)
+	go func() {
+		s.Patch(no, yes)
+		patched <- no()
+		<-cleaned
+		unpatched <- !no()
+	}()
+	assert(t, <-patched, "patch made in It does not apply to it")
+	assert(t, len(cleanups) == 1)
+	node(cleanups[0].(func()))
+	close(cleaned)
+	assert(t, <-unpatched, "patch outlived the spec")
+}
+
 func TestUnpatchAll(t *testing.T) {
 	assert(t, !no())
 	monkey.Patch(no, yes)
@@ -94,101 +191,2107 @@ func TestOnInstanceMethod(t *testing.T) {
 	assert(t, !i.No())
 }
 
-func TestNotFunction(t *testing.T) {
-	panics(t, func() {
-		monkey.Patch(no, 1)
-	})
-	panics(t, func() {
-		monkey.Patch(1, yes)
-	})
+type inner struct{ x int }
+
+func (i *inner) M(a int) int { return i.x + a }
+
+type outer struct{ inner }
+
+func TestFollowWrapper(t *testing.T) {
+	o := &outer{inner{1}}
+	g := monkey.Patch((*outer).M, func(_ *outer, a int) int { return -a }, monkey.FollowWrapper())
+	assert(t, o.M(1) == -1)
+	assert(t, o.inner.M(2) == -2)
+	g.Unpatch()
+	assert(t, o.M(1) == 2)
 }
 
-func TestNotCompatible(t *testing.T) {
-	panics(t, func() {
-		monkey.Patch(no, func() {})
-	})
+type middle struct{ *inner }
+
+func (i inner) V() int { return i.x }
+
+func TestPromotedMethod(t *testing.T) {
+	o := &outer{inner{1}}
+	typ := reflect.TypeOf(o)
+	_, err := monkey.TryPatchInstanceMethod(typ, "M", func(_ *outer, a int) int { return -a })
+	assert(t, errors.Is(err, monkey.ErrPromoted), err)
+
+	monkey.PatchInstanceMethod(typ, "M", func(_ *inner, a int) int { return -a })
+	assert(t, o.M(1) == -1)
+	assert(t, o.inner.M(2) == -2)
+	assert(t, monkey.UnpatchInstanceMethod(typ, "M"))
+	assert(t, o.M(1) == 2)
+
+	m := middle{&inner{3}}
+	monkey.PatchInstanceMethod(reflect.TypeOf(m), "V", func(inner) int { return 0 })
+	assert(t, m.V() == 0)
+	assert(t, monkey.UnpatchInstanceMethod(reflect.TypeOf(m), "V"))
+	assert(t, m.V() == 3)
 }
 
-func assert(t *testing.T, b bool, args ...interface{}) {
-	t.Helper()
-	if !b {
-		t.Fatal(append([]interface{}{"assertion failed"}, args...))
+func TestMethodExpression(t *testing.T) {
+	o := &outer{inner{1}}
+	_, err := monkey.TryPatch((*outer).M, func(_ *outer, a int) int { return -a })
+	assert(t, errors.Is(err, monkey.ErrPromoted), err)
+
+	g := monkey.Patch((*f).No, func(_ *f) bool { return true })
+	assert(t, (&f{}).No())
+	assert(t, monkey.UnpatchInstanceMethod(reflect.TypeOf(&f{}), "No"))
+	assert(t, !(&f{}).No())
+	assert(t, g.Err() == nil)
+
+	monkey.Patch(inner.V, func(inner) int { return 0 })
+	assert(t, o.V() == 0)
+	assert(t, monkey.Unpatch(inner.V))
+	assert(t, o.V() == 1)
+
+	_, err = monkey.TryPatch(io.Reader.Read, func(io.Reader, []byte) (int, error) { return 0, nil })
+	assert(t, err != nil && strings.Contains(err.Error(), "interface io.Reader"), err)
+}
+
+type closer func() error
+
+func (c closer) Close() error { return c() }
+
+func TestCompose(t *testing.T) {
+	var order []string
+	c := monkey.Compose(
+		closer(func() error { order = append(order, "first"); return io.EOF }),
+		monkey.Patch(no, yes),
+		closer(func() error { order = append(order, "last"); return io.ErrUnexpectedEOF }),
+	)
+	assert(t, no())
+	assert(t, c.Close() == io.ErrUnexpectedEOF)
+	assert(t, !no())
+	assert(t, len(order) == 2 && order[0] == "last" && order[1] == "first", order)
+}
+
+func TestProfile(t *testing.T) {
+	monkey.RegisterProfile("yes", func() { monkey.Patch(no, yes) })
+	if os.Getenv(monkey.ProfileEnv) != "" {
+		assert(t, monkey.ApplyProfile() == nil)
+		defer monkey.Unpatch(no)
+		assert(t, no())
+		return
 	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestProfile$", "-test.v")
+	out, err := monkey.ProfileCmd(cmd, "yes").CombinedOutput()
+	assert(t, err == nil, err, string(out))
+	assert(t, strings.Contains(string(out), "--- PASS: TestProfile"), string(out))
+	assert(t, !no())
 }
 
-func panics(t *testing.T, f func()) {
-	t.Helper()
-	defer func() {
-		t.Helper()
-		if v := recover(); v == nil {
-			t.Fatal("expected panic")
+func TestStackTrace(t *testing.T) {
+	var frames []string
+	monkey.Patch(no, func() bool {
+		pc := make([]uintptr, 8)
+		fs := runtime.CallersFrames(pc[:runtime.Callers(1, pc)])
+		for {
+			f, more := fs.Next()
+			frames = append(frames, f.Function)
+			if !more {
+				break
+			}
 		}
-	}()
-	f()
+		return true
+	})
+	defer monkey.Unpatch(no)
+	assert(t, no())
+	assert(t, len(frames) > 2, frames)
+	assert(t, strings.HasPrefix(frames[0], "github.com/go-kiss/monkey_test.TestStackTrace.func"), frames)
+	assert(t, frames[1] == "github.com/go-kiss/monkey_test.TestStackTrace", frames)
 }
 
 //go:noinline
-func foo(a, b int) int {
-	return a + b
+func deep(n int) int {
+	var buf [64]byte
+	buf[n%64] = byte(n)
+	if n == 0 {
+		return int(buf[0])
+	}
+	return deep(n-1) + int(buf[n%64])
+}
+
+func TestStackGrowth(t *testing.T) {
+	want := deep(100)
+	g := monkey.Patch(deep, func(int) int { return -1 })
+	defer g.Unpatch()
+	c := make(chan int)
+	// A fresh goroutine has to grow its stack inside the moved prologue,
+	// as the patch only applies to this one.
+	go func() { c <- deep(100) }()
+	assert(t, <-c == want)
 }
 
 //go:noinline
-func bar(a, b int) int {
-	return a - b
+func explode(v interface{}) bool {
+	panic(v)
 }
 
-func TestEmpty(t *testing.T) {
-	monkey.PatchEmpty(foo)
+func TestPanicThroughOriginal(t *testing.T) {
+	monkey.Patch(explode, func(interface{}) bool { return false })
+	defer monkey.Unpatch(explode)
 
-	stop := make(chan int)
+	c := make(chan interface{})
+	go func() {
+		deferred := false
+		defer func() { c <- recover(); c <- deferred }()
+		defer func() { deferred = true }()
+		explode("boom")
+	}()
+	assert(t, <-c == "boom")
+	assert(t, (<-c).(bool))
+}
+
+//go:noinline
+func hammer1(a int) int { return a + 1 }
+
+//go:noinline
+func hammer2(a int) int { return a + 1 }
+
+//go:noinline
+func hammer3(a int) int { return a + 1 }
+
+//go:noinline
+func hammer4(a int) int { return a + 1 }
+
+var spinStop uint32
+
+// spin loops without calls until spinStop is set, so that only async
+// preemption can stop the goroutines running it.
+//
+//go:noinline
+func spin(n int) int {
+	// The call gives spin a prologue ahead of the loop.
+	n = hammer1(n)
+	for atomic.LoadUint32(&spinStop) == 0 {
+		n++
+	}
+	return n
+}
+
+func TestPreemptHammer(t *testing.T) {
+	if !strings.Contains(os.Getenv("GODEBUG"), "asyncpreemptoff=0") {
+		// Without async preemption the spinning goroutines below
+		// starve the process for good.
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestPreemptHammer$")
+		cmd.Env = append(os.Environ(), "GODEBUG=asyncpreemptoff=0")
+		out, err := cmd.CombinedOutput()
+		assert(t, ctx.Err() == nil, "goroutines looping in spin were never preempted")
+		assert(t, err == nil, err, string(out))
+		return
+	}
+
+	for _, f := range []func(int) int{hammer1, hammer2, hammer3, hammer4} {
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; ; j++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if v := f(j); v != j+1 {
+						t.Errorf("got %d, want %d", v, j+1)
+						return
+					}
+				}
+			}()
+		}
+
+		// The first patch rewrites the prologue under the callers,
+		// the following ones only swap the stub address.
+		for i := 0; i < 1000; i++ {
+			monkey.Patch(f, func(a int) int { return a - 1 })
+			assert(t, f(1) == 0)
+			monkey.Unpatch(f)
+		}
+		close(stop)
+		wg.Wait()
+	}
+
+	// Every P runs spin, and the prologue it entered is rewritten under
+	// it. This goroutine only gets to run, and to stop the world between
+	// patches, through async preemption of the loop, which resumes into
+	// code that must not have been torn.
 	var wg sync.WaitGroup
-	for i := 1; i < runtime.NumCPU()-1; i++ {
+	var started int32
+	n := runtime.GOMAXPROCS(0)
+	for i := 0; i < n; i++ {
 		wg.Add(1)
-		go func(i int) {
+		go func() {
 			defer wg.Done()
-			j := 0
-			for {
-				f := func(j int) func(a, b int) int {
-					return func(a, b int) int {
-						return a + b + j
-					}
-				}(j)
-				monkey.Patch(foo, f)
-				assert(t, 1+1+j == foo(1, 1))
-				monkey.Unpatch(foo)
-				select {
-				case <-stop:
-					return
-				default:
-					j++
-				}
+			atomic.AddInt32(&started, 1)
+			if v := spin(0); v <= 0 {
+				t.Errorf("spin returned %d", v)
 			}
-		}(i)
+		}()
 	}
-
-	for i := 0; i < 2000000; i++ {
-		assert(t, i+1 == foo(i, 1))
+	for atomic.LoadInt32(&started) < int32(n) {
+		runtime.Gosched()
 	}
-	close(stop)
+	var ms runtime.MemStats
+	for i := 0; i < 100; i++ {
+		monkey.Patch(spin, func(int) int { return -1 })
+		assert(t, spin(0) == -1)
+		monkey.Unpatch(spin)
+		runtime.ReadMemStats(&ms)
+	}
+	atomic.StoreUint32(&spinStop, 1)
 	wg.Wait()
 }
 
-func TestG(t *testing.T) {
-	monkey.Patch(foo, bar)
+type counter struct{ n int }
 
-	assert(t, -1 == foo(1, 2))
-	var wg sync.WaitGroup
-	wg.Add(2)
+func (c *counter) Add(d int) int { c.n += d; return c.n }
+
+func (c *counter) Sum(xs ...int) int { return 0 }
+
+type mockCounter struct{ calls []int }
+
+func (m *mockCounter) Add(d int) int { m.calls = append(m.calls, d); return -1 }
+
+func (m *mockCounter) Sum(xs ...int) int { return len(xs) }
+
+type controller struct{ T testing.TB }
+
+func TestBindMock(t *testing.T) {
+	m := &mockCounter{}
+	var s *monkey.Session
+	t.Run("bound", func(t *testing.T) {
+		s = monkey.BindMock(&controller{t}, reflect.TypeOf(&counter{}), m)
+		c := &counter{}
+		assert(t, c.Add(2) == -1)
+		assert(t, c.Sum(1, 2, 3) == 3)
+	})
+	assert(t, len(m.calls) == 1 && m.calls[0] == 2)
+	assert(t, (&counter{}).Add(2) == 2)
+	s.Unpatch()
+}
+
+//go:noinline
+func fetch(key string) (string, error) { return key + "=value", nil }
+
+var fetchLine = line() - 2
+
+//go:noinline
+func store(key, value string) error {
+	if key+value == "" {
+		return errors.New("empty")
+	}
+	return nil
+}
+
+func TestRunFaultMatrix(t *testing.T) {
+	errFault := errors.New("fault")
+	var runs []string
+	monkey.RunFaultMatrix(t, []monkey.FaultSpec{
+		{fetch, []monkey.Fault{monkey.ErrorFault(errFault), monkey.DelayFault(time.Millisecond)}},
+		{store, []monkey.Fault{monkey.PanicFault("boom")}},
+	}, func(t *testing.T) {
+		runs = append(runs, t.Name())
+		defer func() {
+			v := recover()
+			assert(t, (v != nil) == strings.Contains(t.Name(), "store=panic"), v)
+		}()
+		v, err := fetch("k")
+		if strings.Contains(t.Name(), "fetch=error") {
+			assert(t, v == "" && err == errFault)
+		} else {
+			assert(t, v == "k=value" && err == nil, v, err)
+		}
+		store("k", v)
+	})
+	assert(t, len(runs) == 5, runs)
+	v, err := fetch("k")
+	assert(t, v == "k=value" && err == nil)
+}
+
+func TestIntercept(t *testing.T) {
+	g := monkey.Intercept(fetch, func(in []reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf("test-" + in[0].String())}
+	}, func(out []reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(strings.ToUpper(out[0].String())), out[1]}
+	})
+	v, err := fetch("k")
+	assert(t, v == "TEST-K=VALUE" && err == nil, v, err)
+	v, _ = fetch("j")
+	assert(t, v == "TEST-J=VALUE", v)
+	assert(t, g.Hits() == 2, g.Hits())
+	g.Unpatch()
+
+	monkey.Intercept(fetch, nil, nil)
+	v, _ = fetch("k")
+	assert(t, v == "k=value", v)
+	monkey.Unpatch(fetch)
+
+	_, err = monkey.TryIntercept(1, nil, nil)
+	assert(t, err != nil)
+}
+
+func TestDisable(t *testing.T) {
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	assert(t, no())
+	g.Disable()
+	assert(t, !no())
+	g.Disable()
+	assert(t, !no())
+	g.Enable()
+	assert(t, no())
+	assert(t, g.Hits() == 2, g.Hits())
+}
+
+func TestUnpatchAllForCurrentGoroutine(t *testing.T) {
+	monkey.Patch(no, yes)
+	monkey.Patch(fetch, func(string) (string, error) { return "", nil })
+
+	ready, done := make(chan struct{}), make(chan bool)
 	go func() {
-		defer wg.Done()
-		assert(t, 3 == foo(1, 2))
+		monkey.Patch(no, yes)
+		close(ready)
+		<-done
+		done <- no()
+		monkey.UnpatchAllForCurrentGoroutine()
 	}()
+	<-ready
+
+	monkey.UnpatchAllForCurrentGoroutine()
+	assert(t, !no())
+	v, _ := fetch("k")
+	assert(t, v == "k=value", v)
+
+	done <- true
+	assert(t, <-done)
+}
+
+func TestSessionT(t *testing.T) {
+	leaked := make(chan struct{})
+	defer close(leaked)
+	done := make(chan bool)
+
+	t.Run("sub", func(t *testing.T) {
+		ctx := monkey.ContextWithSession(context.Background(), monkey.SessionT(t))
+		patched := make(chan struct{})
+		go func() {
+			monkey.SessionFromContext(ctx).Patch(no, yes)
+			close(patched)
+			<-done
+			done <- no()
+			<-leaked
+		}()
+		<-patched
+	})
+
+	done <- true
+	assert(t, !<-done)
+	assert(t, monkey.SessionFromContext(context.Background()) == nil)
+}
+
+func TestGuardUnpatchFromOtherGoroutine(t *testing.T) {
+	var g *monkey.PatchGuard
+	done := make(chan bool)
 	go func() {
-		monkey.Patch(foo, func(a, b int) int {
-			return a * b
-		})
-		defer wg.Done()
-		assert(t, 2 == foo(1, 2))
+		g = monkey.Patch(no, yes)
+		done <- no()
+		<-done
+		done <- no()
 	}()
-	wg.Wait()
+	assert(t, <-done)
+	monkey.Patch(no, yes)
+	g.Unpatch()
+	assert(t, no())
+	monkey.Unpatch(no)
+	done <- true
+	assert(t, !<-done)
+}
+
+func TestDynamicFunc(t *testing.T) {
+	made := reflect.MakeFunc(reflect.TypeOf(no), func([]reflect.Value) []reflect.Value {
+		return []reflect.Value{reflect.ValueOf(false)}
+	}).Interface().(func() bool)
+	_, err := monkey.TryPatch(made, yes)
+	assert(t, errors.Is(err, monkey.ErrDynamicFunc), err)
+
+	var s monkey.Session
+	method := reflect.ValueOf(&s).MethodByName("Unpatch").Interface().(func())
+	_, err = monkey.TryPatch(method, func() {})
+	assert(t, errors.Is(err, monkey.ErrDynamicFunc), err)
+	assert(t, !made())
+}
+
+func TestAssemblyTarget(t *testing.T) {
+	floor := monkey.Prototype(math.Floor)
+	_, err := monkey.TryPatchByName("math.archFloor", math.Floor, floor)
+	assert(t, errors.Is(err, monkey.ErrAssemblyTarget), err)
+	// Its arguments are on the stack.
+	_, err = monkey.TryPatchByName("math.archFloor", math.Floor, floor, monkey.AllowAssemblyTargets())
+	assert(t, errors.Is(err, monkey.ErrAssemblyTarget), err)
+
+	// Only run at startup.
+	_, err = monkey.TryPatchByName("runtime.asminit", func() {}, monkey.Prototype(func() {}))
+	assert(t, errors.Is(err, monkey.ErrAssemblyTarget), err)
+	g, err := monkey.TryPatchByName("runtime.asminit", func() {}, monkey.Prototype(func() {}), monkey.AllowAssemblyTargets())
+	assert(t, err == nil, err)
+	g.Unpatch()
+}
+
+func TestIntrinsic(t *testing.T) {
+	_, err := monkey.TryPatch(math.Floor, math.Ceil)
+	assert(t, errors.Is(err, monkey.ErrIntrinsic), err)
+	_, err = monkey.TryPatch(atomic.AddInt32, func(*int32, int32) int32 { return 0 })
+	assert(t, errors.Is(err, monkey.ErrIntrinsic), err)
+
+	g, err := monkey.TryPatch(math.Floor, math.Ceil, monkey.AllowIntrinsics())
+	assert(t, err == nil, err)
+	defer g.Unpatch()
+	floor := math.Floor
+	assert(t, floor(1.5) == 2)
+}
+
+func TestVerify(t *testing.T) {
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	assert(t, g.Verify() == nil, g.Verify())
+
+	g.Disable()
+	assert(t, g.Verify() != nil)
+	g.Enable()
+
+	c := make(chan error)
+	go func() { c <- g.Verify() }()
+	err := <-c
+	assert(t, err != nil && strings.Contains(err.Error(), "not patched for this goroutine"), err)
+
+	f, err := monkey.TryPatch(math.Floor, math.Ceil, monkey.AllowIntrinsics())
+	assert(t, err == nil, err)
+	defer f.Unpatch()
+	assert(t, errors.Is(f.Verify(), monkey.ErrIntrinsic), f.Verify())
+}
+
+//go:noinline
+func hot(n int) int { return n*31 + len(strconv.Itoa(n)) }
+
+func BenchmarkUnpatched(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		hot(i)
+	}
+}
+
+func BenchmarkPatched(b *testing.B) {
+	monkey.Patch(hot, func(n int) int { return n })
+	defer monkey.Unpatch(hot)
+	for i := 0; i < b.N; i++ {
+		hot(i)
+	}
+}
+
+func BenchmarkPatchedOtherGoroutine(b *testing.B) {
+	done := make(chan struct{})
+	go func() {
+		monkey.Patch(hot, func(n int) int { return n })
+		done <- struct{}{}
+		<-done
+		monkey.Unpatch(hot)
+		close(done)
+	}()
+	<-done
+	defer func() {
+		done <- struct{}{}
+		<-done
+	}()
+	for i := 0; i < b.N; i++ {
+		hot(i)
+	}
+}
+
+type lines []string
+
+func (l *lines) Printf(format string, v ...interface{}) {
+	*l = append(*l, fmt.Sprintf(format, v...))
+}
+
+func TestOptions(t *testing.T) {
+	defer monkey.SetOptions(monkey.CurrentOptions())
+	assert(t, monkey.CurrentOptions().PanicOnError)
+
+	var l lines
+	monkey.SetOptions(monkey.Options{Logger: &l, MaxPatches: 1})
+	g := monkey.Patch(no, yes)
+	assert(t, g.Err() == nil && no())
+
+	g2 := monkey.Patch(foo, bar)
+	assert(t, errors.Is(g2.Err(), monkey.ErrMaxPatches), g2.Err())
+	assert(t, foo(1, 2) == 3)
+	g2.Unpatch()
+	g2.Restore()
+
+	g3 := monkey.Patch(no, yes)
+	assert(t, g3.Err() != nil)
+	g.Unpatch()
+	assert(t, !no())
+
+	assert(t, len(l) == 4, l)
+	assert(t, strings.HasPrefix(l[0], "monkey: patched ") && strings.HasSuffix(l[0], ".no"), l[0])
+	assert(t, strings.HasSuffix(l[1], monkey.ErrMaxPatches.Error()+": 1 in place"), l[1])
+	assert(t, strings.HasPrefix(l[3], "monkey: unpatched "), l[3])
+
+	monkey.SetOptions(monkey.Options{PanicOnError: true, MaxPatches: 1})
+	g = monkey.Patch(no, yes)
+	defer g.Unpatch()
+	panics(t, func() { monkey.Patch(foo, bar) })
+}
+
+//go:noinline
+func released(n int) int { return n * 5 }
+
+func TestReleaseOnLastUnpatch(t *testing.T) {
+	code := func() []byte {
+		return append([]byte(nil), raw.Memory(reflect.ValueOf(released).Pointer(), raw.JmpSize)...)
+	}
+	original := code()
+	g := monkey.Patch(released, func(n int) int { return -n })
+	c := make(chan *monkey.PatchGuard)
+	go func() { c <- monkey.Patch(released, func(n int) int { return n }) }()
+	other := <-c
+	assert(t, !bytes.Equal(code(), original))
+
+	g.Unpatch()
+	assert(t, !bytes.Equal(code(), original), "still patched on another goroutine")
+	other.Unpatch()
+	assert(t, bytes.Equal(code(), original))
+	assert(t, released(1) == 5)
+
+	g.Restore()
+	defer g.Unpatch()
+	assert(t, released(1) == -1)
+}
+
+//go:noinline
+func drifted(n int) int { return n * 7 }
+
+func TestCodeChanged(t *testing.T) {
+	from := reflect.ValueOf(drifted).Pointer()
+	g := monkey.Patch(drifted, func(n int) int { return -n })
+	g.Unpatch()
+
+	// Someone else writes to the target meanwhile.
+	first := raw.Memory(from, 1)[0]
+	assert(t, raw.CopyToText(from, []byte{0xCC}) == nil)
+	_, err := monkey.TryPatch(drifted, func(n int) int { return -n })
+	assert(t, errors.Is(err, monkey.ErrCodeChanged), err)
+	assert(t, strings.Contains(err.Error(), "starts with cc"), err)
+
+	assert(t, raw.CopyToText(from, []byte{first}) == nil)
+	g.Restore()
+	defer g.Unpatch()
+	assert(t, drifted(1) == -1)
+}
+
+//go:noinline
+func foreign(n int) int { return n * 11 }
+
+func TestForeignPatch(t *testing.T) {
+	// Patch foreign the way bouk/monkey and gomonkey do.
+	from := reflect.ValueOf(foreign).Pointer()
+	original := append([]byte(nil), raw.Memory(from, 16)...)
+	theirs := func(n int) int { return 0 }
+	fv := *(*uintptr)(unsafe.Pointer(&theirs))
+	jump := []byte{0x48, 0xBA, 0, 0, 0, 0, 0, 0, 0, 0, 0xFF, 0x22}
+	for i := 0; i < 8; i++ {
+		jump[2+i] = byte(fv >> (8 * i))
+	}
+	assert(t, raw.CopyToText(from, jump) == nil)
+	defer func() { assert(t, raw.CopyToText(from, original) == nil) }()
+	assert(t, foreign(1) == 0)
+
+	_, err := monkey.TryPatch(foreign, func(n int) int { return -n })
+	assert(t, errors.Is(err, monkey.ErrForeignPatch), err)
+	assert(t, strings.Contains(err.Error(), "gomonkey"), err)
+
+	g := monkey.Patch(foreign, func(n int) int { return -n }, monkey.ChainForeignPatches())
+	assert(t, foreign(1) == -1)
+	c := make(chan int)
+	go func() { c <- foreign(1) }()
+	assert(t, <-c == 0)
+
+	g.Unpatch()
+	assert(t, bytes.Equal(raw.Memory(from, len(jump)), jump))
+	assert(t, foreign(1) == 0)
+}
+
+func TestPatchError(t *testing.T) {
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	_, err := monkey.TryPatch(no, yes)
+	var pe *monkey.PatchError
+	assert(t, errors.As(err, &pe), err)
+	assert(t, pe.Op == "patch" && strings.HasSuffix(pe.Target, "monkey_test.no"), pe.Op, pe.Target)
+	assert(t, strings.Contains(pe.Site, "monkey_test.go:") && pe.Site != g.CreatedAt(), pe.Site)
+	assert(t, pe.Conflict == g.CreatedAt(), pe.Conflict, g.CreatedAt())
+	assert(t, pe.Goroutine != 0)
+	assert(t, strings.Contains(err.Error(), "already patched on this goroutine, patched at "+g.CreatedAt()), err)
+
+	assert(t, strings.Contains(pe.ConflictStack, "monkey_test.TestPatchError\n"), pe.ConflictStack)
+	assert(t, pe.ConflictStack == g.CreationStack() && strings.Contains(err.Error(), g.CreationStack()), err)
+
+	_, err = monkey.TryPatchInstanceMethod(reflect.TypeOf(s{}), "Nope", func(s) {})
+	assert(t, errors.As(err, &pe) && strings.HasSuffix(pe.Target, "monkey_test.s.Nope"), err)
+}
+
+var ripA, ripB = 1, 2
+
+// ripPair loads globals relative to rip right from its first instruction,
+// without a stack check, like the code of PIE binaries does.
+//
+//go:noinline
+func ripPair() (int, int) { return ripA, ripB }
+
+func TestRIPRelativePrologue(t *testing.T) {
+	g := monkey.Patch(ripPair, func() (int, int) { return 0, 0 })
+	defer g.Unpatch()
+	a, b := ripPair()
+	assert(t, a == 0 && b == 0, a, b)
+
+	// Other goroutines run the loads moved into the stub.
+	c := make(chan [2]int)
+	go func() {
+		a, b := ripPair()
+		c <- [2]int{a, b}
+	}()
+	assert(t, <-c == [2]int{1, 2})
+}
+
+//go:noinline
+func compacted(n int) int { return n * 3 }
+
+func TestCompact(t *testing.T) {
+	monkey.UnpatchAll()
+	g := monkey.Patch(compacted, func(n int) int { return -n })
+	assert(t, compacted(1) == -1)
+	monkey.Compact()
+	assert(t, compacted(1) == -1)
+	g.Unpatch()
+	stub := compactedStub()
+	assert(t, monkey.Compact() > 0)
+	assert(t, monkey.StubBytes(compacted) == nil)
+	assert(t, compacted(1) == 3)
+	for _, e := range monkey.Report() {
+		assert(t, e.Func != "github.com/go-kiss/monkey_test.compacted", e)
+	}
+
+	// The stub is reused once freed by the next call.
+	monkey.Compact()
+	g = monkey.Patch(compacted, func(n int) int { return n })
+	defer g.Unpatch()
+	assert(t, compacted(2) == 2)
+	assert(t, compactedStub() == stub)
+}
+
+func compactedStub() uintptr {
+	for _, s := range monkey.Stubs() {
+		if s.Func == "stub for github.com/go-kiss/monkey_test.compacted" {
+			return s.Addr
+		}
+	}
+	return 0
+}
+
+func TestMaxTargets(t *testing.T) {
+	defer monkey.SetOptions(monkey.CurrentOptions())
+	monkey.UnpatchAll()
+	monkey.Compact()
+	monkey.SetOptions(monkey.Options{PanicOnError: true, MaxTargets: 1})
+
+	g := monkey.Patch(no, yes)
+	_, err := monkey.TryPatch(foo, bar)
+	assert(t, errors.Is(err, monkey.ErrMaxTargets), err)
+	g.Unpatch()
+	g = monkey.Patch(foo, bar)
+	defer g.Unpatch()
+	assert(t, foo(1, 2) == -1)
+	assert(t, monkey.StubBytes(no) == nil)
+}
+
+func TestMaxGoroutinesPerTarget(t *testing.T) {
+	defer monkey.SetOptions(monkey.CurrentOptions())
+	monkey.SetOptions(monkey.Options{PanicOnError: true, MaxGoroutinesPerTarget: 2})
+
+	monkey.Patch(no, yes)
+	defer monkey.Unpatch(no)
+	assert(t, monkey.Goroutines(no) == 1)
+
+	errs := make(chan error)
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := monkey.TryPatch(no, yes)
+			errs <- err
+			<-done
+			monkey.Unpatch(no)
+			errs <- nil
+		}()
+	}
+	err1, err2 := <-errs, <-errs
+	assert(t, (err1 == nil) != (err2 == nil), err1, err2)
+	assert(t, errors.Is(err1, monkey.ErrMaxGoroutines) || errors.Is(err2, monkey.ErrMaxGoroutines))
+	assert(t, monkey.Goroutines(no) == 2)
+	close(done)
+	<-errs
+	<-errs
+	assert(t, monkey.Goroutines(no) == 1)
+	assert(t, monkey.Goroutines(foo) == 0)
+
+	for _, e := range monkey.Report() {
+		if strings.HasSuffix(e.Func, ".no") {
+			assert(t, e.Goroutines == 1 && e.PeakGoroutines >= 2, e)
+		}
+	}
+}
+
+func TestPatchStd(t *testing.T) {
+	g := monkey.PatchStd("os.hostname", func() (string, error) { return "fake", nil })
+	defer g.Unpatch()
+	name, err := os.Hostname()
+	assert(t, name == "fake" && err == nil, name, err)
+
+	_, err = monkey.TryPatchStd("os.hostname", func() string { return "" })
+	assert(t, err != nil && strings.Contains(err.Error(), "func() (string, error)"), err)
+	_, err = monkey.TryPatchStd("os.nothing", func() {})
+	assert(t, errors.Is(err, monkey.ErrUnknownStd), err)
+
+	names := monkey.StdSymbols()
+	assert(t, sort.StringsAreSorted(names) && len(names) > 0, names)
+}
+
+func TestSeedRand(t *testing.T) {
+	want := rand.New(rand.NewSource(42))
+	s := monkey.SeedRand(42)
+	assert(t, rand.Intn(1000) == want.Intn(1000))
+	assert(t, rand.Int63() == want.Int63())
+	assert(t, rand.Float64() == want.Float64())
+	s.Unpatch()
+
+	s = monkey.SeedCryptoRand(7)
+	defer s.Unpatch()
+	want = rand.New(rand.NewSource(7))
+	a, b := make([]byte, 8), make([]byte, 8)
+	crand.Read(a)
+	want.Read(b)
+	assert(t, bytes.Equal(a, b), a, b)
+	io.ReadFull(crand.Reader, a)
+	want.Read(b)
+	assert(t, bytes.Equal(a, b), a, b)
+}
+
+func TestDeterministic(t *testing.T) {
+	var first int
+	t.Run("pinned", func(t *testing.T) {
+		p := monkey.Deterministic(t)
+		assert(t, time.Now().Equal(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)), time.Now())
+		p.Advance(time.Hour)
+		assert(t, time.Since(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)) == time.Hour)
+		assert(t, os.Getpid() == 1000)
+		p.SetPID(7)
+		assert(t, os.Getpid() == 7)
+		name, _ := os.Hostname()
+		assert(t, name == "localhost", name)
+		first = rand.Int()
+		p.Seed(1)
+		assert(t, rand.Int() == first)
+	})
+	assert(t, time.Now().Year() > 2000)
+	assert(t, os.Getpid() != 7)
+}
+
+func TestUnpatchOnPanic(t *testing.T) {
+	g := monkey.Patch(no, func() bool { panic("replacement") }, monkey.UnpatchOnPanic())
+	defer g.Unpatch()
+
+	func() {
+		defer func() {
+			assert(t, recover() == "replacement")
+			assert(t, !no())
+		}()
+		no()
+	}()
+	assert(t, !no())
+	g.Restore()
+	panics(t, func() { no() })
+}
+
+//go:noinline
+func fib(n int) int {
+	if n < 2 {
+		return n
+	}
+	return fib(n-1) + fib(n-2)
+}
+
+func TestReentry(t *testing.T) {
+	calls := 0
+	g := monkey.Patch(fib, func(n int) int {
+		calls++
+		return fib(n) + 1
+	})
+	assert(t, fib(10) == 56)
+	assert(t, calls == 1, calls)
+	g.Unpatch()
+
+	calls = 0
+	g = monkey.Patch(fib, func(n int) int {
+		calls++
+		if n < 2 {
+			return n
+		}
+		return fib(n-1) + fib(n-2)
+	}, monkey.AllowReentry())
+	defer g.Unpatch()
+	assert(t, fib(10) == 55)
+	assert(t, calls == 177, calls)
+}
+
+//go:noinline
+func fibs(n [2]int) [2]int {
+	return [2]int{fib(n[0]), fib(n[1])}
+}
+
+// TestReentryStack guards a target taking and returning arrays, which go
+// on the stack.
+func TestReentryStack(t *testing.T) {
+	g := monkey.Patch(fibs, func(n [2]int) [2]int {
+		r := fibs(n)
+		return [2]int{r[0] + 1, r[1] + 1}
+	})
+	defer g.Unpatch()
+	assert(t, fibs([2]int{10, 5}) == [2]int{56, 6}, fibs([2]int{10, 5}))
+}
+
+//go:noinline
+func descend(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return fib(1) + descend(n-1)
+}
+
+// TestReentryDeep calls a guarded target from every depth of a recursion,
+// some of them short of stack until the stack grows.
+func TestReentryDeep(t *testing.T) {
+	g := monkey.Patch(fib, func(n int) int {
+		return fib(n) + 1
+	})
+	defer g.Unpatch()
+	for _, n := range []int{100, 1000, 10000} {
+		assert(t, descend(n) == 2*n, n, descend(n))
+	}
+}
+
+// TestReentryPanic makes sure a replacement panicking leaves it guarded
+// and called, from any depth.
+func TestReentryPanic(t *testing.T) {
+	g := monkey.Patch(fib, func(n int) int {
+		if n < 0 {
+			panic("negative")
+		}
+		return fib(n) + 1
+	})
+	defer g.Unpatch()
+	panics(t, func() { fib(-1) })
+	assert(t, fib(10) == 56)
+	panics(t, func() { fib(-1) })
+	assert(t, descend(10) == 20)
+	assert(t, fib(10) == 56)
+}
+
+func TestHooks(t *testing.T) {
+	var events []string
+	veto := errors.New("veto")
+	remove := monkey.AddHooks(monkey.Hooks{
+		BeforeApply: func(g *monkey.PatchGuard) error {
+			if g.Target().Pointer() == reflect.ValueOf(foo).Pointer() {
+				return veto
+			}
+			events = append(events, "apply")
+			return nil
+		},
+		AfterApply: func(g *monkey.PatchGuard, err error) {
+			events = append(events, fmt.Sprint("applied ", err))
+		},
+		BeforeCall: func(g *monkey.PatchGuard, args []reflect.Value) {
+			events = append(events, fmt.Sprint("call ", no()))
+		},
+		AfterCall: func(g *monkey.PatchGuard, args, results []reflect.Value) {
+			events = append(events, fmt.Sprint("returned ", results[0]))
+		},
+	})
+
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	assert(t, no())
+	_, err := monkey.TryPatch(foo, bar)
+	assert(t, err == veto, err)
+	remove()
+	g.Unpatch()
+	g.Restore()
+	assert(t, no())
+
+	want := []string{"apply", "applied <nil>", "call false", "returned true", "applied veto"}
+	assert(t, reflect.DeepEqual(events, want), events)
+}
+
+func TestSharedCallHooks(t *testing.T) {
+	inHook, release := make(chan bool), make(chan bool)
+	remove := monkey.AddHooks(monkey.Hooks{
+		BeforeCall: func(g *monkey.PatchGuard, args []reflect.Value) {
+			if args[0].Int() == 1 {
+				inHook <- foo(5, 3) == 8
+				<-release
+			}
+			if args[0].Int() == 2 {
+				panic("hook")
+			}
+		},
+	})
+	defer remove()
+	g := monkey.Patch(foo, bar, monkey.ForLabels(), monkey.AllowReentry())
+	defer g.Unpatch()
+
+	go foo(1, 0)
+	// Calls made by the hook run the original, those of other goroutines
+	// meanwhile still run the replacement.
+	assert(t, <-inHook)
+	assert(t, foo(5, 3) == 2)
+	release <- true
+
+	panics(t, func() { foo(2, 0) })
+	assert(t, foo(5, 3) == 2)
+}
+
+func TestStubBytes(t *testing.T) {
+	assert(t, monkey.StubBytes(strings.Repeat) == nil)
+
+	g := monkey.Patch(no, yes)
+	b := monkey.StubBytes(no)
+	assert(t, len(b) > 0)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g := monkey.Patch(no, yes)
+		defer g.Unpatch()
+		assert(t, bytes.Equal(monkey.StubBytes(no), b))
+	}()
+	<-done
+	g.Unpatch()
+	assert(t, bytes.Equal(monkey.StubBytes(no), b))
+}
+
+func TestStubs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stubs")
+	assert(t, err == nil, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "stubs.map")
+	assert(t, monkey.SetStubMapFile(path) == nil)
+	defer monkey.SetStubMapFile("")
+
+	g := monkey.Patch(store, func(key, value string) error { return nil })
+	defer g.Unpatch()
+	pc := uintptr(0)
+	for _, s := range monkey.Stubs() {
+		if strings.HasSuffix(s.Func, ".store") {
+			pc = s.Addr + uintptr(s.Size)/2
+		}
+	}
+	assert(t, pc != 0, monkey.Stubs())
+	name, ok := monkey.StubFunc(pc)
+	assert(t, ok && name == "stub for github.com/go-kiss/monkey_test.store", name)
+	_, ok = monkey.StubFunc(reflect.ValueOf(store).Pointer())
+	assert(t, !ok)
+
+	var buf bytes.Buffer
+	assert(t, monkey.WriteStubs(&buf) == nil)
+	b, err := ioutil.ReadFile(path)
+	assert(t, err == nil, err)
+	assert(t, bytes.Equal(b, buf.Bytes()), string(b))
+	assert(t, strings.Contains(string(b), " monkey dispatcher\n"), string(b))
+}
+
+func TestSelfCheck(t *testing.T) {
+	assert(t, monkey.SelfCheck() == nil)
+	assert(t, monkey.SelfCheck() == nil)
+}
+
+type account struct{ name string }
+
+//go:noinline
+func (a *account) Balance(currency string) (string, error) {
+	if currency == "" {
+		return "", errors.New("no currency")
+	}
+	return a.name + ": 100 " + currency, nil
+}
+
+type point struct{ x, y int }
+
+//go:noinline
+func (p point) String() string { return strconv.Itoa(p.x) + "," + strconv.Itoa(p.y) }
+
+func TestPatchReceiver(t *testing.T) {
+	alice, bob := &account{"alice"}, &account{"bob"}
+	g := monkey.PatchReceiver(alice, "Balance", func(a *account, currency string) (string, error) {
+		return "", errors.New("frozen")
+	})
+	_, err := alice.Balance("EUR")
+	assert(t, err != nil && err.Error() == "frozen", err)
+	b, err := bob.Balance("EUR")
+	assert(t, err == nil && b == "bob: 100 EUR", b, err)
+	b, err = (&account{"alice"}).Balance("EUR")
+	assert(t, err == nil, err)
+	g.Unpatch()
+	_, err = alice.Balance("EUR")
+	assert(t, err == nil, err)
+
+	g = monkey.PatchReceiver(point{1, 2}, "String", func(point) string { return "origin" })
+	defer g.Unpatch()
+	assert(t, point{1, 2}.String() == "origin")
+	assert(t, point{2, 1}.String() == "2,1")
+
+	_, err = monkey.TryPatchReceiver(alice, "Balance", func(*account) string { return "" })
+	assert(t, err != nil)
+	_, err = monkey.TryPatchReceiver(alice, "Missing", func() {})
+	assert(t, err != nil)
+}
+
+func TestPatchImplementation(t *testing.T) {
+	var s fmt.Stringer = point{1, 2}
+	g := monkey.PatchImplementation((*fmt.Stringer)(nil), s, "String", func(point) string { return "origin" })
+	assert(t, s.String() == "origin")
+	assert(t, point{2, 1}.String() == "origin")
+	g.Unpatch()
+	assert(t, s.String() == "1,2")
+
+	_, err := monkey.TryPatchImplementation((*fmt.Stringer)(nil), s, "Missing", func(point) {})
+	assert(t, err != nil)
+	_, err = monkey.TryPatchImplementation((*error)(nil), s, "Error", func(point) string { return "" })
+	assert(t, err != nil)
+	_, err = monkey.TryPatchImplementation(s, s, "String", func(point) string { return "" })
+	assert(t, err != nil)
+}
+
+func TestPatchReceiverMatching(t *testing.T) {
+	g := monkey.PatchReceiverMatching(reflect.TypeOf(&account{}), "Balance", func(a *account) bool {
+		return strings.HasPrefix(a.name, "test-")
+	}, func(a *account, currency string) (string, error) {
+		return "0 " + currency, nil
+	})
+	defer g.Unpatch()
+	b, _ := (&account{"test-1"}).Balance("EUR")
+	assert(t, b == "0 EUR", b)
+	b, _ = (&account{"alice"}).Balance("EUR")
+	assert(t, b == "alice: 100 EUR", b)
+
+	_, err := monkey.TryPatchReceiverMatching(reflect.TypeOf(&account{}), "Balance", func(account) bool { return true }, func(a *account, currency string) (string, error) {
+		return "", nil
+	})
+	assert(t, err != nil)
+}
+
+func TestPatchResults(t *testing.T) {
+	g := monkey.PatchResults(fetch, func(v string, err error) (string, error) {
+		return strings.ToUpper(v), err
+	})
+	v, err := fetch("a")
+	assert(t, v == "A=VALUE" && err == nil, v, err)
+	g.Unpatch()
+
+	_, err = monkey.TryPatchResults(fetch, func(string) string { return "" })
+	assert(t, err != nil)
+}
+
+// failures records the errors reported to it instead of failing the test.
+type failures struct {
+	testing.TB
+	errs []string
+}
+
+func (f *failures) Errorf(format string, args ...interface{}) {
+	f.errs = append(f.errs, fmt.Sprintf(format, args...))
+}
+
+func TestScript(t *testing.T) {
+	f := &failures{TB: t}
+	g := monkey.Script(f, fetch).
+		PushReturn("a", nil).
+		PushFunc(func(key string) (string, error) { return "", errors.New(key) }).
+		PushReturn("", io.EOF)
+	v, err := fetch("x")
+	assert(t, v == "a" && err == nil, v, err)
+	_, err = fetch("x")
+	assert(t, err != nil && err.Error() == "x", err)
+	_, err = fetch("x")
+	assert(t, err == io.EOF, err)
+	assert(t, len(f.errs) == 0, f.errs)
+	v, err = fetch("x")
+	assert(t, v == "" && err == nil, v, err)
+	assert(t, len(f.errs) == 1 && strings.Contains(f.errs[0], "monkey_test.fetch"), f.errs)
+	g.Unpatch()
+
+	g = monkey.Patch(fetch, fetch)
+	panics(t, func() { g.PushReturn("a", nil) })
+	g.Unpatch()
+	g = monkey.Script(f, fetch)
+	panics(t, func() { g.PushReturn("a") })
+	panics(t, func() { g.PushFunc(store) })
+	g.Unpatch()
+}
+
+func TestOnExhausted(t *testing.T) {
+	f := &failures{TB: t}
+	for _, c := range []struct {
+		e    monkey.Exhaustion
+		want string
+	}{
+		{monkey.ExhaustOriginal, "x=value"},
+		{monkey.ExhaustRepeat, "a"},
+		{monkey.ExhaustZero, ""},
+	} {
+		g := monkey.Script(f, fetch, monkey.OnExhausted(c.e)).PushReturn("a", nil)
+		fetch("x")
+		v, _ := fetch("x")
+		assert(t, v == c.want, c.e, v)
+		g.Unpatch()
+	}
+	assert(t, len(f.errs) == 0, f.errs)
+
+	g := monkey.Script(f, fetch, monkey.OnExhausted(monkey.ExhaustRepeat))
+	fetch("x")
+	g.Unpatch()
+	assert(t, len(f.errs) == 1, f.errs)
+}
+
+func TestUnpatchOn(t *testing.T) {
+	stop := make(chan struct{})
+	monkey.Patch(no, yes).UnpatchOn(stop)
+	monkey.Patch(fetch, func(string) (string, error) { return "", io.EOF }).UnpatchOn(stop)
+	assert(t, no())
+	close(stop)
+	for i := 0; i < 100 && monkey.Goroutines(no)+monkey.Goroutines(fetch) > 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	assert(t, !no())
+	_, err := fetch("a")
+	assert(t, err == nil, err)
+}
+
+func TestQuiesce(t *testing.T) {
+	done := make(chan *monkey.PatchGuard)
+	go func() {
+		done <- monkey.Patch(no, yes)
+	}()
+	g := <-done
+	monkey.Quiesce()
+	assert(t, !no())
+	g.Unpatch()
+	monkey.Quiesce()
+}
+
+//go:noinline
+func visible(x int) int { return x }
+
+func TestVisibility(t *testing.T) {
+	for _, procs := range []int{1, 4 * runtime.NumCPU()} {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+		// Other goroutines keep calling the target, and never see the
+		// patches of this one, nor a mix of old and new code.
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+		var bad int32
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					if visible(1) != 1 {
+						atomic.StoreInt32(&bad, 1)
+					}
+					runtime.Gosched()
+				}
+			}()
+		}
+
+		for i := 0; i < 200; i++ {
+			g := monkey.Patch(visible, func(x int) int { return -x })
+			// Moves the goroutine to another thread now and then.
+			runtime.Gosched()
+			assert(t, visible(1) == -1, procs, i)
+			runtime.Gosched()
+			assert(t, visible(1) == -1, procs, i)
+			// Counted without a lock prefix, none is lost.
+			assert(t, g.Hits() == 2, g.Hits())
+			g.Unpatch()
+			assert(t, visible(1) == 1, procs, i)
+		}
+		close(stop)
+		wg.Wait()
+		assert(t, atomic.LoadInt32(&bad) == 0, procs)
+
+		// A shared patch applies to the calls of a goroutine already
+		// running, once synchronized after it.
+		for i := 0; i < 50; i++ {
+			ready := make(chan struct{})
+			res := make(chan int)
+			go func() {
+				<-ready
+				res <- visible(1)
+			}()
+			g := monkey.Patch(visible, func(x int) int { return -x }, monkey.ForLabels())
+			close(ready)
+			assert(t, <-res == -1, procs, i)
+			g.Unpatch()
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("the test binary is traced already")
+	}
+	g := monkey.Patch(lookup, func(ctx context.Context, id string) (string, error) {
+		return "fake " + id, nil
+	}, monkey.Trace())
+	defer g.Unpatch()
+
+	var b bytes.Buffer
+	assert(t, trace.Start(&b) == nil)
+	ctx, task := trace.NewTask(context.Background(), "lookups")
+	v, _ := lookup(ctx, "1")
+	task.End()
+	trace.Stop()
+	assert(t, v == "fake 1")
+	assert(t, bytes.Contains(b.Bytes(), []byte("monkey: github.com/go-kiss/monkey_test.lookup")))
+}
+
+func TestLayers(t *testing.T) {
+	if trace.IsEnabled() {
+		t.Skip("the test binary is traced already")
+	}
+	tr := monkey.TraceCalls(lookup)
+	defer tr.Unpatch()
+	g := monkey.Patch(lookup, func(ctx context.Context, id string) (string, error) {
+		return "fake " + id, nil
+	})
+	defer g.Unpatch()
+
+	var b bytes.Buffer
+	assert(t, trace.Start(&b) == nil)
+	v, _ := lookup(context.Background(), "1")
+	trace.Stop()
+	assert(t, v == "fake 1", v)
+	assert(t, bytes.Contains(b.Bytes(), []byte("monkey: github.com/go-kiss/monkey_test.lookup")))
+	assert(t, tr.Hits() == 1 && g.Hits() == 1, tr.Hits(), g.Hits())
+
+	boom := errors.New("boom")
+	f := monkey.InjectFault(lookup, monkey.Fault{Err: boom})
+	_, err := lookup(context.Background(), "2")
+	assert(t, err == boom, err)
+	f.Disable()
+	v, _ = lookup(context.Background(), "2")
+	assert(t, v == "fake 2", v)
+	f.Unpatch()
+
+	g.Unpatch()
+	v, _ = lookup(context.Background(), "3")
+	assert(t, v == "user 3", v)
+	assert(t, tr.Hits() == 4, tr.Hits())
+
+	g.Restore()
+	v, _ = lookup(context.Background(), "4")
+	assert(t, v == "fake 4", v)
+	tr.Unpatch()
+	v, _ = lookup(context.Background(), "5")
+	assert(t, v == "fake 5", v)
+	assert(t, tr.Hits() == 5, tr.Hits())
+
+	panics(t, func() { monkey.InjectFault(no, monkey.Fault{Err: boom}) })
+}
+
+func TestUse(t *testing.T) {
+	var order []string
+	mark := func(name string) monkey.Middleware {
+		return func(in []reflect.Value, next func([]reflect.Value) []reflect.Value) []reflect.Value {
+			order = append(order, name)
+			return next(in)
+		}
+	}
+	a := monkey.Use(fetch, mark("a"))
+	defer a.Unpatch()
+	rec := monkey.Use(fetch, nil, monkey.Record())
+	defer rec.Unpatch()
+	g := monkey.Patch(fetch, func(key string) (string, error) {
+		order = append(order, "patch")
+		return key + "=fake", nil
+	})
+	defer g.Unpatch()
+	b := monkey.Use(fetch, mark("b"))
+	defer b.Unpatch()
+
+	v, _ := fetch("k")
+	assert(t, v == "k=fake", v)
+	assert(t, strings.Join(order, " ") == "a b patch", order)
+	calls := rec.Calls()
+	assert(t, len(calls) == 1 && calls[0].Results[0].String() == "k=fake", calls)
+
+	order = nil
+	a.Unpatch()
+	b.Disable()
+	fetch("k")
+	assert(t, strings.Join(order, " ") == "patch", order)
+	assert(t, a.Hits() == 1 && b.Hits() == 1 && len(rec.Calls()) == 2)
+
+	order = nil
+	b.Enable()
+	g.Unpatch()
+	v, _ = fetch("k")
+	assert(t, v == "k=value", v)
+	assert(t, strings.Join(order, " ") == "b", order)
+
+	start := time.Now()
+	d := monkey.Use(fetch, monkey.Delay(10*time.Millisecond))
+	fetch("k")
+	d.Unpatch()
+	assert(t, time.Since(start) >= 10*time.Millisecond)
+
+	panics(t, func() { monkey.Use(fetch, nil, monkey.ForLabels("test", "shared")) })
+}
+
+func TestCheckPassThrough(t *testing.T) {
+	alice, bob := &account{"alice"}, &account{"bob"}
+	if os.Getenv("MONKEY_TEST_PASS_THROUGH") != "" {
+		monkey.CheckPassThrough(t, func(t *testing.T) {
+			g := monkey.PatchReceiver(alice, "Balance", func(a *account, currency string) (string, error) {
+				return "alice: 0 " + currency, nil
+			})
+			defer g.Unpatch()
+			b, _ := bob.Balance("EUR")
+			assert(t, b == "bob: 100 EUR", b)
+		})
+		return
+	}
+
+	runs := 0
+	monkey.CheckPassThrough(t, func(t *testing.T) {
+		runs++
+		g := monkey.PatchReceiver(alice, "Balance", func(a *account, currency string) (string, error) {
+			return "alice: 0 " + currency, nil
+		})
+		defer g.Unpatch()
+		b, _ := alice.Balance("EUR")
+		assert(t, b == "alice: 0 EUR", b)
+	})
+	assert(t, runs == 2)
+	b, _ := bob.Balance("EUR")
+	assert(t, b == "bob: 100 EUR", b)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCheckPassThrough$", "-test.v")
+	cmd.Env = append(os.Environ(), "MONKEY_TEST_PASS_THROUGH=1")
+	out, err := cmd.CombinedOutput()
+	assert(t, err != nil, string(out))
+	assert(t, strings.Contains(string(out), "--- PASS: TestCheckPassThrough/with_pass-through"), string(out))
+	assert(t, strings.Contains(string(out), "only passes when calls go on to the originals of github.com/go-kiss/monkey_test.(*account).Balance"), string(out))
+}
+
+func TestQuarantine(t *testing.T) {
+	f := &failures{TB: t}
+	t.Run("quarantined", func(t *testing.T) {
+		f.TB = t
+		monkey.Quarantine(f, store)
+
+		err := store("k", "v")
+		assert(t, errors.Is(err, monkey.ErrQuarantined), err)
+		assert(t, len(f.errs) == 1 && strings.Contains(f.errs[0], "monkey_test.TestQuarantine"), f.errs)
+
+		done := make(chan error)
+		go func() { done <- store("k", "v") }()
+		assert(t, errors.Is(<-done, monkey.ErrQuarantined))
+
+		g := monkey.Patch(store, func(key, value string) error { return nil })
+		assert(t, store("k", "v") == nil)
+		g.Unpatch()
+		g = monkey.Intercept(store, nil, nil)
+		assert(t, errors.Is(store("k", "v"), monkey.ErrQuarantined))
+		g.Unpatch()
+		assert(t, len(f.errs) == 3, f.errs)
+	})
+	assert(t, store("k", "v") == nil)
+}
+
+func TestPassThroughs(t *testing.T) {
+	alice := &account{"alice"}
+	g := monkey.PatchReceiver(alice, "Balance", func(a *account, currency string) (string, error) {
+		return "", nil
+	})
+	defer g.Unpatch()
+	alice.Balance("EUR")
+	(&account{"bob"}).Balance("EUR")
+	(&account{"carol"}).Balance("EUR")
+	assert(t, g.Hits() == 1, g.Hits())
+	assert(t, g.PassThroughs() == 2, g.PassThroughs())
+
+	g.Disable()
+	alice.Balance("EUR")
+	g.Enable()
+	done := make(chan struct{})
+	go func() {
+		alice.Balance("EUR")
+		close(done)
+	}()
+	<-done
+	assert(t, g.Hits() == 1, g.Hits())
+	assert(t, g.PassThroughs() == 3, g.PassThroughs())
+}
+
+func TestAssertCalled(t *testing.T) {
+	f := &failures{TB: t}
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	assert(t, strings.HasSuffix(g.CreatedAt(), "monkey_test.go:"+strconv.Itoa(line()-2)), g.CreatedAt())
+	g.AssertCalled(f)
+	g.AssertNotCalled(f)
+	no()
+	g.AssertCalled(f)
+	g.AssertNotCalled(f)
+	assert(t, len(f.errs) == 2, f.errs)
+	assert(t, strings.Contains(f.errs[0], "monkey_test.no patched at "+g.CreatedAt()+" was not called"), f.errs[0])
+	assert(t, strings.HasSuffix(f.errs[1], "was called 1 times"), f.errs[1])
+}
+
+func TestAssertNoPatches(t *testing.T) {
+	monkey.UnpatchAll()
+	f := &failures{TB: t}
+	g := monkey.Patch(no, yes)
+	monkey.AssertNoPatches(f)
+	g.Unpatch()
+	monkey.AssertNoPatches(f)
+	assert(t, len(f.errs) == 1, f.errs)
+	assert(t, strings.Contains(f.errs[0], "monkey_test.no"), f.errs[0])
+	assert(t, strings.Contains(f.errs[0], "patched by:\ngithub.com/go-kiss/monkey_test.TestAssertNoPatches\n"), f.errs[0])
+}
+
+func TestNamed(t *testing.T) {
+	g := monkey.Patch(no, yes, monkey.Named("no-yes"))
+	assert(t, monkey.Find("no-yes") == g && g.Name() == "no-yes")
+	assert(t, monkey.Find("other") == nil)
+	_, err := monkey.TryPatch(foo, bar, monkey.Named("no-yes"))
+	assert(t, errors.Is(err, monkey.ErrNameTaken), err)
+	assert(t, foo(1, 2) == 3)
+
+	g.Unpatch()
+	assert(t, monkey.Find("no-yes") == nil)
+	g2 := monkey.Patch(foo, bar, monkey.Named("no-yes"))
+	defer g2.Unpatch()
+	assert(t, monkey.Find("no-yes") == g2)
+	monkey.Unpatch(foo)
+	assert(t, monkey.Find("no-yes") == nil)
+}
+
+func TestAuditMode(t *testing.T) {
+	monkey.AuditMode(true)
+	defer monkey.AuditMode(false)
+	for i := 0; i < 2; i++ {
+		g := monkey.Patch(reported, func() int { return 1 })
+		assert(t, g.Err() == nil && reported() == 0)
+		g.Unpatch()
+	}
+	g := monkey.Patch(reported, func() int { return 1 }, monkey.ForLabels())
+	defer g.Unpatch()
+	assert(t, reported() == 0)
+
+	var found []monkey.AuditEntry
+	for _, e := range monkey.AuditReport() {
+		if e.Func == "github.com/go-kiss/monkey_test.reported" {
+			found = append(found, e)
+		}
+	}
+	assert(t, len(found) == 2, found)
+	assert(t, found[0].Count == 2 && found[0].Scope == "goroutine" && strings.Contains(found[0].Site, "monkey_test.go:"), found[0])
+	assert(t, found[1].Count == 1 && found[1].Scope == "global", found[1])
+
+	var b bytes.Buffer
+	assert(t, monkey.WriteAuditReport(&b) == nil)
+	assert(t, strings.Contains(b.String(), `"func": "github.com/go-kiss/monkey_test.reported"`), b.String())
+}
+
+//go:noinline
+func openBody(url string) (io.ReadCloser, error) {
+	return nil, errors.New("no network")
+}
+
+//go:noinline
+func createLog(name string) (io.WriteCloser, error) {
+	return os.Create(name)
+}
+
+func TestPatchStream(t *testing.T) {
+	g := monkey.PatchStream(openBody, "hello world")
+	defer g.Unpatch()
+	for i := 0; i < 2; i++ {
+		r, err := openBody("http://example.com")
+		assert(t, err == nil)
+		b := make([]byte, 5)
+		_, err = io.ReadFull(r, b)
+		assert(t, err == nil && string(b) == "hello")
+		if i == 0 {
+			r.Close()
+		}
+	}
+	s := g.Streams()
+	assert(t, len(s) == 2 && len(g.Calls()) == 2, s)
+	assert(t, string(s[0].Consumed()) == "hello" && s[0].Closed())
+	assert(t, !s[1].Closed())
+	_, err := s[0].Read(make([]byte, 1))
+	assert(t, err == io.ErrClosedPipe, err)
+
+	w := monkey.PatchStream(createLog, "")
+	defer w.Unpatch()
+	f, _ := createLog("/nonexistent/log")
+	fmt.Fprintf(f, "line %d\n", 1)
+	assert(t, string(w.Streams()[0].Written()) == "line 1\n")
+
+	defer monkey.SetOptions(monkey.CurrentOptions())
+	var l lines
+	monkey.SetOptions(monkey.Options{Logger: &l})
+	g2 := monkey.PatchStream(foo, "")
+	assert(t, g2.Err() != nil && strings.Contains(g2.Err().Error(), "returns no stream"), g2.Err())
+}
+
+func TestBind(t *testing.T) {
+	type row struct {
+		value string
+		err   error
+	}
+	fake := func(r row, key string) (string, error) { return key + "=" + r.value, r.err }
+	for _, r := range []row{{"a", nil}, {"", io.EOF}} {
+		g := monkey.Patch(fetch, monkey.Bind(fake, r))
+		v, err := fetch("k")
+		assert(t, v == "k="+r.value && err == r.err, v, err)
+		g.Unpatch()
+	}
+
+	var seen []int
+	g := monkey.Patch(foo, monkey.Bind(func(seen *[]int, a, b int) int {
+		*seen = append(*seen, a)
+		return a * b
+	}, &seen))
+	assert(t, foo(2, 3) == 6 && len(seen) == 1)
+	g.Unpatch()
+
+	panics(t, func() { monkey.Bind(fake, 1) })
+	panics(t, func() { monkey.Bind(no, nil) })
+}
+
+func line() int {
+	_, _, l, _ := runtime.Caller(1)
+	return l
+}
+
+func TestPatchByName(t *testing.T) {
+	g := monkey.PatchByName("github.com/go-kiss/monkey_test.fetch", func(key string) (string, error) {
+		return "", io.EOF
+	}, monkey.Prototype(fetch))
+	_, err := fetch("a")
+	assert(t, err == io.EOF, err)
+	g.Unpatch()
+	_, err = fetch("a")
+	assert(t, err == nil, err)
+
+	_, err = monkey.TryPatchByName("github.com/go-kiss/monkey_test.nothing", func() {}, monkey.Prototype(func() {}))
+	assert(t, errors.Is(err, monkey.ErrSymbolNotFound), err)
+	_, err = monkey.TryPatchByName("os.hostname", func() string { return "" })
+	assert(t, err != nil)
+	_, err = monkey.TryPatchByName("github.com/go-kiss/monkey_test.fetch", func() {}, monkey.Prototype(fetch))
+	assert(t, err != nil)
+
+	// go test leaves DWARF out, unless given -ldflags=-w=0.
+	_, err = monkey.TryPatchByName("github.com/go-kiss/monkey_test.fetch", func(string) (string, error) {
+		return "", nil
+	})
+	if errors.Is(err, monkey.ErrUnknownSignature) {
+		t.Log(err)
+		return
+	}
+	assert(t, err == nil, err)
+	monkey.UnpatchAll()
+	_, err = monkey.TryPatchByName("github.com/go-kiss/monkey_test.fetch", func(string) string { return "" })
+	assert(t, err != nil && strings.Contains(err.Error(), "func(key string) (string, error)"), err)
+}
+
+func TestListPatches(t *testing.T) {
+	g := monkey.Patch(fetch, func(string) (string, error) { return "", nil })
+	defer g.Unpatch()
+	var found *monkey.PatchInfo
+	l := monkey.ListPatches()
+	for i := range l {
+		if l[i].Func == "github.com/go-kiss/monkey_test.fetch" {
+			found = &l[i]
+		}
+	}
+	assert(t, found != nil, l)
+	assert(t, found.Goroutines == 1, found)
+	assert(t, len(found.CreationStacks) == 1 && found.CreationStacks[0] == g.CreationStack(), found.CreationStacks)
+	// Parameter names and positions only come with DWARF, which go test
+	// leaves out unless given -ldflags=-w=0.
+	if found.Position == "" {
+		assert(t, found.Signature == "func(string) (string, error)", found.Signature)
+		return
+	}
+	assert(t, found.Signature == "func(key string) (string, error)", found.Signature)
+	assert(t, strings.HasSuffix(found.Position, "monkey_test.go:"+strconv.Itoa(fetchLine)), found.Position)
+	assert(t, strings.Contains(found.String(), "declared at"), found)
+
+	_, err := monkey.TryPatch(fetch, func(string) string { return "" })
+	assert(t, err != nil && strings.Contains(err.Error(), "func(key string) (string, error) declared at"), err)
+}
+
+func TestUnpatchAllFrom(t *testing.T) {
+	g := monkey.Patch(no, yes)
+	defer g.Unpatch()
+	assert(t, g.Package() == "github.com/go-kiss/monkey_test", g.Package())
+	assert(t, g.Module() == "github.com/go-kiss/monkey", g.Module())
+	monkey.UnpatchAllFrom("example.com/other")
+	assert(t, no())
+	monkey.UnpatchAllFrom("github.com/go-kiss/monkey")
+	assert(t, !no())
+}
+
+//go:noinline
+func patchSelf(n int) int {
+	if n > 0 {
+		g := monkey.Patch(patchSelf, func(int) int { return -1 })
+		defer g.Unpatch()
+		return patchSelf(0)
+	}
+	return n + 1
+}
+
+func TestActiveFrame(t *testing.T) {
+	defer monkey.SetOptions(monkey.CurrentOptions())
+	var l lines
+	monkey.SetOptions(monkey.Options{PanicOnError: true, Logger: &l})
+	assert(t, patchSelf(1) == -1)
+	assert(t, len(l) > 0 && strings.Contains(l[0], "patchSelf while it runs"), l)
+}
+
+func TestNotFunction(t *testing.T) {
+	panics(t, func() {
+		monkey.Patch(no, 1)
+	})
+	panics(t, func() {
+		monkey.Patch(1, yes)
+	})
+}
+
+func TestNotCompatible(t *testing.T) {
+	panics(t, func() {
+		monkey.Patch(no, func() {})
+	})
+}
+
+func TestTryPatch(t *testing.T) {
+	g, err := monkey.TryPatch(no, func() {})
+	assert(t, g == nil && err != nil)
+	g, err = monkey.TryPatch(no, yes)
+	assert(t, err == nil, err)
+	assert(t, no())
+	g.Unpatch()
+	assert(t, !no())
+}
+
+func TestProtectError(t *testing.T) {
+	var err error = &monkey.ProtectError{Addr: 0x1000, Length: 13, Err: syscall.EPERM}
+	assert(t, errors.Is(err, syscall.EPERM))
+	assert(t, strings.Contains(err.Error(), "13 bytes at 0x1000"), err)
+}
+
+func TestPolicy(t *testing.T) {
+	monkey.SetPolicy(monkey.TestOnly)
+	defer monkey.SetPolicy(monkey.AllowAll)
+	_, err := monkey.TryPatch(no, yes)
+	assert(t, err == nil, err)
+	assert(t, monkey.Unpatch(no))
+}
+
+func TestResolveSymbol(t *testing.T) {
+	name := "github.com/go-kiss/monkey_test.no"
+	pc, err := monkey.ResolveSymbol(name)
+	assert(t, err == nil, err)
+	assert(t, pc == reflect.ValueOf(no).Pointer())
+	assert(t, monkey.SymbolName(pc) == name)
+
+	_, err = monkey.ResolveSymbol("github.com/go-kiss/monkey_test.missing")
+	assert(t, errors.Is(err, monkey.ErrSymbolNotFound), err)
+}
+
+func assert(t *testing.T, b bool, args ...interface{}) {
+	t.Helper()
+	if !b {
+		t.Fatal(append([]interface{}{"assertion failed"}, args...))
+	}
+}
+
+func panics(t *testing.T, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		if v := recover(); v == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	f()
+}
+
+//go:noinline
+func foo(a, b int) int {
+	return a + b
+}
+
+//go:noinline
+func bar(a, b int) int {
+	return a - b
+}
+
+func TestEmpty(t *testing.T) {
+	monkey.PatchEmpty(foo)
+
+	stop := make(chan int)
+	var wg sync.WaitGroup
+	for i := 1; i < runtime.NumCPU()-1; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			j := 0
+			for {
+				f := func(j int) func(a, b int) int {
+					return func(a, b int) int {
+						return a + b + j
+					}
+				}(j)
+				monkey.Patch(foo, f)
+				assert(t, 1+1+j == foo(1, 1))
+				monkey.Unpatch(foo)
+				select {
+				case <-stop:
+					return
+				default:
+					j++
+				}
+			}
+		}(i)
+	}
+
+	for i := 0; i < 2000000; i++ {
+		assert(t, i+1 == foo(i, 1))
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestG(t *testing.T) {
+	monkey.Patch(foo, bar)
+
+	assert(t, -1 == foo(1, 2))
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assert(t, 3 == foo(1, 2))
+	}()
+	go func() {
+		monkey.Patch(foo, func(a, b int) int {
+			return a * b
+		})
+		defer wg.Done()
+		assert(t, 2 == foo(1, 2))
+	}()
+	wg.Wait()
+}
+
+//go:noinline
+func firstPatched(n int) int { return n + 1 }
+
+func TestConcurrentInstall(t *testing.T) {
+	// Goroutines patching a target that isn't installed yet wait for the
+	// first one, and see the jump written once Patch returns.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g := monkey.Patch(firstPatched, func(int) int { return -i })
+			defer g.Unpatch()
+			assert(t, firstPatched(1) == -i)
+		}(i)
+	}
+	wg.Wait()
+	assert(t, firstPatched(1) == 2)
+}
+
+func TestIntrospectionWhilePatching(t *testing.T) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			monkey.Patch(firstPatched, func(n int) int { return n }).Unpatch()
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			assert(t, monkey.Goroutines(firstPatched) == 0)
+			return
+		default:
+			monkey.Report()
+			monkey.Goroutines(firstPatched)
+		}
+	}
+}
+
+//go:noinline
+func reported() int { return 0 }
+
+func TestReport(t *testing.T) {
+	monkey.Patch(reported, func() int { return 1 })
+	for i := 0; i < 3; i++ {
+		assert(t, reported() == 1)
+	}
+	monkey.Unpatch(reported)
+	assert(t, reported() == 0)
+
+	var found bool
+	for _, e := range monkey.Report() {
+		if e.Func == "github.com/go-kiss/monkey_test.reported" {
+			found = true
+			assert(t, e.Patches == 1, e.Patches)
+			assert(t, e.Calls == 3, e.Calls)
+		}
+	}
+	assert(t, found)
+
+	var b bytes.Buffer
+	assert(t, monkey.WriteReport(&b) == nil)
+	assert(t, strings.Contains(b.String(), `"func": "github.com/go-kiss/monkey_test.reported"`), b.String())
+}
+
+func TestForLabels(t *testing.T) {
+	calls := 0
+	g := monkey.Patch(no, func() bool {
+		calls++
+		return !no()
+	}, monkey.ForLabels("handler", "checkout"))
+	defer g.Unpatch()
+	assert(t, !no())
+
+	run := func(labels pprof.LabelSet) bool {
+		c := make(chan bool)
+		go pprof.Do(context.Background(), labels, func(context.Context) {
+			// Started goroutines inherit the labels.
+			go func() { c <- no() }()
+		})
+		return <-c
+	}
+	assert(t, run(pprof.Labels("handler", "checkout", "user", "alice")))
+	assert(t, !run(pprof.Labels("handler", "cart")))
+	assert(t, calls == 1, calls)
+	assert(t, g.Hits() == 1, g.Hits())
+	// The call of the test, of the cart handler and of the replacement.
+	assert(t, g.PassThroughs() == 3, g.PassThroughs())
+
+	own := monkey.Patch(no, func() bool { return false })
+	pprof.Do(context.Background(), pprof.Labels("handler", "checkout"), func(context.Context) {
+		assert(t, !no())
+	})
+	own.Unpatch()
+
+	// Goroutines with the labels get the patch for them, the others the
+	// one for every goroutine.
+	global := monkey.Patch(no, yes, monkey.ForLabels())
+	assert(t, g.Scope() == monkey.ScopeLabels && global.Scope() == monkey.ScopeGlobal)
+	assert(t, run(pprof.Labels("handler", "checkout")) && calls == 2, calls)
+	assert(t, run(pprof.Labels("handler", "cart")) && calls == 2, calls)
+	g.Unpatch()
+	global.Unpatch()
+	assert(t, !run(pprof.Labels("handler", "checkout")))
+}
+
+func TestScope(t *testing.T) {
+	ctx := monkey.Activate(context.Background(), "slow-db")
+	reply := func(s string) func(context.Context, string) (string, error) {
+		return func(context.Context, string) (string, error) { return s, nil }
+	}
+	global := monkey.Patch(lookup, reply("global"), monkey.ForLabels())
+	defer global.Unpatch()
+	onCtx := monkey.Patch(lookup, reply("context"), monkey.OnContext("slow-db"))
+	defer onCtx.Unpatch()
+	labels := monkey.Patch(lookup, reply("labels"), monkey.ForLabels("handler", "checkout"))
+	defer labels.Unpatch()
+	assert(t, onCtx.Scope() == monkey.ScopeContext && labels.Scope().String() == "labels")
+
+	// Patches for goroutines carrying labels take precedence over those
+	// for contexts, which take precedence over those for every goroutine.
+	in := func(labelSet pprof.LabelSet, f func()) {
+		done := make(chan struct{})
+		go pprof.Do(context.Background(), labelSet, func(context.Context) {
+			f()
+			close(done)
+		})
+		<-done
+	}
+	in(pprof.Labels("handler", "checkout"), func() {
+		v, _ := lookup(ctx, "1")
+		assert(t, v == "labels" && monkey.Match(lookup, ctx) == labels, v)
+	})
+	in(pprof.Labels("handler", "cart"), func() {
+		v, _ := lookup(ctx, "1")
+		assert(t, v == "context" && monkey.Match(lookup, ctx, "1") == onCtx, v)
+		v, _ = lookup(context.Background(), "1")
+		assert(t, v == "global" && monkey.Match(lookup) == global, v)
+	})
+
+	// And patches for the goroutine itself over all of them.
+	own := monkey.Patch(lookup, reply("goroutine"))
+	v, _ := lookup(ctx, "1")
+	assert(t, v == "goroutine" && monkey.Match(lookup, ctx) == own && own.Scope() == monkey.ScopeGoroutine, v)
+	own.Unpatch()
+
+	global.Unpatch()
+	onCtx.Disable()
+	in(pprof.Labels("handler", "cart"), func() {
+		v, _ := lookup(ctx, "1")
+		assert(t, v == "user 1" && monkey.Match(lookup, ctx) == nil, v)
+	})
+	assert(t, labels.Hits() == 1 && onCtx.Hits() == 1 && global.Hits() == 1)
+}
+
+//go:noinline
+func lookup(ctx context.Context, id string) (string, error) {
+	return "user " + id, nil
+}
+
+func TestOnContext(t *testing.T) {
+	g := monkey.Patch(lookup, func(ctx context.Context, id string) (string, error) {
+		return "", errors.New("timeout")
+	}, monkey.OnContext("slow-db"))
+	defer g.Unpatch()
+
+	ctx := context.Background()
+	_, err := lookup(ctx, "1")
+	assert(t, err == nil, err)
+	active := monkey.Activate(ctx, "slow-db")
+	assert(t, monkey.Active(active, "slow-db") && !monkey.Active(ctx, "slow-db"))
+	c := make(chan error)
+	go func() {
+		_, err := lookup(active, "1")
+		c <- err
+	}()
+	err = <-c
+	assert(t, err != nil && err.Error() == "timeout", err)
+	_, err = lookup(monkey.Activate(ctx, "other"), "1")
+	assert(t, err == nil, err)
+
+	_, err = monkey.TryPatch(no, yes, monkey.OnContext("slow-db"))
+	assert(t, err != nil)
+}
+
+func TestFixture(t *testing.T) {
+	s := monkey.NewSession()
+	spec, err := monkey.ReturnSpec("ghost")
+	assert(t, err == nil, err)
+	spec.Error = "not found"
+	_, err = s.Declare(lookup, spec)
+	assert(t, err == nil, err)
+	_, err = s.Declare(no, monkey.PatchSpec{Returns: []json.RawMessage{[]byte("true")}})
+	assert(t, err == nil, err)
+	u, err := lookup(context.Background(), "1")
+	assert(t, u == "ghost" && err != nil && err.Error() == "not found", u, err)
+	assert(t, no())
+
+	var b bytes.Buffer
+	assert(t, s.WriteFixture(&b) == nil)
+	s.Unpatch()
+	assert(t, !no())
+
+	loaded := monkey.NewSession()
+	defer loaded.Unpatch()
+	assert(t, monkey.LoadFixture(loaded, bytes.NewReader(b.Bytes()), no, lookup) == nil)
+	u, err = lookup(context.Background(), "1")
+	assert(t, u == "ghost" && err != nil, u, err)
+	assert(t, no())
+	loaded.Unpatch()
+
+	assert(t, monkey.LoadFixture(loaded, bytes.NewReader(b.Bytes()), no) != nil)
+	loaded.Unpatch()
+	loaded.Patch(no, yes)
+	_, err = loaded.Export()
+	assert(t, err != nil)
+	_, err = loaded.Declare(no, monkey.PatchSpec{Error: "no error result"})
+	assert(t, err != nil)
+}
+
+func TestGoroutineSource(t *testing.T) {
+	// Patches left by goroutines of other tests would be keyed by go-tls.
+	monkey.UnpatchAll()
+	assert(t, monkey.SetGoroutineSource(monkey.Loader) == nil)
+	defer monkey.SetGoroutineSource(monkey.GoTLS)
+	g := monkey.Patch(no, yes)
+	assert(t, no())
+	c := make(chan bool)
+	go func() { c <- no() }()
+	assert(t, !<-c)
+	assert(t, monkey.SetGoroutineSource(monkey.GoTLS) != nil)
+	g.Unpatch()
+	assert(t, !no())
+}
+
+func TestGoroutineID(t *testing.T) {
+	if os.Getenv("MONKEY_TEST_GOID") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestGoroutineID$", "-test.v")
+		cmd.Env = append(os.Environ(), "MONKEY_TEST_GOID=1")
+		out, err := cmd.CombinedOutput()
+		assert(t, err == nil, err, string(out))
+		assert(t, strings.Contains(string(out), "--- PASS: TestGoroutineID"), string(out))
+		return
+	}
+
+	assert(t, monkey.SetGoroutineSource(monkey.GoroutineID) == nil)
+	g := monkey.Patch(no, yes)
+	assert(t, no())
+	c := make(chan bool)
+	go func() { c <- no() }()
+	assert(t, !<-c)
+	g.Unpatch()
+
+	// Goroutines getting the g of one that exited patched don't get its
+	// patch.
+	go func() {
+		monkey.Patch(no, yes)
+		c <- no()
+	}()
+	assert(t, <-c)
+	for i := 0; i < 100; i++ {
+		go func() { c <- no() }()
+		assert(t, !<-c)
+	}
+	assert(t, monkey.SetGoroutineSource(monkey.GoTLS) != nil)
 }
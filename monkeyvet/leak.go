@@ -0,0 +1,168 @@
+package monkeyvet
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// leaks reports the guards returned by monkey in body that are dropped, or
+// kept in a variable whose Unpatch is never called. Variables handed to
+// anything else than one of their own methods are assumed to be unpatched
+// elsewhere, and so are all guards of a function unpatching by target, with
+// the package level Unpatch or UnpatchAll.
+// Session methods and Script remove their patches themselves.
+func leaks(info *types.Info, body *ast.BlockStmt, report func(token.Pos, string, ...interface{})) {
+	type guard struct {
+		call      *ast.CallExpr
+		unpatched bool
+		escapes   bool
+		name      string
+	}
+	var (
+		discarded []*ast.CallExpr
+		guards    = make(map[types.Object]*guard)
+		order     []types.Object
+		all       bool
+	)
+	assigned := func(lhs ast.Expr, rhs ast.Expr) {
+		c, ok := rhs.(*ast.CallExpr)
+		if !ok || !returnsGuard(info, c) {
+			return
+		}
+		id, ok := lhs.(*ast.Ident)
+		if !ok {
+			return
+		}
+		if id.Name == "_" {
+			discarded = append(discarded, c)
+			return
+		}
+		obj := info.Defs[id]
+		if obj == nil {
+			obj = info.Uses[id]
+		}
+		if v, ok := obj.(*types.Var); !ok || v.Parent() == v.Pkg().Scope() {
+			return
+		}
+		if _, ok := guards[obj]; !ok {
+			guards[obj] = &guard{call: c, name: id.Name}
+			order = append(order, obj)
+		}
+	}
+
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ExprStmt:
+			if c, ok := n.X.(*ast.CallExpr); ok && returnsGuard(info, c) {
+				discarded = append(discarded, c)
+			}
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 {
+				// g := Patch(...) or g, err := TryPatch(...)
+				assigned(n.Lhs[0], n.Rhs[0])
+			} else if len(n.Lhs) == len(n.Rhs) {
+				for i := range n.Lhs {
+					assigned(n.Lhs[i], n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Values) == 1 && len(n.Names) > 0 {
+				assigned(n.Names[0], n.Values[0])
+			}
+		case *ast.CallExpr:
+			if isMonkeyFunc(info, n.Fun, "Unpatch", "UnpatchInstanceMethod", "UnpatchAll", "UnpatchAllForCurrentGoroutine") && !isMethod(info, n.Fun) {
+				all = true
+			}
+		}
+		return true
+	})
+	if all {
+		return
+	}
+
+	// Go over the uses of the guards with their parents at hand.
+	var stack []ast.Node
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if id, ok := n.(*ast.Ident); ok {
+			if g := guards[info.Uses[id]]; g != nil {
+				switch p := stack[len(stack)-1].(type) {
+				case *ast.SelectorExpr:
+					if p.Sel.Name == "Unpatch" {
+						g.unpatched = true
+					}
+				case *ast.AssignStmt:
+					if !isLHS(p, id) {
+						g.escapes = true
+					}
+				default:
+					g.escapes = true
+				}
+			}
+		}
+		stack = append(stack, n)
+		return true
+	})
+
+	for _, c := range discarded {
+		report(c.Pos(), "the guard returned here is dropped, so the patch is never removed: defer its Unpatch, or patch through a Session made by SessionT")
+	}
+	for _, obj := range order {
+		if g := guards[obj]; !g.unpatched && !g.escapes {
+			report(g.call.Pos(), "%s.Unpatch is never called, so the patch is never removed: defer %s.Unpatch(), or patch through a Session made by SessionT", g.name, g.name)
+		}
+	}
+}
+
+func isMethod(info *types.Info, fun ast.Expr) bool {
+	s, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	_, ok = info.Selections[s]
+	return ok
+}
+
+func isLHS(a *ast.AssignStmt, id *ast.Ident) bool {
+	for _, e := range a.Lhs {
+		if e == id {
+			return true
+		}
+	}
+	return false
+}
+
+// returnsGuard reports whether c calls a function of monkey returning a new
+// guard the caller has to unpatch.
+func returnsGuard(info *types.Info, c *ast.CallExpr) bool {
+	var id *ast.Ident
+	switch f := c.Fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return false
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != MonkeyPath {
+		return false
+	}
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() != nil || fn.Name() == "Script" || fn.Name() == "TryScript" {
+		return false
+	}
+	if sig.Results().Len() == 0 {
+		return false
+	}
+	p, ok := sig.Results().At(0).Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	n, ok := p.Elem().(*types.Named)
+	return ok && n.Obj().Name() == "PatchGuard"
+}
@@ -0,0 +1,174 @@
+// Package monkeyvet finds mistakes in the use of monkey that can be told
+// from the source alone, so that they fail the build instead of a test run.
+// The monkeyvet command runs it on packages and their tests.
+package monkeyvet
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// MonkeyPath is the import path of the monkey package.
+const MonkeyPath = "github.com/go-kiss/monkey"
+
+// Diagnostic is a problem found at Pos.
+type Diagnostic struct {
+	Pos     token.Pos
+	Message string
+}
+
+// Config tells Check about the build of the package it checks.
+type Config struct {
+	// NoInline is set when the package is built with inlining turned off,
+	// with -gcflags=-l, so that targets are not checked to be small enough
+	// to be inlined.
+	NoInline bool
+}
+
+// Check reports the problems in files, a type-checked package. info must
+// have its Types, Defs, Uses and Selections filled in.
+//
+// Calls of Patch and TryPatch, as functions or Session methods, are checked
+// for a replacement of another type than the target, and for targets
+// declared in files that are small enough to be inlined by the compiler.
+//
+// Guards returned by the package are checked to be unpatched in the function
+// getting them, or to leave it, e.g. by being returned or passed to another
+// function. See leaks.
+func Check(files []*ast.File, info *types.Info) []Diagnostic {
+	return Config{}.Check(files, info)
+}
+
+// Check is like the package level Check, leaving out the checks c turns
+// off.
+func (c Config) Check(files []*ast.File, info *types.Info) []Diagnostic {
+	decls := make(map[*types.Func]*ast.FuncDecl)
+	for _, f := range files {
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok {
+				if fn, ok := info.Defs[fd.Name].(*types.Func); ok {
+					decls[fn] = fd
+				}
+			}
+		}
+	}
+
+	var diags []Diagnostic
+	report := func(pos token.Pos, format string, args ...interface{}) {
+		diags = append(diags, Diagnostic{pos, fmt.Sprintf(format, args...)})
+	}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) < 2 || !isMonkeyFunc(info, call.Fun, "Patch", "TryPatch") {
+				return true
+			}
+			target, replacement := call.Args[0], call.Args[1]
+			tt, rt := signature(info, target), signature(info, replacement)
+			if tt != nil && rt != nil && !types.Identical(tt, rt) {
+				report(replacement.Pos(), "replacement of type %s does not match target of type %s", rt, tt)
+			}
+			if fn := funcOf(info, target); fn != nil && !c.NoInline {
+				if fd := decls[fn]; fd != nil && inlinable(fd) {
+					report(target.Pos(), "%s is small enough to be inlined, and then can't be patched: mark it //go:noinline or build with -gcflags=-l", fn.Name())
+				}
+			}
+			return true
+		})
+		for _, d := range f.Decls {
+			if fd, ok := d.(*ast.FuncDecl); ok && fd.Body != nil {
+				leaks(info, fd.Body, report)
+			}
+		}
+	}
+	return diags
+}
+
+// isMonkeyFunc reports whether fun is one of the named functions or
+// methods of the monkey package.
+func isMonkeyFunc(info *types.Info, fun ast.Expr, names ...string) bool {
+	var id *ast.Ident
+	switch f := fun.(type) {
+	case *ast.Ident:
+		id = f
+	case *ast.SelectorExpr:
+		id = f.Sel
+	default:
+		return false
+	}
+	fn, ok := info.Uses[id].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != MonkeyPath {
+		return false
+	}
+	for _, n := range names {
+		if fn.Name() == n {
+			return true
+		}
+	}
+	return false
+}
+
+// signature returns the function type of e, or nil if it has none.
+func signature(info *types.Info, e ast.Expr) *types.Signature {
+	tv, ok := info.Types[e]
+	if !ok || tv.Type == nil {
+		return nil
+	}
+	s, _ := tv.Type.Underlying().(*types.Signature)
+	return s
+}
+
+// funcOf returns the function or method e names, as in F, pkg.F or T.M.
+func funcOf(info *types.Info, e ast.Expr) *types.Func {
+	for p, ok := e.(*ast.ParenExpr); ok; p, ok = e.(*ast.ParenExpr) {
+		e = p.X
+	}
+	switch e := e.(type) {
+	case *ast.Ident:
+		fn, _ := info.Uses[e].(*types.Func)
+		return fn
+	case *ast.SelectorExpr:
+		if s, ok := info.Selections[e]; ok {
+			if s.Kind() != types.MethodExpr {
+				return nil
+			}
+			fn, _ := s.Obj().(*types.Func)
+			return fn
+		}
+		fn, _ := info.Uses[e.Sel].(*types.Func)
+		return fn
+	}
+	return nil
+}
+
+// inlinable reports whether fd is a leaf function of a single statement not
+// marked //go:noinline, which the compiler inlines into its callers.
+func inlinable(fd *ast.FuncDecl) bool {
+	if fd.Body == nil || len(fd.Body.List) > 1 || hasDirective(fd.Doc, "//go:noinline") {
+		return false
+	}
+	leaf := true
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.CallExpr, *ast.FuncLit, *ast.GoStmt, *ast.DeferStmt, *ast.RangeStmt, *ast.ForStmt, *ast.SelectStmt:
+			leaf = false
+		}
+		return leaf
+	})
+	return leaf
+}
+
+func hasDirective(doc *ast.CommentGroup, directive string) bool {
+	if doc == nil {
+		return false
+	}
+	for _, c := range doc.List {
+		if strings.HasPrefix(c.Text, directive) {
+			return true
+		}
+	}
+	return false
+}
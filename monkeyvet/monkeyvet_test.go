@@ -0,0 +1,224 @@
+package monkeyvet_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/go-kiss/monkey/monkeyvet"
+)
+
+// fakeMonkey declares the parts of monkey the checks look at.
+const fakeMonkey = `package monkey
+
+type PatchGuard struct{}
+type Session struct{}
+
+func Patch(target, replacement interface{}) *PatchGuard { return nil }
+func TryPatch(target, replacement interface{}) (*PatchGuard, error) { return nil, nil }
+func (s *Session) Patch(target, replacement interface{}) *PatchGuard { return nil }
+func (g *PatchGuard) Unpatch() {}
+func (g *PatchGuard) Disable() {}
+func UnpatchAll() {}
+`
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+// check type-checks src, importing the fake monkey, and runs monkeyvet.
+func check(t *testing.T, src string) []string {
+	return checkWith(t, monkeyvet.Config{}, src)
+}
+
+// checkWith is like check, running monkeyvet with c.
+func checkWith(t *testing.T, c monkeyvet.Config, src string) []string {
+	fset := token.NewFileSet()
+	parse := func(name, src string) *ast.File {
+		f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return f
+	}
+	std := importer.Default()
+	m, err := (&types.Config{Importer: std}).Check(monkeyvet.MonkeyPath, fset, []*ast.File{parse("monkey.go", fakeMonkey)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []*ast.File{parse("a_test.go", src)}
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		if path == monkeyvet.MonkeyPath {
+			return m, nil
+		}
+		return std.Import(path)
+	})}
+	if _, err := conf.Check("a", fset, files, info); err != nil {
+		t.Fatal(err)
+	}
+	var r []string
+	for _, d := range c.Check(files, info) {
+		r = append(r, fset.Position(d.Pos).String()+": "+d.Message)
+	}
+	return r
+}
+
+func TestCheck(t *testing.T) {
+	diags := check(t, `package a
+
+import "github.com/go-kiss/monkey"
+
+func tiny() int { return 1 }
+
+//go:noinline
+func marked() int { return 1 }
+
+func big(n int) int {
+	s := 0
+	for i := 0; i < n; i++ {
+		s += i
+	}
+	return s
+}
+
+type T struct{}
+
+func (T) M() int { return 1 }
+
+func f(s *monkey.Session) {
+	defer monkey.UnpatchAll()
+	monkey.Patch(big, func(int) int { return 0 })
+	monkey.Patch(marked, func() int { return 0 })
+	monkey.Patch(big, func() int { return 0 })
+	monkey.TryPatch(tiny, func() int { return 0 })
+	s.Patch(T.M, func(T) int { return 0 })
+	s.Patch(big, marked)
+}
+`)
+	want := []string{
+		"a_test.go:26:20: replacement of type func() int does not match target of type func(n int) int",
+		"a_test.go:27:18: tiny is small enough to be inlined",
+		"a_test.go:28:10: M is small enough to be inlined",
+		"a_test.go:29:15: replacement of type func() int does not match target of type func(n int) int",
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d:\n%s", len(diags), len(want), strings.Join(diags, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(diags[i], want[i]) {
+			t.Errorf("got %q, want %q", diags[i], want[i])
+		}
+	}
+}
+
+func TestCheckNoInline(t *testing.T) {
+	src := `package a
+
+import "github.com/go-kiss/monkey"
+
+func tiny() int { return 1 }
+
+func f() {
+	defer monkey.UnpatchAll()
+	monkey.Patch(tiny, func() int { return 0 })
+	monkey.Patch(tiny, func(int) int { return 0 })
+}
+`
+	for _, c := range []struct {
+		config monkeyvet.Config
+		want   []string
+	}{
+		{monkeyvet.Config{}, []string{
+			"a_test.go:9:15: tiny is small enough to be inlined",
+			"a_test.go:10:21: replacement of type func(int) int does not match",
+			"a_test.go:10:15: tiny is small enough to be inlined",
+		}},
+		{monkeyvet.Config{NoInline: true}, []string{
+			"a_test.go:10:21: replacement of type func(int) int does not match",
+		}},
+	} {
+		diags := checkWith(t, c.config, src)
+		if len(diags) != len(c.want) {
+			t.Fatalf("%+v: got %d diagnostics, want %d:\n%s", c.config, len(diags), len(c.want), strings.Join(diags, "\n"))
+		}
+		for i := range c.want {
+			if !strings.HasPrefix(diags[i], c.want[i]) {
+				t.Errorf("%+v: got %q, want %q", c.config, diags[i], c.want[i])
+			}
+		}
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	diags := check(t, `package a
+
+import "github.com/go-kiss/monkey"
+
+//go:noinline
+func f() int { return 1 }
+
+func g() int { return 2 }
+
+func dropped() {
+	monkey.Patch(f, g)
+	_, _ = monkey.TryPatch(f, g)
+}
+
+func kept() {
+	p := monkey.Patch(f, g)
+	defer p.Unpatch()
+	q, err := monkey.TryPatch(f, g)
+	if err == nil {
+		q.Unpatch()
+	}
+}
+
+func forgotten() {
+	p := monkey.Patch(f, g)
+	p.Disable()
+	var q = monkey.Patch(f, g)
+	q = monkey.Patch(f, g)
+	q.Disable()
+}
+
+func escapes(s *monkey.Session) *monkey.PatchGuard {
+	s.Patch(f, g)
+	p := monkey.Patch(f, g)
+	func() { p.Unpatch() }()
+	keep(monkey.Patch(f, g))
+	return monkey.Patch(f, g)
+}
+
+func keep(*monkey.PatchGuard) {}
+
+func all() {
+	monkey.Patch(f, g)
+	defer monkey.UnpatchAll()
+}
+`)
+	want := []string{
+		"a_test.go:11:2: the guard returned here is dropped",
+		"a_test.go:12:9: the guard returned here is dropped",
+		"a_test.go:25:7: p.Unpatch is never called",
+		"a_test.go:27:10: q.Unpatch is never called",
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d:\n%s", len(diags), len(want), strings.Join(diags, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(diags[i], want[i]) {
+			t.Errorf("got %q, want %q", diags[i], want[i])
+		}
+	}
+}
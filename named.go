@@ -0,0 +1,63 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNameTaken is returned when patching with a name another patch in
+// place already has, see Named.
+var ErrNameTaken = errors.New("monkey: patch name already taken")
+
+// names holds the guards of the patches made with Named, by name. Guards
+// whose patch was removed since are forgotten when looked up. lock must be
+// held.
+var names = map[string]*PatchGuard{}
+
+// Named gives the patch a name to get its guard back with Find, so that
+// code managing patches declaratively, like chaos controllers or test DSLs,
+// can refer to them by stable names rather than pass guards around. A name
+// belongs to a single patch in place at a time.
+func Named(name string) PatchOption {
+	return func(c *patchConfig) {
+		c.name = name
+	}
+}
+
+// Find returns the guard of the patch in place named name, see Named, or
+// nil. Once unpatched, by its guard or otherwise, a patch isn't found
+// anymore, until restored.
+func Find(name string) *PatchGuard {
+	lock.Lock()
+	defer lock.Unlock()
+	return findNamed(name)
+}
+
+// Name returns the name given to the patch with Named.
+func (g *PatchGuard) Name() string {
+	return g.config.name
+}
+
+func findNamed(name string) *PatchGuard {
+	g, ok := names[name]
+	if !ok {
+		return nil
+	}
+	if p, ok := lookupPatch(g.target.Pointer()); !ok || !p.holds(g) {
+		delete(names, name)
+		return nil
+	}
+	return g
+}
+
+// claimName checks the name of pg is free for it. lock must be held.
+func claimName(pg *PatchGuard) error {
+	name := pg.config.name
+	if name == "" {
+		return nil
+	}
+	if g := findNamed(name); g != nil && g != pg {
+		return fmt.Errorf("%w: %q is %s patched at %s", ErrNameTaken, name, g.TargetName(), g.CreatedAt())
+	}
+	return nil
+}
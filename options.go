@@ -0,0 +1,141 @@
+package monkey
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"sync/atomic"
+)
+
+// PatchOption changes how a single patch is applied.
+type PatchOption func(*patchConfig)
+
+type patchConfig struct {
+	follow         bool
+	record         bool
+	unpatchOnPanic bool
+	reentrant      bool
+	exhausted      Exhaustion
+	prototype      reflect.Type
+	// when makes the patch apply to every goroutine, for the calls it
+	// holds for, see shared.go.
+	when func(in []reflect.Value) bool
+	// onContext requires a target taking a context first, see OnContext.
+	onContext bool
+	// assembly allows targets written in assembly, see
+	// AllowAssemblyTargets.
+	assembly bool
+	// intrinsic allows targets the compiler intrinsifies, see
+	// AllowIntrinsics.
+	intrinsic bool
+	// chain allows targets patched by other libraries, see
+	// ChainForeignPatches.
+	chain bool
+	// debuggerSafe refuses to write over breakpoints, see DebuggerSafe.
+	debuggerSafe bool
+	// name is the name of the patch, see Named.
+	name string
+	// scope orders the patches applying to every goroutine, see Scope.
+	scope Scope
+	// trace wraps calls in trace regions, see Trace.
+	trace bool
+}
+
+func newPatchConfig(opts []PatchOption) *patchConfig {
+	c := &patchConfig{}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// FollowWrapper patches the function target tail-calls into instead of
+// target itself, when target does nothing but jump to it with its arguments
+// unchanged. Such wrappers are generated for methods promoted from embedded
+// structs, and following them makes the patch also catch callers going to
+// the implementation directly. Everything calling the implementation is
+// affected, not only the callers of target.
+//
+// Wrappers that are inlined into their callers can't be caught this way,
+// build with -gcflags=-l for that.
+func FollowWrapper() PatchOption {
+	return func(c *patchConfig) {
+		c.follow = true
+	}
+}
+
+// followTailCalls follows the chain of pure tail calls starting at from.
+func followTailCalls(from uintptr) uintptr {
+	for i := 0; i < 8; i++ {
+		to, ok := tailCallTarget(from)
+		if !ok {
+			break
+		}
+		if f := runtime.FuncForPC(to); f == nil || f.Entry() != to {
+			break
+		}
+		from = to
+	}
+	return from
+}
+
+// Options configures the whole package, see SetOptions.
+type Options struct {
+	// PanicOnError makes the functions without an error result, like
+	// Patch, panic when they fail. Otherwise the error is logged and
+	// they return an inert guard reporting it from Err.
+	PanicOnError bool
+	// Logger, if set, is told about every patch made or removed and
+	// every error.
+	Logger Logger
+	// MaxPatches limits the number of patches in place at once, counting
+	// one per target and goroutine. Zero means no limit.
+	MaxPatches int
+	// MaxGoroutinesPerTarget limits the number of goroutines a single
+	// function can be patched for at once. The dispatcher looks entries
+	// up one by one, so every one of them slows down all calls to the
+	// target. Zero means no limit.
+	MaxGoroutinesPerTarget int
+	// MaxTargets limits the number of functions with a stub at once.
+	// Reaching it runs Compact to forget those no longer patched first.
+	// Zero means no limit.
+	MaxTargets int
+}
+
+// Logger is implemented by *log.Logger.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+var (
+	// ErrMaxPatches is returned when Options.MaxPatches would be
+	// exceeded.
+	ErrMaxPatches = errors.New("monkey: too many patches")
+	// ErrMaxGoroutines is returned when Options.MaxGoroutinesPerTarget
+	// would be exceeded.
+	ErrMaxGoroutines = errors.New("monkey: too many goroutines patching the same function")
+)
+
+var options atomic.Value
+
+func init() {
+	options.Store(Options{PanicOnError: true})
+}
+
+// SetOptions replaces the options of the package. Until it is called, they
+// are Options{PanicOnError: true}. Use CurrentOptions to change a single
+// field.
+func SetOptions(o Options) {
+	options.Store(o)
+}
+
+// CurrentOptions returns the options of the package.
+func CurrentOptions() Options {
+	return options.Load().(Options)
+}
+
+func logf(format string, v ...interface{}) {
+	if l := CurrentOptions().Logger; l != nil {
+		l.Printf("monkey: "+format, v...)
+	}
+}
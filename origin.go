@@ -0,0 +1,82 @@
+package monkey
+
+import (
+	"net/url"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Package returns the import path of the package that made g, the first
+// one outside of this package on the stack.
+func (g *PatchGuard) Package() string {
+	return funcPackage(g.site.Function)
+}
+
+// Module returns the path of the module the package that made g belongs to,
+// or "" if the binary has no module information.
+func (g *PatchGuard) Module() string {
+	return moduleOf(g.Package())
+}
+
+// UnpatchAllFrom removes the patches made by the packages of the module
+// with the given path, on every goroutine, and leaves the others in place.
+// Frameworks sharing a process can sweep their own patches this way.
+func UnpatchAllFrom(modulePath string) {
+	lock.Lock()
+	defer lock.Unlock()
+	for _, p := range loadRegistry().patches {
+		for gid, e := range p.entries() {
+			if gid != anyG && moduleOf(e.pkg) == modulePath {
+				p.Del(gid, e)
+			}
+		}
+		for _, g := range p.sharedGuards() {
+			if moduleOf(g.entry.pkg) == modulePath {
+				p.delShared(g)
+			}
+		}
+	}
+}
+
+// funcPackage returns the import path of the package of the function with
+// the fully qualified name fn, e.g. "net/http" for "net/http.(*Client).Do".
+// The linker escapes the dots of the last element of the path, and a few
+// other bytes, as in "gopkg.in/yaml%2ev3.Unmarshal", which are unescaped.
+func funcPackage(fn string) string {
+	slash := strings.LastIndex(fn, "/")
+	if dot := strings.Index(fn[slash+1:], "."); dot >= 0 {
+		fn = fn[:slash+1+dot]
+	}
+	if pkg, err := url.PathUnescape(fn); err == nil {
+		return pkg
+	}
+	return fn
+}
+
+var modules struct {
+	once  sync.Once
+	paths []string
+}
+
+// moduleOf returns the path of the module of the package pkg, the longest
+// one of the binary it is in. External test packages belong to the module
+// of the package they test.
+func moduleOf(pkg string) string {
+	modules.once.Do(func() {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			modules.paths = append(modules.paths, bi.Main.Path)
+			for _, m := range bi.Deps {
+				modules.paths = append(modules.paths, m.Path)
+			}
+		}
+	})
+	pkg = strings.TrimSuffix(pkg, "_test")
+	found := ""
+	for _, m := range modules.paths {
+		if (pkg == m || strings.HasPrefix(pkg, m+"/")) && len(m) > len(found) {
+			found = m
+		}
+	}
+	return found
+}
@@ -0,0 +1,20 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import "testing"
+
+func TestFuncPackage(t *testing.T) {
+	for fn, want := range map[string]string{
+		"net/http.(*Client).Do":           "net/http",
+		"main.main":                       "main",
+		"gopkg.in/yaml%2ev3.Unmarshal":    "gopkg.in/yaml.v3",
+		"example.com/a.b/c%2ed.(*T).Func": "example.com/a.b/c.d",
+		"example.com/%22q%22.F":           `example.com/"q"`,
+	} {
+		if got := funcPackage(fn); got != want {
+			t.Errorf("funcPackage(%q) = %q, want %q", fn, got, want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package monkey
+
+import "reflect"
+
+// UnpatchOnPanic removes the patch as soon as the replacement panics, before
+// the panic goes on. Code recovering from the panic, or reporting it, then
+// runs the original function instead of entering the replacement again.
+func UnpatchOnPanic() PatchOption {
+	return func(c *patchConfig) {
+		c.unpatchOnPanic = true
+	}
+}
+
+// unpatchOnPanic returns fn unpatching g if it panics.
+func unpatchOnPanic(g *PatchGuard, fn reflect.Value) reflect.Value {
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		returned := false
+		defer func() {
+			if !returned {
+				g.Unpatch()
+			}
+		}()
+		out := call(fn, in)
+		returned = true
+		return out
+	})
+}
@@ -0,0 +1,84 @@
+package monkey
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// refusing makes the calls replacements hand on to the original return
+// zero results instead, see CheckPassThrough.
+var refusing int32
+
+var refused struct {
+	sync.Mutex
+	funcs map[string]bool
+}
+
+// CheckPassThrough runs body twice as subtests: first as usual, then with
+// every call a replacement doesn't handle, which would go on to the
+// original function, returning zero results instead. It reports tests
+// passing only the first time, which rely on the real code behind some of
+// their patches, e.g. on the network for a PatchReceiver of another
+// client, and tests passing only the second time. The calls refused are
+// those handed on by PatchReceiver, Script and conditional patches, such as
+// those of ForLabels and OnContext. Calls to the original made by the
+// replacement itself, through Intercept for instance, are left alone.
+//
+// Patches made by any goroutine are affected while body runs without pass
+// through, so body must not run in parallel with other tests.
+func CheckPassThrough(t *testing.T, body func(t *testing.T)) {
+	t.Helper()
+	with := t.Run("with pass-through", body)
+
+	refused.Lock()
+	refused.funcs = map[string]bool{}
+	refused.Unlock()
+	atomic.StoreInt32(&refusing, 1)
+	without := t.Run("without pass-through", body)
+	atomic.StoreInt32(&refusing, 0)
+
+	refused.Lock()
+	var funcs []string
+	for f := range refused.funcs {
+		funcs = append(funcs, f)
+	}
+	refused.funcs = nil
+	refused.Unlock()
+	sort.Strings(funcs)
+
+	switch {
+	case with && !without:
+		t.Errorf("monkey: the test only passes when calls go on to the originals of %s", strings.Join(funcs, ", "))
+	case !with && without:
+		t.Errorf("monkey: the test only passes when calls don't go on to the originals of %s", strings.Join(funcs, ", "))
+	}
+}
+
+// refusePassThrough returns zero results for a call to the function at
+// from of type typ about to be handed on to the original, or nil if it
+// may go on.
+func refusePassThrough(from uintptr, typ reflect.Type) []reflect.Value {
+	if atomic.LoadInt32(&refusing) == 0 {
+		return nil
+	}
+	refused.Lock()
+	if refused.funcs != nil {
+		refused.funcs[SymbolName(from)] = true
+	}
+	refused.Unlock()
+	return zeroResults(typ)
+}
+
+// handOn is passOn for a call the replacement doesn't handle, unless
+// CheckPassThrough refuses it.
+func handOn(g *PatchGuard, in []reflect.Value) []reflect.Value {
+	if out := refusePassThrough(g.target.Pointer(), g.target.Type()); out != nil {
+		atomic.AddUint64(&g.entry.calls, ^uint64(0))
+		return out
+	}
+	return passOn(g, in)
+}
@@ -0,0 +1,60 @@
+package monkey
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"strings"
+)
+
+// Policy decides in which binaries patches may be applied.
+type Policy int
+
+const (
+	// AllowAll applies patches in any binary.
+	AllowAll Policy = iota
+	// TestOnly refuses to apply patches outside test binaries.
+	TestOnly
+)
+
+// ErrNotTest is returned when the TestOnly policy refuses a patch.
+var ErrNotTest = errors.New("monkey: refusing to patch outside a test binary (see monkey.SetPolicy)")
+
+// PolicyEnv names the environment variable holding the default policy.
+// Set it to "testonly" to start with TestOnly, anything else means AllowAll.
+const PolicyEnv = "MONKEY_POLICY"
+
+var policy = defaultPolicy()
+
+func defaultPolicy() Policy {
+	if strings.EqualFold(os.Getenv(PolicyEnv), "testonly") {
+		return TestOnly
+	}
+	return AllowAll
+}
+
+// SetPolicy sets the policy used by every following patch.
+func SetPolicy(p Policy) {
+	lock.Lock()
+	defer lock.Unlock()
+	policy = p
+}
+
+// checkPolicy must be called with lock held.
+func checkPolicy() error {
+	if policy == TestOnly && !testBinary() {
+		return ErrNotTest
+	}
+	return nil
+}
+
+// testBinary tells whether the running binary is a test, replaced by the
+// tests of the policy.
+var testBinary = isTestBinary
+
+func isTestBinary() bool {
+	if flag.Lookup("test.v") != nil {
+		return true
+	}
+	return strings.HasSuffix(strings.TrimSuffix(os.Args[0], ".exe"), ".test")
+}
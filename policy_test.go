@@ -0,0 +1,37 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import (
+	"errors"
+	"testing"
+)
+
+//go:noinline
+func guarded() bool { return false }
+
+func TestPolicyRefuses(t *testing.T) {
+	defer func(f func() bool) { testBinary = f }(testBinary)
+	testBinary = func() bool { return false }
+	SetPolicy(TestOnly)
+	defer SetPolicy(AllowAll)
+
+	_, err := TryPatch(guarded, func() bool { return true })
+	if !errors.Is(err, ErrNotTest) {
+		t.Fatalf("got %v, want ErrNotTest", err)
+	}
+	if guarded() {
+		t.Error("the refused patch applies")
+	}
+
+	SetPolicy(AllowAll)
+	g, err := TryPatch(guarded, func() bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Unpatch()
+	if !guarded() {
+		t.Error("the patch allowed by AllowAll doesn't apply")
+	}
+}
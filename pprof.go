@@ -0,0 +1,286 @@
+package monkey
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+)
+
+// SymbolizeProfile copies the profile read from r to w, as written by
+// runtime/pprof, naming the code generated by the package, which the runtime
+// can't symbolize. Addresses in the stub of a target are attributed to a
+// "monkey.dispatch(pkg.Func)" frame, and those in the dispatcher shared by
+// all targets to "monkey.dispatch". The CPU profiler of Go keeps neither
+// the address nor the callers of such samples, and puts them in
+// runtime._ExternalCode, which is renamed to "monkey.dispatch" as well once
+// a stub was generated, unless the process calls C, which would be there
+// too. It has to run in the process that took the profile, before Compact
+// frees stubs:
+//
+//	var buf bytes.Buffer
+//	pprof.StartCPUProfile(&buf)
+//	...
+//	pprof.StopCPUProfile()
+//	monkey.SymbolizeProfile(f, &buf)
+func SymbolizeProfile(w io.Writer, r io.Reader) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		r = zr
+	} else {
+		r = br
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	stubs := Stubs()
+	var rename map[string]string
+	if len(stubs) > 0 && runtime.NumCgoCall() <= 1 {
+		rename = map[string]string{externalCode: "monkey.dispatch"}
+	}
+	out, err := symbolizeProfile(data, stubs, rename)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(out); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// Fields of the messages of profile.proto read and written.
+const (
+	profileLocation    = 4
+	profileFunction    = 5
+	profileStringTable = 6
+
+	locationAddress = 3
+	locationLine    = 4
+
+	lineFunctionID = 1
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+)
+
+var errProfile = errors.New("monkey: malformed profile")
+
+// externalCode is the function the runtime puts CPU samples taken outside of
+// Go code in, dropping their address and callers. In a process not calling
+// C, only the code generated by the package is there.
+const externalCode = "runtime._ExternalCode"
+
+// protoField is a field of an encoded protocol buffer message. val holds
+// varints, raw the whole encoded field.
+type protoField struct {
+	num  int
+	val  uint64
+	data []byte
+	raw  []byte
+}
+
+// protoFields splits an encoded message into its fields.
+func protoFields(b []byte) ([]protoField, error) {
+	var fields []protoField
+	for len(b) > 0 {
+		start := b
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errProfile
+		}
+		b = b[n:]
+		f := protoField{num: int(key >> 3)}
+		switch key & 7 {
+		case 0:
+			f.val, n = binary.Uvarint(b)
+			if n <= 0 {
+				return nil, errProfile
+			}
+		case 1:
+			n = 8
+		case 2:
+			l, m := binary.Uvarint(b)
+			if m <= 0 || uint64(len(b)-m) < l {
+				return nil, errProfile
+			}
+			f.data = b[m : m+int(l)]
+			n = m + int(l)
+		case 5:
+			n = 4
+		default:
+			return nil, errProfile
+		}
+		if len(b) < n {
+			return nil, errProfile
+		}
+		b = b[n:]
+		f.raw = start[:len(start)-len(b)]
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func appendVarintField(b []byte, num int, v uint64) []byte {
+	b = appendUvarint(b, uint64(num)<<3)
+	return appendUvarint(b, v)
+}
+
+func appendBytesField(b []byte, num int, v []byte) []byte {
+	b = appendUvarint(b, uint64(num)<<3|2)
+	b = appendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// symbolizeProfile gives the locations of profile, an encoded Profile
+// message, without lines and in stubs a line in a function named after the
+// stub. The functions and their names are added at the end of the message,
+// every other field is kept as it is, except for the names of the functions
+// renamed, see externalCode.
+func symbolizeProfile(profile []byte, stubs []Stub, rename map[string]string) ([]byte, error) {
+	fields, err := protoFields(profile)
+	if err != nil {
+		return nil, err
+	}
+	var strs []string
+	var maxID uint64
+	for _, f := range fields {
+		switch f.num {
+		case profileStringTable:
+			strs = append(strs, string(f.data))
+		case profileFunction:
+			fn, err := protoFields(f.data)
+			if err != nil {
+				return nil, err
+			}
+			for _, ff := range fn {
+				if ff.num == functionID && ff.val > maxID {
+					maxID = ff.val
+				}
+			}
+		}
+	}
+
+	// added are the strings added to the table, by index.
+	var added []string
+	str := func(s string) uint64 {
+		for i, a := range added {
+			if a == s {
+				return uint64(len(strs) + i)
+			}
+		}
+		added = append(added, s)
+		return uint64(len(strs) + len(added) - 1)
+	}
+	ids := map[string]uint64{}
+	var names []string
+	out := make([]byte, 0, len(profile))
+	for _, f := range fields {
+		switch f.num {
+		case profileLocation:
+			b, err := symbolizeLocation(f, stubs, func(name string) uint64 {
+				id, ok := ids[name]
+				if !ok {
+					id = maxID + uint64(len(names)) + 1
+					ids[name] = id
+					names = append(names, name)
+				}
+				return id
+			})
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b...)
+		case profileFunction:
+			fn, err := protoFields(f.data)
+			if err != nil {
+				return nil, err
+			}
+			var b []byte
+			for _, ff := range fn {
+				to, ok := "", false
+				if ff.num == functionName || ff.num == functionSystemName {
+					if ff.val < uint64(len(strs)) {
+						to, ok = rename[strs[ff.val]]
+					}
+				}
+				if ok {
+					b = appendVarintField(b, ff.num, str(to))
+				} else {
+					b = append(b, ff.raw...)
+				}
+			}
+			out = appendBytesField(out, profileFunction, b)
+		default:
+			out = append(out, f.raw...)
+		}
+	}
+
+	for i, name := range names {
+		s := str(name)
+		fn := appendVarintField(nil, functionID, maxID+uint64(i)+1)
+		fn = appendVarintField(fn, functionName, s)
+		fn = appendVarintField(fn, functionSystemName, s)
+		out = appendBytesField(out, profileFunction, fn)
+	}
+	for _, s := range added {
+		out = appendBytesField(out, profileStringTable, []byte(s))
+	}
+	return out, nil
+}
+
+// symbolizeLocation returns the location f with a line in the function id
+// returns for the name of its stub, if it has no line and its address is in
+// a stub.
+func symbolizeLocation(f protoField, stubs []Stub, id func(string) uint64) ([]byte, error) {
+	loc, err := protoFields(f.data)
+	if err != nil {
+		return nil, err
+	}
+	var addr uint64
+	for _, lf := range loc {
+		switch lf.num {
+		case locationAddress:
+			addr = lf.val
+		case locationLine:
+			return f.raw, nil
+		}
+	}
+	name := stubProfileName(stubs, uintptr(addr))
+	if name == "" {
+		return f.raw, nil
+	}
+	line := appendVarintField(nil, lineFunctionID, id(name))
+	return appendBytesField(nil, profileLocation, appendBytesField(f.data, locationLine, line)), nil
+}
+
+// stubProfileName returns the name given in profiles to the code generated
+// at pc, or "" if pc isn't in a stub.
+func stubProfileName(stubs []Stub, pc uintptr) string {
+	for _, s := range stubs {
+		if pc < s.Addr || pc >= s.Addr+uintptr(s.Size) {
+			continue
+		}
+		if strings.HasPrefix(s.Func, "stub for ") {
+			return "monkey.dispatch(" + strings.TrimPrefix(s.Func, "stub for ") + ")"
+		}
+		return "monkey.dispatch"
+	}
+	return ""
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
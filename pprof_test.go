@@ -0,0 +1,74 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import "testing"
+
+func TestSymbolizeProfile(t *testing.T) {
+	stubs := []Stub{
+		{Addr: 0x1000, Size: 0x40, Func: "stub for main.fetch"},
+		{Addr: 0x2000, Size: 0x80, Func: "monkey dispatcher"},
+	}
+	var p []byte
+	p = appendBytesField(p, profileStringTable, nil)
+	p = appendBytesField(p, profileStringTable, []byte("main.run"))
+	p = appendBytesField(p, profileFunction, appendVarintField(appendVarintField(nil, functionID, 7), functionName, 1))
+	for id, addr := range []uint64{0x1010, 0x2020, 0x2030, 0x3000} {
+		p = appendBytesField(p, profileLocation, appendVarintField(appendVarintField(nil, 1, uint64(id+1)), locationAddress, addr))
+	}
+
+	b, err := symbolizeProfile(p, stubs, map[string]string{"main.run": "main.renamed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields, err := protoFields(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var strs []string
+	for _, f := range fields {
+		if f.num == profileStringTable {
+			strs = append(strs, string(f.data))
+		}
+	}
+	names := map[uint64]string{}
+	var lines [][]protoField
+	for _, f := range fields {
+		if f.num != profileFunction && f.num != profileLocation {
+			continue
+		}
+		m, err := protoFields(f.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.num == profileFunction {
+			names[m[0].val] = strs[m[1].val]
+		} else {
+			lines = append(lines, m)
+		}
+	}
+	var located []string
+	for _, m := range lines {
+		name := ""
+		for _, lf := range m {
+			if lf.num == locationLine {
+				l, _ := protoFields(lf.data)
+				name = names[l[0].val]
+			}
+		}
+		located = append(located, name)
+	}
+	want := []string{"monkey.dispatch(main.fetch)", "monkey.dispatch", "monkey.dispatch", ""}
+	if len(located) != len(want) {
+		t.Fatal(located)
+	}
+	for i := range want {
+		if located[i] != want[i] {
+			t.Errorf("location %d is in %q, want %q", i+1, located[i], want[i])
+		}
+	}
+	if names[7] != "main.renamed" || len(names) != 3 {
+		t.Error(names)
+	}
+}
@@ -0,0 +1,56 @@
+package monkey
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// ProfileEnv names the environment variable selecting the profile run by
+// ApplyProfile.
+const ProfileEnv = "MONKEY_PROFILE"
+
+var (
+	profilesLock sync.Mutex
+	profiles     = make(map[string]func())
+)
+
+// RegisterProfile declares a named set of patches. Patches live in the
+// memory of a single process, so a child process started by a test doesn't
+// see them. Register the same profile in both processes, start the child
+// with ProfileCmd and call ApplyProfile in it to patch it the same way.
+func RegisterProfile(name string, setup func()) {
+	profilesLock.Lock()
+	defer profilesLock.Unlock()
+	profiles[name] = setup
+}
+
+// ProfileCmd makes cmd apply the profile name when it calls ApplyProfile.
+func ProfileCmd(cmd *exec.Cmd, name string) *exec.Cmd {
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, ProfileEnv+"="+name)
+	return cmd
+}
+
+// ApplyProfile runs the profile selected by ProfileEnv. It does nothing if
+// the variable is not set. Patches only apply to the calling goroutine, so
+// call it where the patched code runs, e.g. first thing in the test the
+// child process executes.
+func ApplyProfile() error {
+	name := os.Getenv(ProfileEnv)
+	if name == "" {
+		return nil
+	}
+
+	profilesLock.Lock()
+	setup, ok := profiles[name]
+	profilesLock.Unlock()
+	if !ok {
+		return fmt.Errorf("monkey: unknown profile %q", name)
+	}
+	setup()
+	return nil
+}
@@ -0,0 +1,71 @@
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// ErrPromoted is returned by PatchInstanceMethod for a method promoted from
+// an embedded field when the replacement takes the outer receiver.
+var ErrPromoted = fmt.Errorf("monkey: method is promoted from an embedded field")
+
+// promotedMethod returns the method the method m of t is promoted from, if
+// it is, following embedded fields down to the method declared in source.
+// Methods promoted from embedded interfaces have no code to patch and are
+// reported as errors.
+func promotedMethod(t reflect.Type, m reflect.Method) (reflect.Method, bool, error) {
+	found := false
+	for generated(m.Func.Pointer()) {
+		s := t
+		if s.Kind() == reflect.Ptr {
+			s = s.Elem()
+		}
+		if s.Kind() != reflect.Struct {
+			break
+		}
+		next, ok := reflect.Type(nil), false
+		for i := 0; i < s.NumField() && !ok; i++ {
+			f := s.Field(i)
+			if !f.Anonymous {
+				continue
+			}
+			// Prefer methods with value receivers to the wrappers the
+			// compiler makes for them on pointers.
+			base := f.Type
+			if base.Kind() == reflect.Ptr {
+				base = base.Elem()
+			}
+			for _, ft := range []reflect.Type{base, reflect.PtrTo(base)} {
+				if _, ok = ft.MethodByName(m.Name); ok {
+					next = ft
+					break
+				}
+			}
+		}
+		if !ok {
+			break
+		}
+		if next.Kind() == reflect.Interface {
+			return m, false, fmt.Errorf("monkey: %s.%s is promoted from the interface %s, patch the method of its implementation instead", t, m.Name, next)
+		}
+		t, m, found = next, methodOf(next, m.Name), true
+	}
+	return m, found, nil
+}
+
+func methodOf(t reflect.Type, name string) reflect.Method {
+	m, _ := t.MethodByName(name)
+	return m
+}
+
+// generated reports whether the function at pc was generated by the
+// compiler, like the wrappers of promoted methods.
+func generated(pc uintptr) bool {
+	f := runtime.FuncForPC(pc)
+	if f == nil {
+		return false
+	}
+	file, _ := f.FileLine(pc)
+	return file == "<autogenerated>"
+}
@@ -0,0 +1,80 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// ErrQuarantined is returned by the calls Quarantine refuses.
+var ErrQuarantined = errors.New("monkey: quarantined function called")
+
+// quarantined holds the quarantines by target.
+var quarantined sync.Map // uintptr -> *quarantine
+
+type quarantine struct {
+	t    testing.TB
+	name string
+	typ  reflect.Type
+}
+
+// Quarantine makes t fail, with the stack of the call, whenever the
+// original code of one of targets would run until t ends, on any goroutine:
+// functions a test must never reach, like net.Dial. Patches of the targets
+// still apply, as every other patch takes precedence over the quarantine,
+// and calls they hand on to the original are refused as well. Code behind
+// the patches of other functions reaches the targets through the
+// quarantine, so a test faking http.Get with a PatchReceiver covering a
+// single client fails if another client dials out.
+//
+// Refused calls return zero results and an error wrapping ErrQuarantined,
+// or panic with it when the target returns no error. Calls made by the
+// replacement of a target to the target itself, see AllowReentry, are not
+// refused.
+func Quarantine(t testing.TB, targets ...interface{}) {
+	t.Helper()
+	s := SessionT(t)
+	for _, target := range targets {
+		v := reflect.ValueOf(target)
+		if v.Kind() != reflect.Func {
+			t.Fatalf("monkey: cannot quarantine %T, it has to be a Func", target)
+		}
+		q := &quarantine{t: t, name: SymbolName(v.Pointer()), typ: v.Type()}
+		r := reflect.MakeFunc(v.Type(), func([]reflect.Value) []reflect.Value {
+			return q.refuse()
+		})
+		g, err := TryPatch(target, r.Interface(), ForLabels())
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Track(g)
+		pc := v.Pointer()
+		quarantined.Store(pc, q)
+		cleanup(t, func() { quarantined.Delete(pc) })
+	}
+}
+
+// refuse reports a call to the original of q and returns its results.
+func (q *quarantine) refuse() []reflect.Value {
+	err := fmt.Errorf("%w: %s", ErrQuarantined, q.name)
+	q.t.Errorf("%v, called by:\n%s", err, formatStack(callStack()))
+	n := q.typ.NumOut()
+	if n == 0 || q.typ.Out(n-1) != errorType {
+		panic(err)
+	}
+	out := zeroResults(q.typ)
+	out[n-1] = reflect.ValueOf(err)
+	return out
+}
+
+// checkQuarantine returns the results of a call to the original of the
+// function at pc if it is quarantined, or nil.
+func checkQuarantine(pc uintptr) []reflect.Value {
+	q, ok := quarantined.Load(pc)
+	if !ok {
+		return nil
+	}
+	return q.(*quarantine).refuse()
+}
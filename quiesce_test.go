@@ -0,0 +1,44 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import (
+	"testing"
+	"time"
+)
+
+//go:noinline
+func installing() bool { return false }
+
+func TestQuiesceWaitsForInstalls(t *testing.T) {
+	g := Patch(installing, func() bool { return true })
+	defer g.Unpatch()
+
+	// Make the patch look mid-install, as while its stub is being built.
+	ch := make(chan struct{})
+	lock.Lock()
+	p, _ := lookupPatch(g.target.Pointer())
+	p.installing = ch
+	lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		Quiesce()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Quiesce returned during an install")
+	case <-time.After(50 * time.Millisecond):
+	}
+	lock.Lock()
+	p.installing = nil
+	close(ch)
+	lock.Unlock()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Quiesce still waits once the install is done")
+	}
+}
@@ -0,0 +1,84 @@
+package monkey
+
+import (
+	crand "crypto/rand"
+	"math/rand"
+	"reflect"
+)
+
+// SeedRand makes the top level functions of math/rand, like rand.Intn, draw
+// from rand.New(rand.NewSource(seed)) on the calling goroutine, so that it
+// gets the same numbers on every run. Unpatch the returned session to go
+// back to the global source.
+func SeedRand(seed int64) *Session {
+	r := rand.New(rand.NewSource(seed))
+	s := NewSession()
+	for _, f := range [][2]interface{}{
+		{rand.Int63, r.Int63},
+		{rand.Uint32, r.Uint32},
+		{rand.Uint64, r.Uint64},
+		{rand.Int31, r.Int31},
+		{rand.Int, r.Int},
+		{rand.Int63n, r.Int63n},
+		{rand.Int31n, r.Int31n},
+		{rand.Intn, r.Intn},
+		{rand.Float64, r.Float64},
+		{rand.Float32, r.Float32},
+		{rand.Perm, r.Perm},
+		{rand.Shuffle, r.Shuffle},
+		{rand.NormFloat64, r.NormFloat64},
+		{rand.ExpFloat64, r.ExpFloat64},
+	} {
+		if !s.try(f[0], f[1]) {
+			break
+		}
+	}
+	return s
+}
+
+// SeedCryptoRand makes crypto/rand.Read and reads from crypto/rand.Reader
+// on the calling goroutine return bytes drawn from
+// rand.New(rand.NewSource(seed)). Obviously, only do this in tests.
+func SeedCryptoRand(seed int64) *Session {
+	r := rand.New(rand.NewSource(seed))
+	s := NewSession()
+	if !s.try(crand.Read, r.Read) {
+		return s
+	}
+
+	// The type of Reader is unexported, so the replacement of its Read
+	// method is made with reflect. Calls through the interface go to the
+	// wrapper generated for the pointer type if Read has a value receiver,
+	// so that one is patched too.
+	typ := reflect.TypeOf(crand.Reader)
+	types := []reflect.Type{typ}
+	if typ.Kind() != reflect.Ptr {
+		types = append(types, reflect.PtrTo(typ))
+	}
+	read := reflect.ValueOf(r.Read)
+	for _, typ := range types {
+		m, ok := typ.MethodByName("Read")
+		if !ok {
+			continue
+		}
+		replacement := reflect.MakeFunc(m.Type, func(in []reflect.Value) []reflect.Value {
+			return read.Call(in[1:])
+		})
+		if !s.try(m.Func.Interface(), replacement.Interface()) {
+			break
+		}
+	}
+	return s
+}
+
+// try patches target in s, unpatching s if it fails.
+func (s *Session) try(target, replacement interface{}) bool {
+	g, err := TryPatch(target, replacement)
+	if err != nil {
+		s.Unpatch()
+		check(err)
+		return false
+	}
+	s.Track(g)
+	return true
+}
@@ -0,0 +1,30 @@
+//go:build monkey_disabled
+// +build monkey_disabled
+
+package raw
+
+// Built with monkey_disabled: nothing in this package can write to the
+// text segment.
+
+// CopyToText always fails with ErrDisabled.
+func CopyToText(addr uintptr, data []byte) error {
+	return ErrDisabled
+}
+
+// WriteText always fails with ErrDisabled.
+func WriteText(addr uintptr, length int, write func([]byte)) error {
+	return ErrDisabled
+}
+
+func mapExec(length int) (uintptr, error) {
+	return 0, ErrDisabled
+}
+
+func protectHint(err error) string {
+	return ""
+}
+
+// SyncCores does nothing, there is never any new code to see.
+func SyncCores() error {
+	return nil
+}
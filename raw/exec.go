@@ -0,0 +1,52 @@
+package raw
+
+import "sync"
+
+// execChunk is the size of the executable memory requested at once.
+const execChunk = 64 << 10
+
+var (
+	execLock sync.Mutex
+	// execFree is what is left of the last chunk of executable memory.
+	execFree []byte
+	// execFreed holds the memory given back by FreeExecutable, by size.
+	execFreed = map[int][][]byte{}
+)
+
+// AllocExecutable returns n bytes of writable and executable memory,
+// aligned to 16 bytes. The memory is never unmapped, but can be handed out
+// again once given back with FreeExecutable.
+func AllocExecutable(n int) ([]byte, error) {
+	execLock.Lock()
+	defer execLock.Unlock()
+
+	n = (n + 15) &^ 15
+	if l := execFreed[n]; len(l) > 0 {
+		b := l[len(l)-1]
+		execFreed[n] = l[:len(l)-1]
+		return b, nil
+	}
+	if n > len(execFree) {
+		size := execChunk
+		if n > size {
+			size = n
+		}
+		addr, err := mapExec(size)
+		if err != nil {
+			return nil, err
+		}
+		execFree = Memory(addr, size)
+	}
+	b := execFree[:n:n]
+	execFree = execFree[n:]
+	return b, nil
+}
+
+// FreeExecutable gives b, returned by AllocExecutable, back to be reused.
+// No thread may run the code in b anymore, nor jump to it later.
+func FreeExecutable(b []byte) {
+	execLock.Lock()
+	defer execLock.Unlock()
+	b = b[:cap(b)]
+	execFreed[len(b)] = append(execFreed[len(b)], b)
+}
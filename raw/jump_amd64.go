@@ -0,0 +1,43 @@
+package raw
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// JmpSize is the length of the jump assembled by JmpStub.
+const JmpSize = 13
+
+// JmpStub assembles an absolute jump to the code at to. It clobbers r13,
+// which Go code doesn't expect to be preserved across calls.
+func JmpStub(to uintptr) []byte {
+	return []byte{
+		0x49, 0xBD,
+		byte(to),
+		byte(to >> 8),
+		byte(to >> 16),
+		byte(to >> 24),
+		byte(to >> 32),
+		byte(to >> 40),
+		byte(to >> 48),
+		byte(to >> 56),   // movabs r13,to
+		0x41, 0xFF, 0xE5, // jmp r13
+	}
+}
+
+// StoreJump writes jump over the prologue in b while other threads may be
+// calling the function. The first two bytes become a jump to itself, so a
+// thread entering the function meanwhile spins until the rest is written
+// instead of running a torn instruction. Being nosplit with no calls, the
+// sequence can't be preempted and leave the spinning threads waiting. Use
+// it as the write function of WriteText.
+//
+//go:nosplit
+func StoreJump(b, jump []byte) {
+	head := (*uint32)(unsafe.Pointer(&b[0]))
+	atomic.StoreUint32(head, uint32(0xFEEB)|uint32(b[2])<<16|uint32(b[3])<<24)
+	for i := 4; i < len(jump); i++ {
+		b[i] = jump[i]
+	}
+	atomic.StoreUint32(head, uint32(jump[0])|uint32(jump[1])<<8|uint32(jump[2])<<16|uint32(jump[3])<<24)
+}
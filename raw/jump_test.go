@@ -0,0 +1,38 @@
+//go:build go1.18
+// +build go1.18
+
+package raw_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-kiss/monkey/raw"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+func FuzzJmpStub(f *testing.F) {
+	f.Add(uint64(0), []byte{0x90})
+	f.Add(^uint64(0), bytes.Repeat([]byte{0xCC}, 32))
+	f.Fuzz(func(t *testing.T, to uint64, code []byte) {
+		jump := raw.JmpStub(uintptr(to))
+		if len(jump) != raw.JmpSize {
+			t.Fatalf("jump of %d bytes", len(jump))
+		}
+		mov, err := x86asm.Decode(jump, 64)
+		if err != nil || mov.Op != x86asm.MOV || mov.Args[0] != x86asm.R13 || mov.Args[1] != x86asm.Imm(to) {
+			t.Fatalf("jump starts with %v, %v", mov, err)
+		}
+		jmp, err := x86asm.Decode(jump[mov.Len:], 64)
+		if err != nil || jmp.Op != x86asm.JMP || jmp.Args[0] != x86asm.R13 || mov.Len+jmp.Len != len(jump) {
+			t.Fatalf("jump goes on with %v, %v", jmp, err)
+		}
+
+		want := append(append([]byte(nil), code...), make([]byte, raw.JmpSize)...)
+		b := append([]byte(nil), want...)
+		raw.StoreJump(b, jump)
+		if !bytes.Equal(b[:len(jump)], jump) || !bytes.Equal(b[len(jump):], want[len(jump):]) {
+			t.Fatalf("stored % x over % x", b, want)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package raw
+
+import (
+	"sync"
+	"syscall"
+)
+
+// pageSize is the size of the pages of memory, whose protection is changed
+// as a whole: 4K on most systems, 16K on Apple Silicon and 64K on some ARM
+// servers.
+var pageSize = uintptr(syscall.Getpagesize())
+
+// PageSize returns the size of the pages of memory, the unit in which code
+// is made writable.
+func PageSize() int {
+	return int(pageSize)
+}
+
+func pageStart(ptr uintptr) uintptr {
+	return ptr &^ (pageSize - 1)
+}
+
+// pages returns the start of every page holding some of the length bytes
+// at addr, which may straddle page boundaries.
+func pages(addr uintptr, length int) []uintptr {
+	var l []uintptr
+	for p := pageStart(addr); p < addr+uintptr(length); p += pageSize {
+		l = append(l, p)
+	}
+	return l
+}
+
+// pageLocks serializes the writes to each page of code, whose protection
+// one writer would otherwise take back while another is still writing.
+var pageLocks sync.Map // page address => *sync.Mutex
+
+// lockPages locks the pages holding the length bytes at addr, in address
+// order, and returns the function unlocking them.
+func lockPages(addr uintptr, length int) (unlock func()) {
+	var held []*sync.Mutex
+	for _, p := range pages(addr, length) {
+		m, _ := pageLocks.LoadOrStore(p, new(sync.Mutex))
+		mu := m.(*sync.Mutex)
+		mu.Lock()
+		held = append(held, mu)
+	}
+	return func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i].Unlock()
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// Package raw holds the platform specific building blocks of monkey:
+// writing to the text segment, allocating executable memory, encoding jumps
+// and finding functions by name. They know nothing of goroutines or
+// replacements, and are exported for other instrumentation tools to reuse.
+//
+// Everything here is unsafe: writing the wrong bytes to the wrong address
+// crashes the process, at best.
+package raw
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unsafe"
+)
+
+// ErrDisabled is returned by every function writing to memory when built
+// with the monkey_disabled tag.
+var ErrDisabled = errors.New("monkey: patching is compiled out by the monkey_disabled build tag")
+
+// ProtectError is returned when the protection of the memory holding code
+// or stubs can't be changed.
+type ProtectError struct {
+	Addr   uintptr
+	Length int
+	// Restoring tells the bytes were written, but their protection
+	// couldn't be set back afterwards.
+	Restoring bool
+	Err       error
+}
+
+func (e *ProtectError) Error() string {
+	if e.Restoring {
+		return fmt.Sprintf("monkey: cannot restore the protection of %d bytes at %#x: %v", e.Length, e.Addr, e.Err)
+	}
+	return fmt.Sprintf("monkey: cannot make %d bytes at %#x writable and executable: %v%s",
+		e.Length, e.Addr, e.Err, protectHint(e.Err))
+}
+
+func (e *ProtectError) Unwrap() error {
+	return e.Err
+}
+
+// Memory returns the length bytes at addr as a slice, without changing
+// their protection.
+func Memory(addr uintptr, length int) []byte {
+	var b []byte
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	h.Data = addr
+	h.Len = length
+	h.Cap = length
+	return b
+}
@@ -0,0 +1,58 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package raw_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
+)
+
+//go:noinline
+func answer() int { return 42 }
+
+func TestJmpStub(t *testing.T) {
+	b, err := raw.AllocExecutable(raw.JmpSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uintptr(unsafe.Pointer(&b[0]))%16 != 0 {
+		t.Fatal("unaligned")
+	}
+	to := reflect.ValueOf(answer).Pointer()
+	if err := raw.CopyToText(uintptr(unsafe.Pointer(&b[0])), raw.JmpStub(to)); err != nil {
+		t.Fatal(err)
+	}
+
+	code := uintptr(unsafe.Pointer(&b[0]))
+	f := *(*func() int)(unsafe.Pointer(&struct{ code *uintptr }{&code}))
+	if f() != 42 {
+		t.Fatal("jump not taken")
+	}
+}
+
+func TestLookupSymbol(t *testing.T) {
+	s, err := raw.LookupSymbol("github.com/go-kiss/monkey/raw_test.answer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Addr != reflect.ValueOf(answer).Pointer() || s.Size == 0 {
+		t.Fatal(s)
+	}
+	if _, err := raw.LookupSymbol("nope.nope"); !errors.Is(err, raw.ErrSymbolNotFound) {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncCores(t *testing.T) {
+	if err := raw.SyncCores(); err != nil {
+		t.Skip("not supported here:", err)
+	}
+	if err := raw.SyncCores(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+package raw
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// ErrSymbolNotFound is returned by LookupSymbol for unknown names.
+var ErrSymbolNotFound = errors.New("monkey: symbol not found")
+
+// Symbol is a function of the program.
+type Symbol struct {
+	// Addr is the entry address of the function.
+	Addr uintptr
+	// Size is the length of its code, up to the next function.
+	Size uintptr
+}
+
+var (
+	symbolsOnce sync.Once
+	symbols     map[string]Symbol
+)
+
+// LookupSymbol returns the function with the fully qualified name, e.g.
+// "net/http.(*Client).Do". Only functions kept by the linker can be found,
+// and stripped binaries work as well since the runtime's own function table
+// is searched.
+func LookupSymbol(name string) (Symbol, error) {
+	symbolsOnce.Do(loadSymbols)
+	s, ok := symbols[name]
+	if !ok {
+		return Symbol{}, fmt.Errorf("%w: %s", ErrSymbolNotFound, name)
+	}
+	return s, nil
+}
+
+// loadSymbols walks the function table of the main module from its last
+// function down to its first one.
+func loadSymbols() {
+	symbols = make(map[string]Symbol)
+
+	// Find the end of the text segment, every pc below it up to the
+	// first function belongs to some function.
+	hi := reflect.ValueOf(loadSymbols).Pointer()
+	step := uintptr(4096)
+	for runtime.FuncForPC(hi+step) != nil {
+		hi += step
+		step *= 2
+	}
+	for end := hi + step; end-hi > 1; {
+		m := hi + (end-hi)/2
+		if runtime.FuncForPC(m) != nil {
+			hi = m
+		} else {
+			end = m
+		}
+	}
+
+	next := hi + 1
+	for pc := hi; ; {
+		f := runtime.FuncForPC(pc)
+		if f == nil {
+			return
+		}
+		entry := f.Entry()
+		// Ask again at the entry to get the name of the outermost
+		// function rather than of something inlined at pc.
+		name := runtime.FuncForPC(entry).Name()
+		if _, ok := symbols[name]; !ok {
+			symbols[name] = Symbol{Addr: entry, Size: next - entry}
+		}
+		next = entry
+		pc = entry - 1
+	}
+}
@@ -0,0 +1,43 @@
+//go:build linux && !monkey_disabled
+// +build linux,!monkey_disabled
+
+package raw
+
+import (
+	"sync"
+	"syscall"
+)
+
+const (
+	sysMembarrier = 324 // amd64
+
+	membarrierCmdPrivateExpeditedSyncCore         = 1 << 5
+	membarrierCmdRegisterPrivateExpeditedSyncCore = 1 << 6
+)
+
+var registerSyncCore struct {
+	once sync.Once
+	err  error
+}
+
+func membarrier(cmd int) error {
+	if _, _, e := syscall.Syscall(sysMembarrier, uintptr(cmd), 0, 0); e != 0 {
+		return e
+	}
+	return nil
+}
+
+// SyncCores makes every thread of the process run a serializing instruction
+// before it goes on, so that none of them still runs code from before the
+// last write to the text segment. It uses membarrier(2), which kernels older
+// than 4.16 lack.
+func SyncCores() error {
+	r := &registerSyncCore
+	r.once.Do(func() {
+		r.err = membarrier(membarrierCmdRegisterPrivateExpeditedSyncCore)
+	})
+	if r.err != nil {
+		return r.err
+	}
+	return membarrier(membarrierCmdPrivateExpeditedSyncCore)
+}
@@ -0,0 +1,12 @@
+//go:build !linux && !windows && !monkey_disabled
+// +build !linux,!windows,!monkey_disabled
+
+package raw
+
+// SyncCores does nothing: the platform has no way to interrupt every thread
+// of the process. Threads still running code from before the last write to
+// the text segment see the new code at their next serializing instruction,
+// at the latest when they are next scheduled.
+func SyncCores() error {
+	return nil
+}
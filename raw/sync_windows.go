@@ -0,0 +1,15 @@
+//go:build windows && !monkey_disabled
+// +build windows,!monkey_disabled
+
+package raw
+
+var procFlushProcessWriteBuffers = kernel32.NewProc("FlushProcessWriteBuffers")
+
+// SyncCores makes every thread of the process run a serializing instruction
+// before it goes on, so that none of them still runs code from before the
+// last write to the text segment. It uses FlushProcessWriteBuffers, which
+// interrupts every processor running a thread of the process.
+func SyncCores() error {
+	procFlushProcessWriteBuffers.Call()
+	return nil
+}
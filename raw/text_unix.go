@@ -0,0 +1,68 @@
+//go:build !windows && !monkey_disabled
+// +build !windows,!monkey_disabled
+
+package raw
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// protect sets the protection of the pages holding the length bytes at
+// addr to prot, one page at a time, as they may belong to different
+// mappings. If a page fails, those already changed are set to undo.
+func protect(addr uintptr, length int, prot, undo int) error {
+	l := pages(addr, length)
+	for i, p := range l {
+		if err := syscall.Mprotect(Memory(p, int(pageSize)), prot); err != nil {
+			for _, q := range l[:i] {
+				syscall.Mprotect(Memory(q, int(pageSize)), undo)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func protectHint(err error) string {
+	if err == syscall.EACCES || err == syscall.EPERM {
+		return " (writable executable memory is denied here, seccomp filters, SELinux execmem/execmod policies, PaX MPROTECT and sandboxes like gVisor commonly do this)"
+	}
+	return ""
+}
+
+// CopyToText copies data to addr, making the memory there writable for the
+// time of the copy.
+func CopyToText(addr uintptr, data []byte) error {
+	return WriteText(addr, len(data), func(f []byte) {
+		copy(f, data[:])
+	})
+}
+
+// WriteText lets write modify the length bytes of code at addr, which are
+// writable only during the call. Other threads may be running the code
+// meanwhile, write has to change it so that they never run a torn
+// instruction, see StoreJump. Writes to the same page are serialized.
+func WriteText(addr uintptr, length int, write func([]byte)) error {
+	defer lockPages(addr, length)()
+	f := Memory(addr, length)
+
+	const rx = syscall.PROT_READ | syscall.PROT_EXEC
+	if err := protect(addr, length, rx|syscall.PROT_WRITE, rx); err != nil {
+		return &ProtectError{Addr: addr, Length: length, Err: err}
+	}
+	write(f)
+	if err := protect(addr, length, rx, rx); err != nil {
+		return &ProtectError{Addr: addr, Length: length, Restoring: true, Err: err}
+	}
+	return nil
+}
+
+// mapExec maps length bytes of writable and executable memory.
+func mapExec(length int) (uintptr, error) {
+	b, err := syscall.Mmap(-1, 0, length, syscall.PROT_READ|syscall.PROT_WRITE|syscall.PROT_EXEC, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		return 0, &ProtectError{Length: length, Err: err}
+	}
+	return uintptr(unsafe.Pointer(&b[0])), nil
+}
@@ -0,0 +1,47 @@
+//go:build !windows && !monkey_disabled
+// +build !windows,!monkey_disabled
+
+package raw
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+func TestWriteTextAcrossPages(t *testing.T) {
+	size := PageSize()
+	if size&(size-1) != 0 || size < 4096 {
+		t.Fatal("page size", size)
+	}
+	b, err := syscall.Mmap(-1, 0, 2*size, syscall.PROT_READ|syscall.PROT_EXEC, syscall.MAP_PRIVATE|syscall.MAP_ANON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Syscall(syscall.SYS_MUNMAP, uintptr(unsafe.Pointer(&b[0])), uintptr(2*size), 0)
+	addr := uintptr(unsafe.Pointer(&b[0]))
+
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	if err := CopyToText(addr+uintptr(size)-4, data); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[size-4:size+4], data) {
+		t.Fatal("not written", b[size-4:size+4])
+	}
+
+	// The second page is gone: the write fails without touching the
+	// first one.
+	if _, _, e := syscall.Syscall(syscall.SYS_MUNMAP, addr+uintptr(size), uintptr(size), 0); e != 0 {
+		t.Fatal(e)
+	}
+	err = CopyToText(addr+uintptr(size)-4, make([]byte, 8))
+	var perr *ProtectError
+	if !errors.As(err, &perr) || perr.Restoring || perr.Addr != addr+uintptr(size)-4 {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[size-4:size], data[:4]) {
+		t.Fatal("written", b[size-4:size])
+	}
+}
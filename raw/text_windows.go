@@ -0,0 +1,101 @@
+//go:build windows && !monkey_disabled
+// +build windows,!monkey_disabled
+
+package raw
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// PAGE_EXECUTE_READWRITE is the protection of memory written by
+	// WriteText or returned by AllocExecutable.
+	PAGE_EXECUTE_READWRITE = 0x40
+
+	memCommit  = 0x1000
+	memReserve = 0x2000
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procVirtualProtect = kernel32.NewProc("VirtualProtect")
+	procVirtualAlloc   = kernel32.NewProc("VirtualAlloc")
+)
+
+func virtualProtect(lpAddress uintptr, dwSize int, flNewProtect uint32, lpflOldProtect unsafe.Pointer) error {
+	ret, _, _ := procVirtualProtect.Call(
+		lpAddress,
+		uintptr(dwSize),
+		uintptr(flNewProtect),
+		uintptr(lpflOldProtect))
+	if ret == 0 {
+		return syscall.GetLastError()
+	}
+	return nil
+}
+
+// CopyToText copies data to addr, making the memory there writable for the
+// time of the copy.
+func CopyToText(addr uintptr, data []byte) error {
+	return WriteText(addr, len(data), func(f []byte) {
+		copy(f, data[:])
+	})
+}
+
+// WriteText lets write modify the length bytes of code at addr, which are
+// writable only during the call. Other threads may be running the code
+// meanwhile, write has to change it so that they never run a torn
+// instruction, see StoreJump. Writes to the same page are serialized.
+func WriteText(addr uintptr, length int, write func([]byte)) error {
+	defer lockPages(addr, length)()
+	f := Memory(addr, length)
+
+	// Pages may belong to different allocations with different
+	// protections, each is set back to its own.
+	l := pages(addr, length)
+	old := make([]uint32, len(l))
+	for i, p := range l {
+		if err := virtualProtect(p, int(pageSize), PAGE_EXECUTE_READWRITE, unsafe.Pointer(&old[i])); err != nil {
+			restoreProtection(l[:i], old)
+			return &ProtectError{Addr: addr, Length: length, Err: err}
+		}
+	}
+	write(f)
+	if err := restoreProtection(l, old); err != nil {
+		return &ProtectError{Addr: addr, Length: length, Restoring: true, Err: err}
+	}
+	return nil
+}
+
+// restoreProtection sets the protection of the pages l back to old, and
+// returns the first error.
+func restoreProtection(l []uintptr, old []uint32) error {
+	var first error
+	for i, p := range l {
+		// VirtualProtect requires you to pass in a pointer which it can
+		// write the current memory protection permissions to, even if
+		// you don't want them.
+		var tmp uint32
+		if err := virtualProtect(p, int(pageSize), old[i], unsafe.Pointer(&tmp)); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// mapExec allocates length bytes of writable and executable memory.
+func mapExec(length int) (uintptr, error) {
+	addr, _, _ := procVirtualAlloc.Call(0, uintptr(length), memCommit|memReserve, PAGE_EXECUTE_READWRITE)
+	if addr == 0 {
+		return 0, &ProtectError{Length: length, Err: syscall.GetLastError()}
+	}
+	return addr, nil
+}
+
+func protectHint(err error) string {
+	if err == syscall.ERROR_ACCESS_DENIED {
+		return " (dynamic code is denied here, e.g. by Arbitrary Code Guard)"
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// PatchReceiver replaces the method methodName of the type of instance with
+// replacement, only for calls made on instance itself. Calls on any other
+// receiver run the original method. Pointers are matched by address, other
+// receivers by ==. As with PatchInstanceMethod, replacement expects the
+// receiver as the first argument.
+func PatchReceiver(instance interface{}, methodName string, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatchReceiver(instance, methodName, replacement, opts...)
+	if err != nil {
+		return inertMethodGuard(reflect.TypeOf(instance), methodName, replacement, err)
+	}
+	return g
+}
+
+// TryPatchReceiver is like PatchReceiver but returns an error instead of
+// panicking.
+func TryPatchReceiver(instance interface{}, methodName string, replacement interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	v := reflect.ValueOf(instance)
+	if !v.IsValid() {
+		return nil, errors.New("monkey: nil instance")
+	}
+	if v.Kind() != reflect.Ptr && !v.Type().Comparable() {
+		return nil, fmt.Errorf("monkey: receivers of type %s cannot be compared", v.Type())
+	}
+	return patchReceivers(v.Type(), methodName, func(recv reflect.Value) bool {
+		if v.Kind() == reflect.Ptr {
+			return recv.Pointer() == v.Pointer()
+		}
+		return recv.Interface() == instance
+	}, reflect.ValueOf(replacement), opts)
+}
+
+// PatchReceiverMatching replaces the method methodName of the type target
+// with replacement for the receivers match returns true for. match is a
+// func(recv target) bool, e.g. to patch (*http.Client).Do only for the
+// clients of one API. Calls on other receivers run the original method.
+func PatchReceiverMatching(target reflect.Type, methodName string, match, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatchReceiverMatching(target, methodName, match, replacement, opts...)
+	if err != nil {
+		return inertMethodGuard(target, methodName, replacement, err)
+	}
+	return g
+}
+
+// TryPatchReceiverMatching is like PatchReceiverMatching but returns an
+// error instead of panicking.
+func TryPatchReceiverMatching(target reflect.Type, methodName string, match, replacement interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	m := reflect.ValueOf(match)
+	want := reflect.FuncOf([]reflect.Type{target}, []reflect.Type{reflect.TypeOf(true)}, false)
+	if m.Kind() != reflect.Func || m.Type() != want {
+		return nil, fmt.Errorf("match has to be a %s, not %T", want, match)
+	}
+	return patchReceivers(target, methodName, func(recv reflect.Value) bool {
+		return m.Call([]reflect.Value{recv})[0].Bool()
+	}, reflect.ValueOf(replacement), opts)
+}
+
+// patchReceivers patches the method methodName of target to call
+// replacement for the receivers match holds for, and the original method
+// for the others.
+func patchReceivers(target reflect.Type, methodName string, match func(reflect.Value) bool, replacement reflect.Value, opts []PatchOption) (*PatchGuard, error) {
+	m, ok := target.MethodByName(methodName)
+	if !ok {
+		return nil, fmt.Errorf("unknown method %s", methodName)
+	}
+	if replacement.Kind() != reflect.Func {
+		return nil, errors.New("replacement has to be a Func")
+	}
+	if replacement.Type() != m.Type {
+		return nil, fmt.Errorf("target and replacement have to have the same type %s != %s, target is %s", m.Type, replacement.Type(), describeFunc(m.Func.Pointer(), m.Type))
+	}
+
+	var g *PatchGuard
+	r := reflect.MakeFunc(m.Type, func(in []reflect.Value) []reflect.Value {
+		if match(in[0]) {
+			return call(replacement, in)
+		}
+		return handOn(g, in)
+	})
+	g, err := patchGuard(m.Func, r, opts)
+	if err != nil {
+		return nil, err
+	}
+	g.replacement = replacement
+	return g, nil
+}
+
+// inertMethodGuard is inertGuard for the method methodName of target.
+func inertMethodGuard(target reflect.Type, methodName string, replacement interface{}, err error) *PatchGuard {
+	var f reflect.Value
+	if target != nil {
+		if m, ok := target.MethodByName(methodName); ok {
+			f = m.Func
+		}
+	}
+	return inertGuard(f, reflect.ValueOf(replacement), err)
+}
+
+// PatchImplementation patches the method of the type implementing an
+// interface, for tests that only get the interface value. iface names the
+// interface, as a pointer to it like (*io.Reader)(nil), and concrete is the
+// value stored in it, whose dynamic type has its method patched as with
+// PatchInstanceMethod. replacement expects that type as the first argument.
+// Every value of the type is affected, see PatchReceiver to patch concrete
+// alone.
+func PatchImplementation(iface, concrete interface{}, method string, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatchImplementation(iface, concrete, method, replacement, opts...)
+	if err != nil {
+		return inertMethodGuard(reflect.TypeOf(concrete), method, replacement, err)
+	}
+	return g
+}
+
+// TryPatchImplementation is like PatchImplementation but returns an error
+// instead of panicking.
+func TryPatchImplementation(iface, concrete interface{}, method string, replacement interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	it := reflect.TypeOf(iface)
+	if it == nil || it.Kind() != reflect.Ptr || it.Elem().Kind() != reflect.Interface {
+		return nil, fmt.Errorf("monkey: iface has to be a pointer to an interface, not %T", iface)
+	}
+	it = it.Elem()
+	if _, ok := it.MethodByName(method); !ok {
+		return nil, fmt.Errorf("monkey: %s has no method %s", it, method)
+	}
+	t := reflect.TypeOf(concrete)
+	if t == nil {
+		return nil, fmt.Errorf("monkey: nil %s has no implementation to patch", it)
+	}
+	if !t.Implements(it) {
+		return nil, fmt.Errorf("monkey: %s does not implement %s", t, it)
+	}
+	return TryPatchInstanceMethod(t, method, replacement, opts...)
+}
@@ -0,0 +1,61 @@
+package monkey
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Call is a call recorded by a patch made with the Record option.
+type Call struct {
+	Args []reflect.Value
+	// Results is nil if the replacement panicked.
+	Results []reflect.Value
+}
+
+// Record makes the patch record the arguments and results of every call to
+// the replacement, see PatchGuard.Calls.
+func Record() PatchOption {
+	return func(c *patchConfig) {
+		c.record = true
+	}
+}
+
+type recorder struct {
+	lock  sync.Mutex
+	calls []Call
+}
+
+// wrap returns fn recording its calls in r.
+func (r *recorder) wrap(fn reflect.Value) reflect.Value {
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		return r.record(in, func(in []reflect.Value) []reflect.Value {
+			return call(fn, in)
+		})
+	})
+}
+
+// record runs a call with the arguments in through run, recording it in r.
+func (r *recorder) record(in []reflect.Value, run func([]reflect.Value) []reflect.Value) []reflect.Value {
+	r.lock.Lock()
+	i := len(r.calls)
+	r.calls = append(r.calls, Call{Args: in})
+	r.lock.Unlock()
+
+	out := run(in)
+
+	r.lock.Lock()
+	r.calls[i].Results = out
+	r.lock.Unlock()
+	return out
+}
+
+// Calls returns the calls recorded so far if the patch was made with
+// Record, oldest first.
+func (g *PatchGuard) Calls() []Call {
+	if g.recorder == nil {
+		return nil
+	}
+	g.recorder.lock.Lock()
+	defer g.recorder.lock.Unlock()
+	return append([]Call(nil), g.recorder.calls...)
+}
@@ -0,0 +1,42 @@
+//go:build go1.18
+// +build go1.18
+
+package monkey
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Calls returns the arguments of the calls recorded by g, see Record, as
+// values of type T. T is either a struct with one field per argument, in
+// order, or the type of the only argument:
+//
+//	g := monkey.Patch(http.Get, fakeGet, monkey.Record())
+//	...
+//	for _, c := range monkey.Calls[struct{ URL string }](g) {
+//		...
+//	}
+//
+// Calls panics if the arguments can't be stored in T.
+func Calls[T any](g *PatchGuard) []T {
+	calls := g.Calls()
+	r := make([]T, len(calls))
+	for i, c := range calls {
+		v := reflect.ValueOf(&r[i]).Elem()
+		if v.Kind() == reflect.Struct && v.NumField() == len(c.Args) {
+			for j, a := range c.Args {
+				if !a.Type().AssignableTo(v.Field(j).Type()) {
+					panic(fmt.Sprintf("monkey: argument %d of type %s can't be stored in field %s of %s", j, a.Type(), v.Type().Field(j).Name, v.Type()))
+				}
+				v.Field(j).Set(a)
+			}
+			continue
+		}
+		if len(c.Args) != 1 || !c.Args[0].Type().AssignableTo(v.Type()) {
+			panic(fmt.Sprintf("monkey: arguments of %s can't be stored in %s", g.target.Type(), v.Type()))
+		}
+		v.Set(c.Args[0])
+	}
+	return r
+}
@@ -0,0 +1,29 @@
+//go:build go1.18 && !monkey_disabled
+// +build go1.18,!monkey_disabled
+
+package monkey_test
+
+import (
+	"testing"
+
+	"github.com/go-kiss/monkey"
+)
+
+func TestCalls(t *testing.T) {
+	g := monkey.Patch(store, func(key, value string) error { return nil }, monkey.Record())
+	defer g.Unpatch()
+	store("a", "1")
+	store("b", "2")
+
+	calls := monkey.Calls[struct{ Key, Value string }](g)
+	assert(t, len(calls) == 2, calls)
+	assert(t, calls[0].Key == "a" && calls[0].Value == "1", calls)
+	assert(t, calls[1].Key == "b" && calls[1].Value == "2", calls)
+	assert(t, len(g.Calls()[1].Results) == 1)
+
+	f := monkey.Patch(fetch, func(string) (string, error) { return "", nil }, monkey.Record())
+	defer f.Unpatch()
+	fetch("k")
+	assert(t, monkey.Calls[string](f)[0] == "k")
+	panics(t, func() { monkey.Calls[int](f) })
+}
@@ -0,0 +1,47 @@
+package monkey
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// AllowReentry lets calls to the target made while the replacement runs on
+// the same goroutine, directly or through other functions, go to the
+// replacement again. By default they go to the original function, so that
+// e.g. a replacement of a logging function can log without recursing
+// forever. Allowing reentry also saves the cost of the guard, a few
+// nanoseconds a call, except for patches shared by every goroutine and for
+// functions passing arguments or results on the stack, whose calls it makes
+// through reflect, adding its frames to the stack traces taken in the
+// replacement.
+func AllowReentry() PatchOption {
+	return func(c *patchConfig) {
+		c.reentrant = true
+	}
+}
+
+// guardReentry returns fn marking e busy while it runs, for the calls
+// guardCall can't make.
+func (e *entry) guardReentry(fn reflect.Value) reflect.Value {
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		busy := atomic.SwapUint32(&e.busy, 2)
+		defer atomic.StoreUint32(&e.busy, busy)
+		return call(fn, in)
+	})
+}
+
+// guardSharedReentry is guardReentry for entries shared by every goroutine,
+// whose busy field would turn the replacement off for all of them. The
+// goroutines running the replacement are tracked instead.
+func (pg *PatchGuard) guardSharedReentry(fn reflect.Value) reflect.Value {
+	var busy sync.Map
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		gid := curG()
+		if _, ok := busy.LoadOrStore(gid, true); ok {
+			return passOn(pg, in)
+		}
+		defer busy.Delete(gid)
+		return call(fn, in)
+	})
+}
@@ -0,0 +1,109 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-kiss/monkey/raw"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+const (
+	// guardSpill is the room guardCall leaves for the replacement to spill
+	// the registers it takes its arguments in, 9 integer and 15 floating
+	// point ones at most, and guardFrame the size of its frame, which
+	// holds the entry above them.
+	guardSpill = (9 + 15) * 8
+	guardFrame = guardSpill + 8
+	// guardStack is the stack the dispatcher makes sure is left to
+	// guardCall, which doesn't check it: its frame, the frame pointer, and
+	// the return addresses of the replacement and of morestack.
+	guardStack = guardFrame + 3*8
+)
+
+// guardCall calls the replacement of an entry guarded against reentry,
+// with busy set, so that the calls the replacement makes to the target go
+// to the original function. It sits between the caller of the target and
+// the replacement, where the dispatcher can't: tracebacks taken meanwhile
+// have to find Go code there. The dispatcher jumps to it, see dispatcher.
+//
+// Arguments and results are left where they are, so only replacements
+// taking and returning all of them in registers go through it, see
+// inRegisters. A replacement panicking leaves busy set, which the
+// dispatcher then tells apart by looking for guardCall in the frames of
+// the caller.
+func guardCall()
+
+// guardCallPC is the address of guardCall itself, where the value of
+// guardCall would call a wrapper for the register ABI.
+var guardCallPC uintptr
+
+// guardReturn returns the address guardCall calls the replacement from.
+func guardReturn() (uintptr, error) {
+	code := raw.Memory(guardCallPC, 64)
+	for s := 0; s < len(code); {
+		i, err := x86asm.Decode(code[s:], 64)
+		if err != nil {
+			return 0, fmt.Errorf("monkey: decoding guardCall: %w", err)
+		}
+		s += i.Len
+		if i.Op == x86asm.CALL {
+			return guardCallPC + uintptr(s), nil
+		}
+	}
+	return 0, errors.New("monkey: guardCall calls nothing")
+}
+
+// inRegisters tells whether Go functions of type t take all their arguments
+// and return all their results in registers, which only happens under the
+// register ABI.
+func inRegisters(t reflect.Type) bool {
+	if !registerABI {
+		return false
+	}
+	ints, floats := 0, 0
+	for i := 0; i < t.NumIn(); i++ {
+		if !assign(t.In(i), &ints, &floats) {
+			return false
+		}
+	}
+	ints, floats = 0, 0
+	for i := 0; i < t.NumOut(); i++ {
+		if !assign(t.Out(i), &ints, &floats) {
+			return false
+		}
+	}
+	return true
+}
+
+// assign adds the integer and floating point registers a value of type t
+// takes to ints and floats, and tells whether there are enough.
+func assign(t reflect.Type, ints, floats *int) bool {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		*floats++
+	case reflect.Complex64, reflect.Complex128:
+		*floats += 2
+	case reflect.String, reflect.Interface:
+		*ints += 2
+	case reflect.Slice:
+		*ints += 3
+	case reflect.Array:
+		if t.Len() > 1 {
+			return false
+		}
+		if t.Len() == 1 {
+			return assign(t.Elem(), ints, floats)
+		}
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !assign(t.Field(i).Type, ints, floats) {
+				return false
+			}
+		}
+	default:
+		*ints++
+	}
+	return *ints <= 9 && *floats <= 15
+}
@@ -0,0 +1,22 @@
+#include "go_asm.h"
+#include "textflag.h"
+#include "funcdata.h"
+
+// guardCall is where the dispatcher sends the calls to replacements guarded
+// against reentry, see reentry_amd64.go. The dispatcher leaves the entry
+// below the word the frame pointer is pushed to, where it is the highest
+// word of the frame, and the replacement in DX. The arguments are in
+// registers: only their spill area is reserved at the bottom of the frame.
+//
+// The function is a wrapper so that tracebacks leave it out.
+TEXT ·guardCall(SB), NOSPLIT|WRAPPER, $const_guardFrame-0
+	NO_LOCAL_POINTERS
+	MOVQ const_guardSpill(SP), R12
+	MOVL $1, entry_busy(R12)
+	CALL (DX)
+	MOVQ const_guardSpill(SP), R12
+	MOVL $0, entry_busy(R12)
+	RET
+
+DATA ·guardCallPC+0(SB)/8, $·guardCall(SB)
+GLOBL ·guardCallPC(SB), RODATA, $8
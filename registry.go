@@ -0,0 +1,92 @@
+package monkey
+
+import "sync/atomic"
+
+// registryMaps holds the patches by target and the aliases, see
+// FollowWrapper. Neither map is ever modified: changes, made under lock,
+// store a modified copy in registry, so that introspection can read them
+// without taking lock.
+type registryMaps struct {
+	patches map[uintptr]*patch
+	// aliases maps targets that were followed to the function patched
+	// in their place.
+	aliases map[uintptr]uintptr
+}
+
+var registry atomic.Value // *registryMaps
+
+func init() {
+	registry.Store(&registryMaps{})
+}
+
+func loadRegistry() *registryMaps {
+	return registry.Load().(*registryMaps)
+}
+
+// lookup returns the patch of the function target, or of the function it
+// was followed to.
+func (r *registryMaps) lookup(target uintptr) (*patch, bool) {
+	if to, ok := r.aliases[target]; ok {
+		target = to
+	}
+	p, ok := r.patches[target]
+	return p, ok
+}
+
+// lookupPatch is lookup on the current registry.
+func lookupPatch(target uintptr) (*patch, bool) {
+	return loadRegistry().lookup(target)
+}
+
+// setPatch registers p as the patch of its target. lock must be held.
+func setPatch(p *patch) {
+	r := loadRegistry()
+	m := make(map[uintptr]*patch, len(r.patches)+1)
+	for from, p := range r.patches {
+		m[from] = p
+	}
+	m[p.from] = p
+	registry.Store(&registryMaps{patches: m, aliases: r.aliases})
+}
+
+// setAlias makes target lead to the patch of from. lock must be held.
+func setAlias(target, from uintptr) {
+	r := loadRegistry()
+	if r.aliases[target] == from {
+		return
+	}
+	m := make(map[uintptr]uintptr, len(r.aliases)+1)
+	for t, f := range r.aliases {
+		m[t] = f
+	}
+	m[target] = from
+	registry.Store(&registryMaps{patches: r.patches, aliases: m})
+}
+
+// entries returns the entries of p by goroutine, a map never modified, see
+// registryMaps.
+func (p *patch) entries() map[uintptr]*entry {
+	m, _ := p.byG.Load().(map[uintptr]*entry)
+	return m
+}
+
+// setEntries replaces the entries of p. lock must be held.
+func (p *patch) setEntries(m map[uintptr]*entry) {
+	p.byG.Store(m)
+}
+
+// withEntry returns a copy of the entries of p, with e for gid, or without
+// gid when e is nil.
+func (p *patch) withEntry(gid uintptr, e *entry) map[uintptr]*entry {
+	old := p.entries()
+	m := make(map[uintptr]*entry, len(old)+1)
+	for g, e := range old {
+		m[g] = e
+	}
+	if e != nil {
+		m[gid] = e
+	} else {
+		delete(m, gid)
+	}
+	return m
+}
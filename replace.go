@@ -1,19 +1,7 @@
 package monkey
 
-import (
-	"reflect"
-	"syscall"
-	"unsafe"
-)
+import "github.com/go-kiss/monkey/raw"
 
-func rawMemoryAccess(p uintptr, length int) []byte {
-	return *(*[]byte)(unsafe.Pointer(&reflect.SliceHeader{
-		Data: p,
-		Len:  length,
-		Cap:  length,
-	}))
-}
-
-func pageStart(ptr uintptr) uintptr {
-	return ptr & ^(uintptr(syscall.Getpagesize() - 1))
-}
+// ProtectError is returned when the protection of the memory holding code
+// or stubs can't be changed.
+type ProtectError = raw.ProtectError
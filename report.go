@@ -0,0 +1,124 @@
+package monkey
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync/atomic"
+)
+
+// ReportEntry describes how a patched function was used during the run.
+type ReportEntry struct {
+	// Func is the fully qualified name of the patched function.
+	Func string `json:"func"`
+	// Patches is the number of replacements installed on Func.
+	Patches int `json:"patches"`
+	// Calls is the number of calls served by replacements instead of Func.
+	Calls uint64 `json:"calls"`
+	// Goroutines is the number of goroutines Func is patched for now,
+	// PeakGoroutines the highest it has been.
+	Goroutines     int `json:"goroutines"`
+	PeakGoroutines int `json:"peak_goroutines"`
+}
+
+// Report returns every function patched so far, sorted by name.
+// Functions only patched with PatchEmpty are left out. It doesn't wait for
+// patches being made or removed, the calls of those may be counted twice or
+// not at all.
+func Report() []ReportEntry {
+	var r []ReportEntry
+	for from, p := range loadRegistry().patches {
+		installs := atomic.LoadUint64(&p.installs)
+		if installs == 0 {
+			continue
+		}
+		entries := p.entries()
+		calls := atomic.LoadUint64(&p.calls)
+		for _, e := range entries {
+			calls += atomic.LoadUint64(&e.calls) - atomic.LoadUint64(&e.folded)
+		}
+		r = append(r, ReportEntry{
+			Func:           SymbolName(from),
+			Patches:        int(installs),
+			Calls:          calls,
+			Goroutines:     len(entries),
+			PeakGoroutines: int(atomic.LoadUint64(&p.peak)),
+		})
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Func < r[j].Func })
+	return r
+}
+
+// PatchInfo describes a function patched now, see ListPatches.
+type PatchInfo struct {
+	Func string
+	// Signature is the type of Func, with the names of its parameters
+	// when the binary has DWARF.
+	Signature string
+	// Position is where Func is declared as "file:line", when the binary
+	// has DWARF.
+	Position string
+	// Goroutines is the number of goroutines Func is patched for.
+	Goroutines int
+	// CreationStacks holds the stack each of those patches was made
+	// from, see PatchGuard.CreationStack.
+	CreationStacks []string
+}
+
+func (i PatchInfo) String() string {
+	s := i.Func + " " + i.Signature
+	if i.Position != "" {
+		s += " declared at " + i.Position
+	}
+	return fmt.Sprintf("%s, patched for %d goroutines", s, i.Goroutines)
+}
+
+// ListPatches returns the functions patched for at least one goroutine,
+// sorted by name.
+func ListPatches() []PatchInfo {
+	var l []PatchInfo
+	var types []reflect.Type
+	for from, p := range loadRegistry().patches {
+		entries := p.entries()
+		if len(entries) == 0 {
+			continue
+		}
+		info := PatchInfo{Func: SymbolName(from), Goroutines: len(entries)}
+		var typ reflect.Type
+		for gid, e := range entries {
+			typ = e.fn.Type()
+			if gid != anyG {
+				info.CreationStacks = append(info.CreationStacks, formatStack(e.stack))
+			}
+		}
+		// The entry under anyG is made by no guard, list those of the
+		// patches it resolves to.
+		for _, g := range p.sharedGuards() {
+			info.CreationStacks = append(info.CreationStacks, formatStack(g.stack))
+		}
+		l = append(l, info)
+		types = append(types, typ)
+	}
+
+	for i := range l {
+		l[i].Signature = types[i].String()
+		if f, err := lookupDWARF(l[i].Func); err == nil {
+			l[i].Signature, l[i].Position = f.String(), f.Position()
+		}
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i].Func < l[j].Func })
+	return l
+}
+
+// WriteReport writes Report to w as a JSON array.
+func WriteReport(w io.Writer) error {
+	r := Report()
+	if r == nil {
+		r = []ReportEntry{}
+	}
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(r)
+}
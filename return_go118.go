@@ -0,0 +1,90 @@
+//go:build go1.18
+// +build go1.18
+
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Return patches target, a function of any arguments with the single result
+// T, to return val:
+//
+//	monkey.Return(time.Now, time.Unix(0, 0))
+//
+// T is taken from val, or has to be given for an untyped nil. Like Patch, it
+// panics or logs if the results of target aren't T, see
+// Options.PanicOnError.
+func Return[T any, F any](target F, val T, opts ...PatchOption) *PatchGuard {
+	return patchReturn(target, []reflect.Type{typeOf[T]()}, []interface{}{val}, opts)
+}
+
+// Return2 patches target, a function of any arguments with the results
+// (T, error), to return val and err:
+//
+//	monkey.Return2(strconv.Atoi, 42, nil)
+//	monkey.Return2[*os.File](os.Open, nil, fs.ErrNotExist)
+func Return2[T any, F any](target F, val T, err error, opts ...PatchOption) *PatchGuard {
+	return patchReturn(target, []reflect.Type{typeOf[T](), errorType}, []interface{}{val, err}, opts)
+}
+
+// Return3 patches target, a function of any arguments with the results
+// (T, U, error), to return val1, val2 and err:
+//
+//	monkey.Return3(net.SplitHostPort, "localhost", "80", nil)
+func Return3[T, U any, F any](target F, val1 T, val2 U, err error, opts ...PatchOption) *PatchGuard {
+	return patchReturn(target, []reflect.Type{typeOf[T](), typeOf[U](), errorType}, []interface{}{val1, val2, err}, opts)
+}
+
+// ReturnError patches target, a function of any arguments with the single
+// result error, to return err.
+func ReturnError[F any](target F, err error, opts ...PatchOption) *PatchGuard {
+	return patchReturn(target, []reflect.Type{errorType}, []interface{}{err}, opts)
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// patchReturn patches target to return vals, of the types want.
+func patchReturn(target interface{}, want []reflect.Type, vals []interface{}, opts []PatchOption) *PatchGuard {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return inertGuard(t, reflect.Value{}, patchError("patch", fmt.Sprint(target), errors.New("target has to be a Func")))
+	}
+	out, err := returnValues(t.Type(), want, vals)
+	if err != nil {
+		return inertGuard(t, reflect.Value{}, patchError("patch", SymbolName(t.Pointer()), err))
+	}
+	r := reflect.MakeFunc(t.Type(), func([]reflect.Value) []reflect.Value { return out })
+	return Patch(target, r.Interface(), opts...)
+}
+
+// returnValues converts vals to the results of a function of type typ.
+func returnValues(typ reflect.Type, want []reflect.Type, vals []interface{}) ([]reflect.Value, error) {
+	if typ.NumOut() != len(want) {
+		return nil, fmt.Errorf("%s doesn't return (%s)", typ, typeList(want))
+	}
+	out := make([]reflect.Value, len(want))
+	for i, w := range want {
+		if !w.AssignableTo(typ.Out(i)) {
+			return nil, fmt.Errorf("%s doesn't return (%s)", typ, typeList(want))
+		}
+		out[i] = reflect.New(typ.Out(i)).Elem()
+		if vals[i] != nil {
+			out[i].Set(reflect.ValueOf(vals[i]))
+		}
+	}
+	return out, nil
+}
+
+func typeList(types []reflect.Type) string {
+	s := make([]string, len(types))
+	for i, t := range types {
+		s[i] = t.String()
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,61 @@
+//go:build go1.18 && !monkey_disabled
+// +build go1.18,!monkey_disabled
+
+package monkey_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kiss/monkey"
+)
+
+func TestReturn(t *testing.T) {
+	g := monkey.Return(time.Now, time.Unix(0, 0))
+	assert(t, time.Now().Unix() == 0)
+	g.Unpatch()
+
+	g = monkey.Return2(strconv.Atoi, 42, nil)
+	n, err := strconv.Atoi("x")
+	assert(t, n == 42 && err == nil, n, err)
+	g.Unpatch()
+
+	g = monkey.Return2[*os.File](os.Open, nil, os.ErrNotExist)
+	f, err := os.Open("monkey.go")
+	assert(t, f == nil && errors.Is(err, os.ErrNotExist), f, err)
+	g.Unpatch()
+
+	g = monkey.Return2(io.ReadAll, []byte("fake"), nil)
+	b, _ := io.ReadAll(strings.NewReader("real"))
+	assert(t, string(b) == "fake", string(b))
+	g.Unpatch()
+
+	g = monkey.Return3(net.SplitHostPort, "localhost", "80", nil)
+	host, port, err := net.SplitHostPort("x")
+	assert(t, host == "localhost" && port == "80" && err == nil)
+	g.Unpatch()
+
+	boom := errors.New("boom")
+	g = monkey.ReturnError(os.Remove, boom)
+	assert(t, os.Remove("nonexistent") == boom)
+	g.Unpatch()
+}
+
+func TestReturnMismatch(t *testing.T) {
+	defer monkey.SetOptions(monkey.CurrentOptions())
+	var l lines
+	monkey.SetOptions(monkey.Options{Logger: &l})
+
+	g := monkey.Return2(strconv.Itoa, "x", nil)
+	assert(t, g.Err() != nil && strings.Contains(g.Err().Error(), "doesn't return (string, error)"), g.Err())
+	assert(t, strconv.Itoa(1) == "1")
+	g = monkey.Return(strconv.Atoi, 1)
+	assert(t, g.Err() != nil, g.Err())
+	assert(t, len(l) == 2, l)
+}
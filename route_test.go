@@ -0,0 +1,105 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package monkey
+
+import (
+	"reflect"
+	"testing"
+)
+
+//go:noinline
+func routed(n int) int { return n }
+
+//go:noinline
+func routedShared(n int) int { return n }
+
+// routedDirect tells whether the stub of target goes to its direct code
+// rather than to the dispatcher.
+func routedDirect(t *testing.T, target interface{}) bool {
+	t.Helper()
+	lock.Lock()
+	defer lock.Unlock()
+	p, ok := lookupPatch(reflect.ValueOf(target).Pointer())
+	if !ok || p.stub == nil {
+		t.Fatal("target not patched")
+	}
+	return p.route != dispatch
+}
+
+// onOtherGoroutine returns f(n) called on another goroutine.
+func onOtherGoroutine(f func(int) int, n int) int {
+	c := make(chan int)
+	go func() { c <- f(n) }()
+	return <-c
+}
+
+func TestDirectRoute(t *testing.T) {
+	g := Patch(routed, func(n int) int { return routed(n) + 1 })
+	defer g.Unpatch()
+	if !routedDirect(t, routed) {
+		t.Fatal("patched by a single goroutine, the target goes through the dispatcher")
+	}
+	if v := routed(1); v != 2 {
+		t.Errorf("direct call returned %d, want 2", v)
+	}
+	if v := onOtherGoroutine(routed, 1); v != 1 {
+		t.Errorf("call on another goroutine returned %d, want 1", v)
+	}
+	g.Disable()
+	if v := routed(1); v != 1 {
+		t.Errorf("disabled call returned %d, want 1", v)
+	}
+	g.Enable()
+	if g.Hits() != 1 || g.PassThroughs() != 2 {
+		t.Errorf("%d hits and %d pass-throughs, want 1 and 2, the reentrant call and the disabled one", g.Hits(), g.PassThroughs())
+	}
+
+	// A second goroutine patching the target switches it to the
+	// dispatcher, which it keeps using once that patch is gone.
+	patched, unpatch, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		g := Patch(routed, func(n int) int { return -n })
+		close(patched)
+		<-unpatch
+		if v := routed(1); v != -1 {
+			t.Errorf("call on the second goroutine returned %d, want -1", v)
+		}
+		g.Unpatch()
+	}()
+	<-patched
+	if routedDirect(t, routed) {
+		t.Error("patched by two goroutines, the target still goes to its direct code")
+	}
+	if v := routed(1); v != 2 {
+		t.Errorf("dispatched call returned %d, want 2", v)
+	}
+	close(unpatch)
+	<-done
+	if routedDirect(t, routed) {
+		t.Error("the target went back to its direct code")
+	}
+	if v := routed(1); v != 2 || onOtherGoroutine(routed, 1) != 1 {
+		t.Errorf("dispatched call returned %d, want 2", v)
+	}
+}
+
+func TestDirectRouteShared(t *testing.T) {
+	g := Patch(routedShared, func(n int) int { return n + 1 })
+	defer g.Unpatch()
+	if !routedDirect(t, routedShared) {
+		t.Fatal("patched by a single goroutine, the target goes through the dispatcher")
+	}
+	shared := Patch(routedShared, func(n int) int { return -n }, ForLabels())
+	defer shared.Unpatch()
+	if routedDirect(t, routedShared) {
+		t.Error("patched for every goroutine, the target still goes to its direct code")
+	}
+	if v := routedShared(1); v != 2 {
+		t.Errorf("call returned %d, want 2", v)
+	}
+	if v := onOtherGoroutine(routedShared, 1); v != -1 {
+		t.Errorf("call on another goroutine returned %d, want -1", v)
+	}
+}
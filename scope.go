@@ -0,0 +1,192 @@
+package monkey
+
+import (
+	"reflect"
+	"sort"
+	"sync/atomic"
+)
+
+// Scope is the set of calls a patch applies to. When the scopes of several
+// patches of a target hold for a call, the patch with the first scope in
+// the order below runs, and among patches of the same scope the one applied
+// first.
+type Scope int
+
+const (
+	// ScopeGoroutine is the goroutine making the patch, the default.
+	ScopeGoroutine Scope = iota
+	// ScopeLabels is the goroutines carrying pprof labels, see
+	// ForLabels.
+	ScopeLabels
+	// ScopeContext is the calls with a context activated for a name,
+	// which it is inherited from, see OnContext.
+	ScopeContext
+	// ScopeGlobal is every goroutine, see ForLabels with no pairs.
+	ScopeGlobal
+)
+
+func (s Scope) String() string {
+	switch s {
+	case ScopeGoroutine:
+		return "goroutine"
+	case ScopeLabels:
+		return "labels"
+	case ScopeContext:
+		return "context"
+	case ScopeGlobal:
+		return "global"
+	}
+	return "unknown"
+}
+
+// narrow makes the patch scoped to s, unless an option gave it a narrower
+// scope already.
+func (c *patchConfig) narrow(s Scope) {
+	if c.scope == ScopeGoroutine || s < c.scope {
+		c.scope = s
+	}
+}
+
+// Scope returns the calls the patch made by g applies to.
+func (g *PatchGuard) Scope() Scope {
+	return g.config.scope
+}
+
+// Match returns the guard of the patch a call to target with args, made on
+// the calling goroutine, would run, or nil if it would run the original.
+// Missing args are taken to be zero. Replacements may still hand the call
+// on to the original, see PatchReceiver.
+func Match(target interface{}, args ...interface{}) *PatchGuard {
+	v := reflect.ValueOf(target)
+	p, ok := lookupPatch(v.Pointer())
+	if !ok {
+		return nil
+	}
+	if e, ok := p.entries()[curG()]; ok && e.guard != nil {
+		if atomic.LoadUint32(&e.off)|atomic.LoadUint32(&e.busy) != 0 {
+			return nil
+		}
+		return e.guard
+	}
+	in := make([]reflect.Value, v.Type().NumIn())
+	for i := range in {
+		in[i] = reflect.New(v.Type().In(i)).Elem()
+		if i < len(args) && args[i] != nil {
+			in[i].Set(reflect.ValueOf(args[i]))
+		}
+	}
+	return p.match(in)
+}
+
+// match returns the shared patch of p applying to a call with in.
+func (p *patch) match(in []reflect.Value) *PatchGuard {
+	for _, g := range p.sharedGuards() {
+		if atomic.LoadUint32(&g.entry.off) == 0 && g.config.when(in) {
+			return g
+		}
+	}
+	return nil
+}
+
+// sharedGuards returns the guards of the patches of p applying to every
+// goroutine, in the order they take precedence. The slice is never
+// modified, see registryMaps.
+func (p *patch) sharedGuards() []*PatchGuard {
+	l, _ := p.shared.Load().([]*PatchGuard)
+	return l
+}
+
+// addShared adds the patch made by pg, which applies to every goroutine.
+// They share a single entry under anyG, whose replacement resolves each call
+// to the first of them holding for it. lock must be held.
+func (p *patch) addShared(pg *PatchGuard, typ reflect.Type) {
+	old := p.sharedGuards()
+	l := make([]*PatchGuard, 0, len(old)+1)
+	l = append(append(l, old...), pg)
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].config.scope < l[j].config.scope
+	})
+	p.shared.Store(l)
+
+	if _, ok := p.entries()[anyG]; ok {
+		atomic.AddUint64(&p.installs, 1)
+		live++
+		return
+	}
+	r := &entry{}
+	r.fn = reflect.MakeFunc(typ, func(in []reflect.Value) []reflect.Value {
+		for _, g := range p.sharedGuards() {
+			e := g.entry
+			if atomic.LoadUint32(&e.off) != 0 {
+				continue
+			}
+			if !g.config.when(in) {
+				// Handed on, as PassThroughs tells.
+				atomic.AddUint64(&e.passed, 1)
+				continue
+			}
+			atomic.AddUint64(&e.calls, 1)
+			return call(e.fn, in)
+		}
+		atomic.AddUint64(&r.calls, ^uint64(0))
+		atomic.AddUint64(&r.passed, 1)
+		if out := refusePassThrough(p.from, typ); out != nil {
+			return out
+		}
+		return call(r.original, in)
+	})
+	r.to = uintptr(getPtr(r.fn))
+	if p.trampoline != nil {
+		r.original = funcValue(uintptr(p.trampoline), typ)
+	}
+	p.Add(anyG, r)
+}
+
+// delShared removes the patch made by pg, and the entry under anyG with
+// the last one. lock must be held.
+func (p *patch) delShared(pg *PatchGuard) bool {
+	if !p.withoutShared(pg) {
+		return false
+	}
+	if len(p.sharedGuards()) > 0 {
+		live--
+		return true
+	}
+	return p.Del(anyG, nil)
+}
+
+// unaddShared undoes addShared when the patch couldn't be installed.
+func (p *patch) unaddShared(pg *PatchGuard) {
+	p.withoutShared(pg)
+	live--
+	if len(p.sharedGuards()) == 0 {
+		p.setEntries(p.withEntry(anyG, nil))
+	}
+}
+
+// withoutShared removes pg from the shared guards of p, and returns whether
+// it was one.
+func (p *patch) withoutShared(pg *PatchGuard) bool {
+	old := p.sharedGuards()
+	l := make([]*PatchGuard, 0, len(old))
+	for _, g := range old {
+		if g != pg {
+			l = append(l, g)
+		}
+	}
+	p.shared.Store(l)
+	return len(l) < len(old)
+}
+
+// holds tells whether the patch made by g is in place.
+func (p *patch) holds(g *PatchGuard) bool {
+	if g.gid != anyG {
+		return p.entries()[g.gid] == g.entry
+	}
+	for _, s := range p.sharedGuards() {
+		if s == g {
+			return true
+		}
+	}
+	return false
+}
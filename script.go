@@ -0,0 +1,160 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// errNotScripted is reported when pushing to a guard not made by Script.
+var errNotScripted = errors.New("monkey: guard is not scripted, see Script")
+
+// Exhaustion is what a guard made by Script does when called with no
+// behavior left, see OnExhausted.
+type Exhaustion int
+
+const (
+	// ExhaustFail fails the test and returns zero values.
+	ExhaustFail Exhaustion = iota
+	// ExhaustOriginal calls the original function.
+	ExhaustOriginal
+	// ExhaustRepeat repeats the last behavior, or fails the test if
+	// there was none.
+	ExhaustRepeat
+	// ExhaustZero returns zero values.
+	ExhaustZero
+)
+
+// OnExhausted selects what a guard made by Script does once its queue is
+// empty. The default is ExhaustFail.
+func OnExhausted(e Exhaustion) PatchOption {
+	return func(c *patchConfig) {
+		c.exhausted = e
+	}
+}
+
+// script is the queue of behaviors of a guard made by Script.
+type script struct {
+	lock  sync.Mutex
+	queue []reflect.Value
+	// last is the behavior taken last from queue.
+	last reflect.Value
+}
+
+// next takes the next behavior off the queue.
+func (s *script) next() (reflect.Value, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.queue) == 0 {
+		return s.last, false
+	}
+	s.last = s.queue[0]
+	s.queue = s.queue[1:]
+	return s.last, true
+}
+
+// Script patches target to behave as queued with PushReturn and PushFunc,
+// one behavior per call. What a call finding the queue empty does is set
+// with OnExhausted, by default it fails t and returns zero values. The
+// patch is removed when t finishes, which needs Go 1.14, see SessionT.
+func Script(t testing.TB, target interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryScript(t, target, opts...)
+	if err != nil {
+		return inertGuard(reflect.ValueOf(target), reflect.Value{}, err)
+	}
+	return g
+}
+
+// TryScript is like Script but returns an error instead of panicking.
+func TryScript(t testing.TB, target interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Func {
+		return nil, errors.New("target has to be a Func")
+	}
+
+	s := &script{}
+	var g *PatchGuard
+	r := reflect.MakeFunc(v.Type(), func(in []reflect.Value) []reflect.Value {
+		f, ok := s.next()
+		if ok {
+			return call(f, in)
+		}
+		switch g.config.exhausted {
+		case ExhaustOriginal:
+			return handOn(g, in)
+		case ExhaustRepeat:
+			if f.IsValid() {
+				return call(f, in)
+			}
+		case ExhaustZero:
+			return zeroResults(v.Type())
+		}
+		t.Errorf("monkey: unexpected call of %s, no scripted behavior left", SymbolName(v.Pointer()))
+		return zeroResults(v.Type())
+	})
+	g, err := TryPatch(target, r.Interface(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	g.script = s
+	cleanup(t, g.Unpatch)
+	return g, nil
+}
+
+// PushReturn queues a call of a guard made by Script returning vals, which
+// have to match the results of the target. Nil stands for the zero value.
+func (g *PatchGuard) PushReturn(vals ...interface{}) *PatchGuard {
+	typ := g.target.Type()
+	out := make([]reflect.Value, len(vals))
+	err := resultValues(typ, vals, out)
+	if err == nil {
+		err = g.push(reflect.MakeFunc(typ, func([]reflect.Value) []reflect.Value { return out }))
+	}
+	check(err)
+	return g
+}
+
+// PushFunc queues a call of a guard made by Script running f, which has the
+// type of the target.
+func (g *PatchGuard) PushFunc(f interface{}) *PatchGuard {
+	v := reflect.ValueOf(f)
+	if v.Kind() != reflect.Func || v.Type() != g.target.Type() {
+		check(fmt.Errorf("monkey: pushed %T, want %s", f, g.target.Type()))
+		return g
+	}
+	check(g.push(v))
+	return g
+}
+
+func (g *PatchGuard) push(f reflect.Value) error {
+	if g.script == nil {
+		return errNotScripted
+	}
+	g.script.lock.Lock()
+	defer g.script.lock.Unlock()
+	g.script.queue = append(g.script.queue, f)
+	return nil
+}
+
+// resultValues converts vals to the results of a function of type typ.
+func resultValues(typ reflect.Type, vals []interface{}, out []reflect.Value) error {
+	if len(vals) != typ.NumOut() {
+		return fmt.Errorf("monkey: %d values for %d results of %s", len(vals), typ.NumOut(), typ)
+	}
+	for i, v := range vals {
+		want := typ.Out(i)
+		if v == nil {
+			out[i] = reflect.Zero(want)
+			continue
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.Type().AssignableTo(want) {
+			return fmt.Errorf("monkey: result %d of %s is a %s, not %T", i, typ, want, v)
+		}
+		out[i] = reflect.New(want).Elem()
+		out[i].Set(rv)
+	}
+	return nil
+}
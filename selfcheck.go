@@ -0,0 +1,36 @@
+package monkey
+
+import "fmt"
+
+// SelfCheck patches a function of the package for the current goroutine,
+// checks calls reach the replacement on this goroutine only, and unpatches
+// it, as done when the dispatcher is loaded. Call it at startup to learn
+// early that patching doesn't work on the running platform, rather than in
+// the middle of an experiment. It returns ErrDisabled in builds with the
+// monkey_disabled tag.
+func SelfCheck() (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("monkey: self check: panic: %v", v)
+		}
+	}()
+	if disabled {
+		return ErrDisabled
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if err := checkPolicy(); err != nil {
+		return err
+	}
+	if err := loadDispatcher(); err != nil {
+		return err
+	}
+	if err := checkDispatch(); err != nil {
+		return fmt.Errorf("monkey: self check: %v", err)
+	}
+	if v := canary(1); v != 3 {
+		return fmt.Errorf("monkey: self check: unpatched call returned %d, want 3", v)
+	}
+	return nil
+}
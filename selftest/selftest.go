@@ -0,0 +1,209 @@
+// Package selftest checks that monkey works on the running platform and Go
+// release. Run it in CI when moving to a new OS, architecture or Go version
+// before trusting the test suites depending on monkey.
+package selftest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-kiss/monkey"
+)
+
+// Result is the outcome of a single scenario.
+type Result struct {
+	Name string
+	Err  error
+}
+
+var scenarios = []struct {
+	name string
+	run  func() error
+}{
+	{"selfcheck", monkey.SelfCheck},
+	{"patch", testPatch},
+	{"goroutine", testGoroutine},
+	{"concurrent", testConcurrent},
+	{"unwind", testUnwind},
+	{"growth", testGrowth},
+	{"globals", testGlobals},
+}
+
+// Run runs every scenario and returns their results.
+func Run() []Result {
+	r := make([]Result, 0, len(scenarios))
+	for _, s := range scenarios {
+		r = append(r, Result{s.name, run(s.run)})
+	}
+	return r
+}
+
+// Check runs every scenario and returns the first failure.
+func Check() error {
+	for _, r := range Run() {
+		if r.Err != nil {
+			return fmt.Errorf("selftest %s: %w", r.Name, r.Err)
+		}
+	}
+	return nil
+}
+
+func run(f func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v", v)
+		}
+	}()
+	return f()
+}
+
+//go:noinline
+func add(a, b int) int { return a + b }
+
+//go:noinline
+func sub(a, b int) int { return a - b }
+
+func testPatch() error {
+	g, err := monkey.TryPatch(add, sub)
+	if err != nil {
+		return err
+	}
+	patched := add(3, 1)
+	g.Unpatch()
+	if patched != 2 {
+		return fmt.Errorf("patched call returned %d, want 2", patched)
+	}
+	if v := add(3, 1); v != 4 {
+		return fmt.Errorf("unpatched call returned %d, want 4", v)
+	}
+	return nil
+}
+
+func testGoroutine() error {
+	g, err := monkey.TryPatch(add, sub)
+	if err != nil {
+		return err
+	}
+	defer g.Unpatch()
+
+	c := make(chan int)
+	go func() { c <- add(3, 1) }()
+	if v := <-c; v != 4 {
+		return fmt.Errorf("call on another goroutine returned %d, want 4", v)
+	}
+	return nil
+}
+
+func testConcurrent() error {
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				g, err := monkey.TryPatch(add, func(a, b int) int { return i })
+				if err != nil {
+					errs <- err
+					return
+				}
+				v := add(j, j)
+				g.Unpatch()
+				if v != i {
+					errs <- fmt.Errorf("goroutine %d got %d", i, v)
+					return
+				}
+			}
+		}(i)
+	}
+	for j := 0; j < 10000; j++ {
+		if v := add(j, 1); v != j+1 {
+			errs <- fmt.Errorf("original returned %d, want %d", v, j+1)
+			break
+		}
+	}
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+var errUnwind = errors.New("unwind")
+
+func testUnwind() (err error) {
+	g, err := monkey.TryPatch(add, func(a, b int) int { panic(errUnwind) })
+	if err != nil {
+		return err
+	}
+	defer g.Unpatch()
+
+	deferred := false
+	func() {
+		defer func() {
+			if v := recover(); v != errUnwind {
+				err = fmt.Errorf("recovered %v, want %v", v, errUnwind)
+			}
+		}()
+		defer func() { deferred = true }()
+		add(1, 2)
+	}()
+	if err == nil && !deferred {
+		err = errors.New("deferred call did not run")
+	}
+	return err
+}
+
+//go:noinline
+func deep(n int) int {
+	var buf [64]byte
+	buf[n%64] = byte(n)
+	if n == 0 {
+		return int(buf[0])
+	}
+	return deep(n-1) + int(buf[n%64])
+}
+
+func testGrowth() error {
+	want := deep(100)
+
+	// Patched on this goroutine only, so the other one runs the original
+	// prologue moved into the stub and has to grow its stack from there.
+	g, err := monkey.TryPatch(deep, func(int) int { return -1 })
+	if err != nil {
+		return err
+	}
+	defer g.Unpatch()
+
+	c := make(chan int)
+	go func() { c <- deep(100) }()
+	if v := <-c; v != want {
+		return fmt.Errorf("original returned %d, want %d", v, want)
+	}
+	return nil
+}
+
+var left, right = 1, 2
+
+// pair loads globals relative to rip from its first instruction, which
+// PIE and -shared code does more often.
+//
+//go:noinline
+func pair() (int, int) { return left, right }
+
+func testGlobals() error {
+	g, err := monkey.TryPatch(pair, func() (int, int) { return 0, 0 })
+	if err != nil {
+		return err
+	}
+	defer g.Unpatch()
+
+	c := make(chan [2]int)
+	go func() {
+		a, b := pair()
+		c <- [2]int{a, b}
+	}()
+	if v := <-c; v != [2]int{1, 2} {
+		return fmt.Errorf("call on another goroutine returned %v, want [1 2]", v)
+	}
+	return nil
+}
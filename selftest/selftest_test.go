@@ -0,0 +1,57 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package selftest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kiss/monkey/selftest"
+)
+
+func TestRun(t *testing.T) {
+	for _, r := range selftest.Run() {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Name, r.Err)
+		}
+	}
+}
+
+// TestBuildModes runs the scenarios in binaries built like integration
+// binaries are, in both the default build mode and as PIE, which lays out
+// and maps the text segment differently from go test on some platforms.
+func TestBuildModes(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds binaries")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, mode := range []string{"default", "pie"} {
+		t.Run(mode, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "selftest")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			bin := filepath.Join(dir, "selftest")
+			out, err := exec.Command(gobin, "build", "-gcflags=all=-l", "-buildmode="+mode, "-o", bin, "./testdata/selftest").CombinedOutput()
+			if err != nil {
+				if strings.Contains(string(out), "not supported") {
+					t.Skip(string(out))
+				}
+				t.Fatalf("%v: %s", err, out)
+			}
+			out, err = exec.Command(bin).CombinedOutput()
+			if err != nil || strings.TrimSpace(string(out)) != "ok" {
+				t.Fatalf("%v: %s", err, out)
+			}
+		})
+	}
+}
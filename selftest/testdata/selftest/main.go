@@ -0,0 +1,24 @@
+// Command selftest runs the scenarios of package selftest, built the way
+// integration binaries are rather than by go test.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-kiss/monkey/selftest"
+)
+
+func main() {
+	failed := false
+	for _, r := range selftest.Run() {
+		if r.Err != nil {
+			fmt.Printf("%s: %v\n", r.Name, r.Err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+	fmt.Println("ok")
+}
@@ -0,0 +1,91 @@
+package monkey
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Session collects patches so they can be removed together, e.g. at the end
+// of a test.
+type Session struct {
+	lock   sync.Mutex
+	guards []*PatchGuard
+}
+
+// NewSession returns an empty session.
+func NewSession() *Session {
+	return &Session{}
+}
+
+// SessionT returns a session emptied when t and its subtests finish.
+// Patches only apply to the goroutine making them, so to patch from a
+// goroutine started by the test, hand it the session, directly or with
+// ContextWithSession, and patch through it: the patches are removed at the
+// end of the test even if the goroutine is still running then. Removing
+// them when the test ends needs Go 1.14: before, SessionT fails t, use
+// NewSession and Unpatch the session instead.
+func SessionT(t testing.TB) *Session {
+	t.Helper()
+	s := NewSession()
+	cleanup(t, s.Unpatch)
+	return s
+}
+
+// cleanup makes t run f when it finishes, or fails t right away if it
+// can't, as testing.TB only has Cleanup from Go 1.14 on: the patches f
+// removes would otherwise outlive t.
+func cleanup(t testing.TB, f func()) {
+	t.Helper()
+	c, ok := t.(cleaner)
+	if !ok {
+		f()
+		t.Fatalf("monkey: removing patches when %s ends needs Go 1.14 or later", t.Name())
+	}
+	c.Cleanup(f)
+}
+
+type sessionKey struct{}
+
+// ContextWithSession returns a copy of ctx carrying s.
+func ContextWithSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionKey{}, s)
+}
+
+// SessionFromContext returns the session carried by ctx, or nil.
+func SessionFromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(sessionKey{}).(*Session)
+	return s
+}
+
+// Patch is like the package level Patch and records the patch in s.
+func (s *Session) Patch(target, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	return s.Track(Patch(target, replacement, opts...))
+}
+
+// PatchInstanceMethod is like the package level PatchInstanceMethod and
+// records the patch in s.
+func (s *Session) PatchInstanceMethod(target reflect.Type, methodName string, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	return s.Track(PatchInstanceMethod(target, methodName, replacement, opts...))
+}
+
+// Track records a guard created elsewhere in s and returns it.
+func (s *Session) Track(g *PatchGuard) *PatchGuard {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.guards = append(s.guards, g)
+	return g
+}
+
+// Unpatch removes every patch recorded in s, the latest first.
+func (s *Session) Unpatch() {
+	s.lock.Lock()
+	guards := s.guards
+	s.guards = nil
+	s.lock.Unlock()
+
+	for i := len(guards) - 1; i >= 0; i-- {
+		guards[i].Unpatch()
+	}
+}
@@ -0,0 +1,22 @@
+package monkey
+
+import "reflect"
+
+// anyG is the goroutine of entries shared by every goroutine, which the
+// dispatcher takes for goroutines having no entry of their own. Patches
+// made with an option setting patchConfig.when go through the one of their
+// target, which hands the calls none of them holds for to the original, see
+// addShared.
+const anyG = ^uintptr(0)
+
+// addWhen makes the patch apply only to the calls f holds for as well.
+func (c *patchConfig) addWhen(f func(in []reflect.Value) bool) {
+	prev := c.when
+	if prev == nil {
+		c.when = f
+		return
+	}
+	c.when = func(in []reflect.Value) bool {
+		return prev(in) && f(in)
+	}
+}
@@ -0,0 +1,41 @@
+package monkey
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// callStack returns the stack of its caller, for CreationStack.
+func callStack() []uintptr {
+	pc := make([]uintptr, 64)
+	return pc[:runtime.Callers(2, pc)]
+}
+
+// formatStack formats the frames of stack from the innermost one outside
+// of this package, like the runtime does in tracebacks.
+func formatStack(stack []uintptr) string {
+	if len(stack) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	frames := runtime.CallersFrames(stack)
+	outside := false
+	for {
+		f, more := frames.Next()
+		outside = outside || !strings.HasPrefix(f.Function, pkgPrefix)
+		if outside && f.Function != "runtime.goexit" {
+			fmt.Fprintf(&b, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// CreationStack returns the stack of the goroutine that made g when it did,
+// formatted like in tracebacks, to tell which test or helper a patch comes
+// from.
+func (g *PatchGuard) CreationStack() string {
+	return formatStack(g.stack)
+}
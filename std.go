@@ -0,0 +1,61 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"unsafe"
+)
+
+// ErrUnknownStd is returned by PatchStd for functions it has no signature
+// of.
+var ErrUnknownStd = errors.New("monkey: no signature known for standard library function")
+
+// stdSignatures maps the unexported standard library functions PatchStd
+// knows to their type in the Go release being built with, as printed by
+// reflect. Methods take their receiver first. Types are compared by name so
+// that this package doesn't link in every package they come from.
+var stdSignatures = map[string]string{}
+
+// PatchStd patches the unexported standard library function with the
+// fully qualified name, e.g. "os.hostname", one of those listed by
+// StdSymbols. The type of replacement is checked against the signature the
+// function has in the Go release the binary is built with. The function has
+// to be linked in, i.e. used by the program, and calls to it inlined into
+// other standard library functions are missed unless building with
+// -gcflags=all=-l.
+func PatchStd(name string, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatchStd(name, replacement, opts...)
+	if err != nil {
+		return inertGuard(reflect.Value{}, reflect.ValueOf(replacement), err)
+	}
+	return g
+}
+
+// TryPatchStd is like PatchStd but returns an error instead of panicking.
+func TryPatchStd(name string, replacement interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	if _, ok := stdSignatures[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStd, name)
+	}
+	return TryPatchByName(name, replacement, opts...)
+}
+
+// StdSymbols returns the names of the functions PatchStd knows, sorted.
+func StdSymbols() []string {
+	names := make([]string, 0, len(stdSignatures))
+	for name := range stdSignatures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// funcValue returns a function of type typ running the code at addr.
+func funcValue(addr uintptr, typ reflect.Type) reflect.Value {
+	code := new(uintptr)
+	*code = addr
+	v := reflect.New(typ).Elem()
+	*(*unsafe.Pointer)(unsafe.Pointer(v.UnsafeAddr())) = unsafe.Pointer(code)
+	return v
+}
@@ -0,0 +1,8 @@
+//go:build go1.17
+// +build go1.17
+
+package monkey
+
+func init() {
+	stdSignatures["crypto/tls.(*Conn).handshakeContext"] = "func(*tls.Conn, context.Context) error"
+}
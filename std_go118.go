@@ -0,0 +1,8 @@
+//go:build go1.18
+// +build go1.18
+
+package monkey
+
+func init() {
+	stdSignatures["crypto/x509.systemRootsPool"] = "func() *x509.CertPool"
+}
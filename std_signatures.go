@@ -0,0 +1,19 @@
+package monkey
+
+// Signatures unchanged since before the oldest Go release supported.
+func init() {
+	for name, sig := range map[string]string{
+		"os.hostname":   "func() (string, error)",
+		"os.executable": "func() (string, error)",
+
+		"net.(*Resolver).lookupHost":   "func(*net.Resolver, context.Context, string) ([]string, error)",
+		"net.(*Resolver).lookupIP":     "func(*net.Resolver, context.Context, string, string) ([]net.IPAddr, error)",
+		"net.(*Resolver).lookupIPAddr": "func(*net.Resolver, context.Context, string, string) ([]net.IPAddr, error)",
+		"net.(*Resolver).lookupCNAME":  "func(*net.Resolver, context.Context, string) (string, error)",
+		"net.(*Resolver).lookupMX":     "func(*net.Resolver, context.Context, string) ([]*net.MX, error)",
+		"net.(*Resolver).lookupTXT":    "func(*net.Resolver, context.Context, string) ([]string, error)",
+		"net.(*Resolver).lookupAddr":   "func(*net.Resolver, context.Context, string) ([]string, error)",
+	} {
+		stdSignatures[name] = sig
+	}
+}
@@ -0,0 +1,137 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// Stream is an in-memory stream standing for a file, a connection or the
+// body of a response, which records what was read from it and written to
+// it. It is safe for concurrent use.
+type Stream struct {
+	lock    sync.Mutex
+	data    []byte
+	read    int
+	written []byte
+	closed  bool
+}
+
+// NewStream returns a stream reading data.
+func NewStream(data string) *Stream {
+	return &Stream{data: []byte(data)}
+}
+
+// Read reads the data of s, returning io.EOF at its end and
+// io.ErrClosedPipe once s is closed.
+func (s *Stream) Read(b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if s.read == len(s.data) && len(b) > 0 {
+		return 0, io.EOF
+	}
+	n := copy(b, s.data[s.read:])
+	s.read += n
+	return n, nil
+}
+
+// Write records b, and returns io.ErrClosedPipe once s is closed.
+func (s *Stream) Write(b []byte) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	s.written = append(s.written, b...)
+	return len(b), nil
+}
+
+// Close closes s. Closing it again returns io.ErrClosedPipe.
+func (s *Stream) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return io.ErrClosedPipe
+	}
+	s.closed = true
+	return nil
+}
+
+// Consumed returns the data read from s so far.
+func (s *Stream) Consumed() []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]byte(nil), s.data[:s.read]...)
+}
+
+// Written returns the data written to s so far.
+func (s *Stream) Written() []byte {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]byte(nil), s.written...)
+}
+
+// Closed tells whether s was closed, to check that the code under test
+// doesn't leak what target returned.
+func (s *Stream) Closed() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.closed
+}
+
+var streamType = reflect.TypeOf((*Stream)(nil))
+
+// PatchStream patches target, a function returning a stream such as an
+// io.ReadCloser or an io.Writer, to return a new Stream reading data on
+// every call, its other results being zero:
+//
+//	g := monkey.PatchStream(openConfig, `{"debug": true}`)
+//	defer g.Unpatch()
+//	...
+//	for _, s := range g.Streams() {
+//		assert(s.Closed())
+//	}
+//
+// The calls are recorded as with the Record option. Like Patch, it panics or
+// logs if target returns no interface a Stream implements.
+func PatchStream(target interface{}, data string, opts ...PatchOption) *PatchGuard {
+	t := reflect.ValueOf(target)
+	if t.Kind() != reflect.Func {
+		return inertGuard(t, reflect.Value{}, patchError("patch", fmt.Sprint(target), errors.New("target has to be a Func")))
+	}
+	typ := t.Type()
+	at := -1
+	for i := 0; i < typ.NumOut() && at < 0; i++ {
+		if typ.Out(i).Kind() == reflect.Interface && streamType.Implements(typ.Out(i)) {
+			at = i
+		}
+	}
+	if at < 0 {
+		return inertGuard(t, reflect.Value{}, patchError("patch", SymbolName(t.Pointer()), fmt.Errorf("%s returns no stream", typ)))
+	}
+	r := reflect.MakeFunc(typ, func([]reflect.Value) []reflect.Value {
+		out := zeroResults(typ)
+		out[at] = reflect.ValueOf(NewStream(data)).Convert(typ.Out(at))
+		return out
+	})
+	return Patch(target, r.Interface(), append(opts, Record())...)
+}
+
+// Streams returns the streams returned by the calls recorded by g, see
+// PatchStream, oldest first.
+func (g *PatchGuard) Streams() []*Stream {
+	var l []*Stream
+	for _, c := range g.Calls() {
+		for _, v := range c.Results {
+			if s, ok := v.Interface().(*Stream); ok {
+				l = append(l, s)
+			}
+		}
+	}
+	return l
+}
@@ -0,0 +1,133 @@
+package monkey
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Stub describes code generated by the package, so that addresses found in
+// a core dump or a profile can be told apart from the rest of the process.
+type Stub struct {
+	Addr uintptr
+	Size int
+	// Func names what the code is for, e.g. "stub for net/http.(*Client).Do".
+	Func string
+}
+
+var (
+	stubsLock sync.Mutex
+	stubs     []Stub
+	stubsFile string
+)
+
+// addStub records the code at addr. The file set with SetStubMapFile is
+// written again to include it.
+func addStub(addr uintptr, size int, name string) {
+	stubsLock.Lock()
+	defer stubsLock.Unlock()
+	stubs = append(stubs, Stub{Addr: addr, Size: size, Func: name})
+	if stubsFile != "" {
+		if err := writeStubsFile(stubsFile); err != nil {
+			logf("writing %s: %v", stubsFile, err)
+		}
+	}
+}
+
+// removeStub forgets the code at addr, freed by Compact.
+func removeStub(addr uintptr) {
+	stubsLock.Lock()
+	defer stubsLock.Unlock()
+	for i, s := range stubs {
+		if s.Addr == addr {
+			stubs = append(stubs[:i], stubs[i+1:]...)
+			break
+		}
+	}
+	if stubsFile != "" {
+		if err := writeStubsFile(stubsFile); err != nil {
+			logf("writing %s: %v", stubsFile, err)
+		}
+	}
+}
+
+// Stubs returns the code generated so far and still in use, sorted by
+// address. Only Compact frees stubs, the result stays valid until it is
+// called.
+func Stubs() []Stub {
+	stubsLock.Lock()
+	defer stubsLock.Unlock()
+	s := append([]Stub(nil), stubs...)
+	sort.Slice(s, func(i, j int) bool { return s[i].Addr < s[j].Addr })
+	return s
+}
+
+// StubFunc returns the name of the generated code containing pc, as given
+// by Stub.Func.
+func StubFunc(pc uintptr) (string, bool) {
+	stubsLock.Lock()
+	defer stubsLock.Unlock()
+	for _, s := range stubs {
+		if pc >= s.Addr && pc < s.Addr+uintptr(s.Size) {
+			return s.Func, true
+		}
+	}
+	return "", false
+}
+
+// WriteStubs writes Stubs to w in the format of perf map files, one
+// "ADDR SIZE NAME" line per stub with ADDR and SIZE in hexadecimal.
+func WriteStubs(w io.Writer) error {
+	return writeStubs(w, Stubs())
+}
+
+func writeStubs(w io.Writer, stubs []Stub) error {
+	b := bufio.NewWriter(w)
+	for _, s := range stubs {
+		fmt.Fprintf(b, "%x %x %s\n", s.Addr, s.Size, s.Func)
+	}
+	return b.Flush()
+}
+
+// SetStubMapFile keeps the file at path up to date with WriteStubs, or
+// stops updating it if path is empty. A fault in Go code kills the process
+// before any handler of SIGSEGV could run, so the file is written each time
+// a stub is generated instead, and is complete when the process crashes.
+// Naming it /tmp/perf-<pid>.map makes perf resolve stubs as well.
+func SetStubMapFile(path string) error {
+	stubsLock.Lock()
+	defer stubsLock.Unlock()
+	stubsFile = path
+	if path == "" {
+		return nil
+	}
+	return writeStubsFile(path)
+}
+
+// writeStubsFile replaces the file at path, which readers never see
+// partially written. stubsLock must be held.
+func writeStubsFile(path string) error {
+	s := append([]Stub(nil), stubs...)
+	sort.Slice(s, func(i, j int) bool { return s[i].Addr < s[j].Addr })
+
+	f, err := ioutil.TempFile(filepath.Dir(path), ".monkey-stubs")
+	if err != nil {
+		return err
+	}
+	err = writeStubs(f, s)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
@@ -0,0 +1,59 @@
+// Package suitehelper gives testify suites a patch session per test.
+//
+// Embed Mixin next to suite.Suite and patch through it, the patches are
+// removed when the test ends:
+//
+//	type ClientSuite struct {
+//		suite.Suite
+//		suitehelper.Mixin
+//	}
+//
+//	func (s *ClientSuite) TestTimeout() {
+//		s.Patch(time.Now, func() time.Time { return epoch })
+//		...
+//	}
+//
+// A suite defining its own SetupTest or TearDownTest shadows the ones of
+// Mixin and has to call them itself.
+package suitehelper
+
+import (
+	"reflect"
+
+	"github.com/go-kiss/monkey"
+)
+
+// Mixin manages a monkey.Session for every test of a suite.
+type Mixin struct {
+	session *monkey.Session
+}
+
+// SetupTest starts the session of the test.
+func (m *Mixin) SetupTest() {
+	m.session = monkey.NewSession()
+}
+
+// TearDownTest removes every patch made during the test.
+func (m *Mixin) TearDownTest() {
+	if m.session != nil {
+		m.session.Unpatch()
+	}
+}
+
+// Session returns the session of the running test.
+func (m *Mixin) Session() *monkey.Session {
+	if m.session == nil {
+		m.session = monkey.NewSession()
+	}
+	return m.session
+}
+
+// Patch patches target for the running test.
+func (m *Mixin) Patch(target, replacement interface{}, opts ...monkey.PatchOption) *monkey.PatchGuard {
+	return m.Session().Patch(target, replacement, opts...)
+}
+
+// PatchInstanceMethod patches a method for the running test.
+func (m *Mixin) PatchInstanceMethod(target reflect.Type, methodName string, replacement interface{}, opts ...monkey.PatchOption) *monkey.PatchGuard {
+	return m.Session().PatchInstanceMethod(target, methodName, replacement, opts...)
+}
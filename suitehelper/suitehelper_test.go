@@ -0,0 +1,30 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package suitehelper_test
+
+import (
+	"testing"
+
+	"github.com/go-kiss/monkey/suitehelper"
+)
+
+//go:noinline
+func no() bool { return false }
+
+type suite struct {
+	suitehelper.Mixin
+}
+
+func TestMixin(t *testing.T) {
+	var s suite
+	s.SetupTest()
+	s.Patch(no, func() bool { return true })
+	if !no() {
+		t.Fatal("patch not applied")
+	}
+	s.TearDownTest()
+	if no() {
+		t.Fatal("patch not removed")
+	}
+}
@@ -0,0 +1,104 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+
+	"github.com/go-kiss/monkey/raw"
+)
+
+// ErrSymbolNotFound is returned by ResolveSymbol for unknown names.
+var ErrSymbolNotFound = raw.ErrSymbolNotFound
+
+// SymbolName returns the fully qualified name of the function containing pc,
+// e.g. "net/http.(*Client).Do", or "" if pc is not inside Go code.
+func SymbolName(pc uintptr) string {
+	if f := runtime.FuncForPC(pc); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+// ResolveSymbol returns the entry address of the function with the fully
+// qualified name, as printed by SymbolName. Only functions kept by the linker
+// can be found, and stripped binaries work as well since the runtime's own
+// function table is searched.
+func ResolveSymbol(name string) (uintptr, error) {
+	s, err := raw.LookupSymbol(name)
+	if err != nil {
+		return 0, err
+	}
+	return s.Addr, nil
+}
+
+// ErrUnknownSignature is returned by PatchByName for functions it can't
+// find the type of.
+var ErrUnknownSignature = errors.New("monkey: unknown signature")
+
+// Prototype gives PatchByName the type of the function patched, as that of
+// fn, e.g. (func(context.Context, string) error)(nil).
+func Prototype(fn interface{}) PatchOption {
+	t := reflect.TypeOf(fn)
+	return func(c *patchConfig) {
+		c.prototype = t
+	}
+}
+
+// PatchByName patches the function with the fully qualified name, as
+// printed by SymbolName, e.g. "example.com/x/internal/y.doWork". It reaches
+// functions the caller can't refer to, like those of internal packages or
+// unexported ones. Methods take their receiver first in replacement.
+//
+// A replacement of another type than the function would corrupt its calls,
+// so the type has to be known: PatchStd knows that of some functions of the
+// standard library, the Prototype option gives it, and otherwise it is read
+// from DWARF. Tests run by go test have no DWARF unless built with
+// -ldflags=-w=0. Without any, PatchByName fails with ErrUnknownSignature.
+func PatchByName(name string, replacement interface{}, opts ...PatchOption) *PatchGuard {
+	g, err := TryPatchByName(name, replacement, opts...)
+	if err != nil {
+		return inertGuard(reflect.Value{}, reflect.ValueOf(replacement), err)
+	}
+	return g
+}
+
+// TryPatchByName is like PatchByName but returns an error instead of
+// panicking.
+func TryPatchByName(name string, replacement interface{}, opts ...PatchOption) (*PatchGuard, error) {
+	r := reflect.ValueOf(replacement)
+	if r.Kind() != reflect.Func {
+		return nil, fmt.Errorf("monkey: replacement for %s has to be a Func, not %T", name, replacement)
+	}
+	if err := checkSignature(name, r.Type(), newPatchConfig(opts)); err != nil {
+		return nil, err
+	}
+	addr, err := ResolveSymbol(name)
+	if err != nil {
+		return nil, err
+	}
+	return patchGuard(funcValue(addr, r.Type()), r, opts)
+}
+
+// checkSignature returns an error unless t is the type of the function with
+// the fully qualified name.
+func checkSignature(name string, t reflect.Type, c *patchConfig) error {
+	if sig, ok := stdSignatures[name]; ok {
+		if t.String() != sig {
+			return fmt.Errorf("monkey: replacement for %s has to be a %s, not %s", name, sig, t)
+		}
+		return nil
+	}
+	if c.prototype != nil {
+		if t != c.prototype {
+			return fmt.Errorf("monkey: replacement for %s has to be a %s, not %s", name, c.prototype, t)
+		}
+		return nil
+	}
+	f, err := lookupDWARF(name)
+	if err != nil {
+		return fmt.Errorf("%w of %s, pass a Prototype: %v", ErrUnknownSignature, name, err)
+	}
+	return f.check(t)
+}
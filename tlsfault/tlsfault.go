@@ -0,0 +1,86 @@
+// Package tlsfault makes TLS handshakes fail in the ways that are tedious to
+// reproduce with real certificates:
+//
+//	tlsfault.ExpiredCert(t)
+//	_, err := tls.Dial("tcp", addr, conf) // x509: certificate has expired
+//
+// Faults apply to the goroutine injecting them until the test ends. They
+// miss handshakes run by other goroutines, like the connections dialed by
+// http.Transport, so dial where the fault is injected, e.g. through a custom
+// DialTLSContext.
+package tlsfault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/go-kiss/monkey"
+)
+
+// ExpiredCert makes certificate verification fail as if the certificate had
+// expired.
+func ExpiredCert(t testing.TB) *monkey.PatchGuard {
+	return failVerify(t, func(c *x509.Certificate) error {
+		return x509.CertificateInvalidError{Cert: c, Reason: x509.Expired}
+	})
+}
+
+// UnknownAuthority makes certificate verification fail as if the
+// certificate was signed by an unknown authority.
+func UnknownAuthority(t testing.TB) *monkey.PatchGuard {
+	return failVerify(t, func(c *x509.Certificate) error {
+		return x509.UnknownAuthorityError{Cert: c}
+	})
+}
+
+// HostnameMismatch makes certificate verification fail as if the
+// certificate wasn't valid for the host dialed.
+func HostnameMismatch(t testing.TB) *monkey.PatchGuard {
+	return failVerify(t, func(c *x509.Certificate) error {
+		return x509.HostnameError{Certificate: c, Host: "unexpected.invalid"}
+	})
+}
+
+func failVerify(t testing.TB, fail func(c *x509.Certificate) error) *monkey.PatchGuard {
+	return monkey.SessionT(t).Patch((*x509.Certificate).Verify, func(c *x509.Certificate, _ x509.VerifyOptions) ([][]*x509.Certificate, error) {
+		return nil, fail(c)
+	})
+}
+
+// HandshakeTimeout makes handshakes wait for d, or until their context is
+// done, and fail with a timeout error.
+func HandshakeTimeout(t testing.TB, d time.Duration) *monkey.PatchGuard {
+	return handshake(t, func(ctx context.Context) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		}
+		return timeoutError{}
+	})
+}
+
+// HandshakeError makes handshakes fail with err.
+func HandshakeError(t testing.TB, err error) *monkey.PatchGuard {
+	return handshake(t, func(context.Context) error {
+		return err
+	})
+}
+
+// handshake patches the function behind Handshake and HandshakeContext,
+// which are inlined into the rest of crypto/tls.
+func handshake(t testing.TB, fail func(ctx context.Context) error) *monkey.PatchGuard {
+	return monkey.SessionT(t).Track(monkey.PatchStd("crypto/tls.(*Conn).handshakeContext", func(_ *tls.Conn, ctx context.Context) error {
+		return fail(ctx)
+	}))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "tls: handshake timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,79 @@
+//go:build !monkey_disabled
+// +build !monkey_disabled
+
+package tlsfault_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kiss/monkey/tlsfault"
+)
+
+func dial(t *testing.T) error {
+	srv := httptest.NewUnstartedServer(http.NotFoundHandler())
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	c, err := tls.Dial("tcp", srv.Listener.Addr().String(), &tls.Config{RootCAs: pool, ServerName: "example.com"})
+	if err == nil {
+		c.Close()
+	}
+	return err
+}
+
+func TestFaults(t *testing.T) {
+	if err := dial(t); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		tlsfault.ExpiredCert(t)
+		var e x509.CertificateInvalidError
+		if err := dial(t); !errors.As(err, &e) || e.Reason != x509.Expired {
+			t.Fatal(err)
+		}
+	})
+	t.Run("unknown authority", func(t *testing.T) {
+		tlsfault.UnknownAuthority(t)
+		var e x509.UnknownAuthorityError
+		if err := dial(t); !errors.As(err, &e) {
+			t.Fatal(err)
+		}
+	})
+	t.Run("hostname", func(t *testing.T) {
+		tlsfault.HostnameMismatch(t)
+		var e x509.HostnameError
+		if err := dial(t); !errors.As(err, &e) {
+			t.Fatal(err)
+		}
+	})
+	t.Run("timeout", func(t *testing.T) {
+		tlsfault.HandshakeTimeout(t, time.Millisecond)
+		var e net.Error
+		if err := dial(t); !errors.As(err, &e) || !e.Timeout() {
+			t.Fatal(err)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		tlsfault.HandshakeError(t, errors.New("broken"))
+		if err := dial(t); err == nil || !strings.Contains(err.Error(), "broken") {
+			t.Fatal(err)
+		}
+	})
+
+	if err := dial(t); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,40 @@
+package monkey
+
+import (
+	"context"
+	"reflect"
+	"runtime/trace"
+)
+
+// Trace wraps every call to the replacement in a runtime/trace region named
+// after the target, so that execution traces of tests, as taken by
+// go test -trace, show where fake behavior ran. When the target takes a
+// context first, the region belongs to the task of that context. Calls cost
+// nothing more while no trace is taken.
+func Trace() PatchOption {
+	return func(c *patchConfig) {
+		c.trace = true
+	}
+}
+
+// traceRegions returns fn running in a region named after the target of pg.
+func traceRegions(pg *PatchGuard, fn reflect.Value) reflect.Value {
+	name := "monkey: " + SymbolName(pg.target.Pointer())
+	withCtx := fn.Type().NumIn() > 0 && fn.Type().In(0) == contextType
+	return reflect.MakeFunc(fn.Type(), func(in []reflect.Value) []reflect.Value {
+		if !trace.IsEnabled() {
+			return call(fn, in)
+		}
+		defer trace.StartRegion(traceContext(withCtx, in), name).End()
+		return call(fn, in)
+	})
+}
+
+// traceContext returns the context regions of a call with the arguments in
+// belong to: the first argument if withCtx and it is set.
+func traceContext(withCtx bool, in []reflect.Value) context.Context {
+	if withCtx && !in[0].IsNil() {
+		return in[0].Interface().(context.Context)
+	}
+	return context.Background()
+}
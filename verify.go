@@ -0,0 +1,77 @@
+package monkey
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/go-kiss/monkey/raw"
+)
+
+// ErrMaybeInlined is returned by Verify for binaries built with inlining,
+// where callers of the target may run a copy of it the patch doesn't reach.
+var ErrMaybeInlined = errors.New("monkey: inlining is on, callers may run their own copy of the target")
+
+// Verify checks that calls to the target made on the current goroutine go
+// to the replacement: that the jump is still written at the start of the
+// target, and that the dispatcher finds the entry of g there. It returns
+// ErrIntrinsic for targets the compiler replaces at their call sites, and
+// ErrMaybeInlined when the binary isn't built with -gcflags=-l, which it
+// can't tell apart from a patch that works. Call it right after patching to
+// fail early rather than on the assertions of a test.
+func (g *PatchGuard) Verify() error {
+	if g.err != nil {
+		return g.err
+	}
+	if g.entry == nil {
+		return errors.New("monkey: nothing was patched")
+	}
+	name := SymbolName(g.target.Pointer())
+	if intrinsics[name] {
+		return fmt.Errorf("%w: %s, calls to it are compiled to inline instructions", ErrIntrinsic, name)
+	}
+
+	lock.Lock()
+	err := g.verifyDispatch()
+	lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if inlining() {
+		return fmt.Errorf("%w: %s, build with -gcflags=-l", ErrMaybeInlined, name)
+	}
+	return nil
+}
+
+// verifyDispatch follows the jump at the target and the table of its patch
+// as the dispatcher does for the current goroutine.
+func (g *PatchGuard) verifyDispatch() error {
+	p, ok := lookupPatch(g.target.Pointer())
+	if !ok || p.stub == nil {
+		return fmt.Errorf("monkey: %s is not patched", SymbolName(g.target.Pointer()))
+	}
+	from := p.from
+	jump := raw.JmpStub(uintptr(unsafe.Pointer(&p.stub[0])))
+	if !bytes.Equal(raw.Memory(from, len(jump)), jump) {
+		return fmt.Errorf("monkey: the jump written at the start of %s was overwritten", SymbolName(from))
+	}
+
+	gid := curG()
+	for d := atomic.LoadPointer(&p.table); ; d = unsafe.Pointer(uintptr(d) + unsafe.Sizeof(dispatchEntry{})) {
+		de := (*dispatchEntry)(d)
+		switch {
+		case de.g == 0:
+			return fmt.Errorf("monkey: %s is not patched for this goroutine", SymbolName(from))
+		case de.g != gid && de.g != anyG:
+			continue
+		case de.g == anyG && !p.holds(g), de.g != anyG && de.e != unsafe.Pointer(g.entry):
+			return fmt.Errorf("monkey: calls to %s on this goroutine go to another patch", SymbolName(from))
+		case atomic.LoadUint32(&g.entry.off) != 0:
+			return fmt.Errorf("monkey: patch of %s is disabled", SymbolName(from))
+		}
+		return nil
+	}
+}
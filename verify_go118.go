@@ -0,0 +1,28 @@
+//go:build go1.18
+// +build go1.18
+
+package monkey
+
+import (
+	"runtime/debug"
+	"strings"
+)
+
+// inlining tells whether the binary was built without -l in its -gcflags.
+func inlining() bool {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return false
+	}
+	for _, s := range bi.Settings {
+		if s.Key != "-gcflags" {
+			continue
+		}
+		for _, f := range strings.Fields(s.Value) {
+			if f == "-l" || strings.HasSuffix(f, "=-l") {
+				return false
+			}
+		}
+	}
+	return true
+}
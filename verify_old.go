@@ -0,0 +1,10 @@
+//go:build !go1.18
+// +build !go1.18
+
+package monkey
+
+// inlining tells whether the binary was built without -l in its -gcflags,
+// which the build info only records since Go 1.18.
+func inlining() bool {
+	return false
+}